@@ -10,6 +10,8 @@ import (
 
 	"github.com/golang/dep/gps"
 	"github.com/golang/dep/internal/fs"
+	"github.com/golang/dep/internal/modfile"
+	"github.com/pkg/errors"
 )
 
 // Analyzer implements gps.ProjectAnalyzer.
@@ -23,10 +25,15 @@ func (a Analyzer) HasDepMetadata(path string) bool {
 }
 
 // DeriveManifestAndLock reads and returns the manifest at path/ManifestName or nil if one is not found.
+// If there is no manifest, but there is a go.mod file, a manifest is derived from it instead.
 // The Lock is always nil for now.
 func (a Analyzer) DeriveManifestAndLock(path string, n gps.ProjectRoot) (gps.Manifest, gps.Lock, error) {
 	if !a.HasDepMetadata(path) {
-		return nil, nil, nil
+		m, err := deriveManifestFromModFile(path)
+		if m == nil || err != nil {
+			return nil, nil, err
+		}
+		return m, nil, nil
 	}
 
 	f, err := os.Open(filepath.Join(path, ManifestName))
@@ -44,10 +51,60 @@ func (a Analyzer) DeriveManifestAndLock(path string, n gps.ProjectRoot) (gps.Man
 	return m, nil, nil
 }
 
+// deriveManifestFromModFile derives a manifest from the go.mod file in dir, so
+// that dependencies described only by go.mod still constrain their own
+// dependencies. It returns nil if there is no go.mod file.
+//
+// Without a SourceManager, the project root of each required module is taken
+// to be its path, less any major version suffix, and requirements on
+// pseudo-versions or local directories are left unconstrained.
+func deriveManifestFromModFile(dir string) (*Manifest, error) {
+	f, err := os.Open(filepath.Join(dir, modfile.FileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	mf, err := modfile.Parse(f)
+	if err != nil {
+		return nil, err
+	}
+
+	m := NewManifest()
+	for _, req := range mf.Requirements() {
+		if req.Local != "" {
+			continue
+		}
+
+		pp := gps.ProjectProperties{
+			Source:     modfile.TrimMajorSuffix(req.Source),
+			Constraint: gps.Any(),
+		}
+		if cs := req.Constraint(); cs != "" {
+			c, err := gps.NewSemverConstraintIC(cs)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid version %s required for %s", req.Version, req.Path)
+			}
+			pp.Constraint = c
+		}
+
+		if pp.Source != "" || !gps.IsAny(pp.Constraint) {
+			m.Constraints[gps.ProjectRoot(modfile.TrimMajorSuffix(req.Path))] = pp
+		}
+	}
+
+	return m, nil
+}
+
 // Info returns Analyzer's name and version info.
+//
+// The version is part of the inputs hash, so it is bumped whenever the
+// analysis changes what it derives; 2 added importing go.mod files.
 func (a Analyzer) Info() gps.ProjectAnalyzerInfo {
 	return gps.ProjectAnalyzerInfo{
 		Name:    "dep",
-		Version: 1,
+		Version: 2,
 	}
 }
@@ -6,8 +6,10 @@ package dep
 
 import (
 	"path/filepath"
+	"reflect"
 	"testing"
 
+	"github.com/golang/dep/gps"
 	"github.com/golang/dep/internal/test"
 )
 
@@ -102,12 +104,59 @@ func TestAnalyzerDeriveManifestAndLockInvalidManifest(t *testing.T) {
 	}
 }
 
+func TestAnalyzerDeriveManifestAndLockFromModFile(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempDir("dep")
+	h.TempFile(filepath.Join("dep", "go.mod"), `module my/fake/project
+
+require (
+	github.com/pkg/errors v0.8.0
+	github.com/sdboyer/deptest v1.0.0
+	github.com/sdboyer/deptestdos/v2 v2.0.0
+	golang.org/x/net v0.0.0-20180724234803-3673e40ba225
+	golang.org/x/text v0.3.0
+)
+
+exclude github.com/sdboyer/deptest v1.0.1
+replace github.com/pkg/errors => github.com/fork/errors v0.8.1
+replace golang.org/x/text => ../text
+`)
+
+	a := Analyzer{}
+
+	m, l, err := a.DeriveManifestAndLock(h.Path("dep"), "my/fake/project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l != nil {
+		t.Fatalf("expected lock to be nil, got: %#v", l)
+	}
+
+	want := map[gps.ProjectRoot]string{
+		"github.com/pkg/errors":         "^0.8.1 (from github.com/fork/errors)",
+		"github.com/sdboyer/deptest":    "^1.0.0, !=1.0.1",
+		"github.com/sdboyer/deptestdos": "^2.0.0",
+	}
+	got := make(map[gps.ProjectRoot]string)
+	for pr, pp := range m.DependencyConstraints() {
+		got[pr] = pp.Constraint.String()
+		if pp.Source != "" {
+			got[pr] += " (from " + pp.Source + ")"
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected constraints:\n\t(GOT): %v\n\t(WNT): %v", got, want)
+	}
+}
+
 func TestAnalyzerInfo(t *testing.T) {
 	a := Analyzer{}
 
 	info := a.Info()
 
-	if info.Name != "dep" || info.Version != 1 {
-		t.Fatalf("expected name to be 'dep' and version to be 1: name -> %q vers -> %d", info.Name, info.Version)
+	if info.Name != "dep" || info.Version != 2 {
+		t.Fatalf("expected name to be 'dep' and version to be 2: name -> %q vers -> %d", info.Name, info.Version)
 	}
 }
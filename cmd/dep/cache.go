@@ -0,0 +1,255 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+const cacheShortHelp = `Inspect and clean up the cache of upstream sources`
+const cacheLongHelp = `
+Cache manages the local copies of upstream sources that dep keeps in its cache
+directory, $DEPCACHEDIR or $GOPATH/pkg/dep by default.
+
+  ls      List the cached sources, with their size, the time they were last
+          used, and the upstream URL they were fetched from.
+
+  clean   Remove cached sources. With -unused-for, sources that have not been
+          used for the given number of days are removed. If Gopkg.lock files
+          are given, sources that are not referenced by any of them are
+          removed. At least one of the two must be given.
+
+  verify  Check that each cached source is an intact repository, cleaning up
+          its working tree where needed. Sources that cannot be recovered are
+          removed, and will be fetched again the next time they are needed.
+
+Flags may be given either before or after the subcommand. With -dry-run, clean
+and verify only report the sources they would remove, and verify does not
+clean up working trees.
+`
+
+func (cmd *cacheCommand) Name() string { return "cache" }
+func (cmd *cacheCommand) Args() string {
+	return "ls | clean [-unused-for days] [-dry-run] [<Gopkg.lock>...] | verify [-dry-run]"
+}
+func (cmd *cacheCommand) ShortHelp() string { return cacheShortHelp }
+func (cmd *cacheCommand) LongHelp() string  { return cacheLongHelp }
+func (cmd *cacheCommand) Hidden() bool      { return false }
+
+func (cmd *cacheCommand) Register(fs *flag.FlagSet) {
+	fs.IntVar(&cmd.unusedFor, "unused-for", cmd.unusedFor, "clean: remove sources that have not been used for this many days")
+	fs.BoolVar(&cmd.dryRun, "dry-run", cmd.dryRun, "only report the sources that would be removed")
+}
+
+type cacheCommand struct {
+	unusedFor int
+	dryRun    bool
+}
+
+func (cmd *cacheCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) == 0 {
+		return errors.New("must specify one of the ls, clean or verify subcommands")
+	}
+
+	// The flags preceding the subcommand have already been parsed; parse those
+	// following it, too.
+	sub := args[0]
+	fs := flag.NewFlagSet(cmd.Name()+" "+sub, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	cmd.Register(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	args = fs.Args()
+
+	switch sub {
+	case "ls", "verify":
+		if len(args) > 0 {
+			return errors.Errorf("dep cache %s takes no arguments", sub)
+		}
+	case "clean":
+		if cmd.unusedFor < 0 {
+			return errors.New("-unused-for must not be negative")
+		}
+		if cmd.unusedFor == 0 && len(args) == 0 {
+			return errors.New("dep cache clean requires -unused-for, Gopkg.lock files, or both")
+		}
+	default:
+		return errors.Errorf("unknown subcommand %q; must be one of ls, clean or verify", sub)
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	sm.UseDefaultSignalHandling()
+	defer sm.Release()
+
+	css, err := sm.CachedSources()
+	if err != nil {
+		return err
+	}
+
+	switch sub {
+	case "ls":
+		var buf bytes.Buffer
+		writeCachedSources(&buf, css)
+		ctx.Out.Print(buf.String())
+		return nil
+	case "clean":
+		return cmd.runClean(ctx, sm, css, args)
+	default:
+		return cmd.runVerify(ctx, sm, css)
+	}
+}
+
+func (cmd *cacheCommand) runClean(ctx *dep.Ctx, sm *gps.SourceMgr, css []gps.CachedSource, lockPaths []string) error {
+	var cutoff time.Time
+	if cmd.unusedFor > 0 {
+		cutoff = time.Now().AddDate(0, 0, -cmd.unusedFor)
+	}
+
+	var referenced map[string]bool
+	if len(lockPaths) > 0 {
+		referenced = make(map[string]bool)
+		for _, lp := range lockPaths {
+			paths, err := lockCachePaths(sm, lp)
+			if err != nil {
+				return err
+			}
+			for _, path := range paths {
+				referenced[path] = true
+			}
+		}
+	}
+
+	var removed int64
+	for _, cs := range staleCachedSources(css, cutoff, referenced) {
+		if err := cmd.remove(ctx, sm, cs); err != nil {
+			return err
+		}
+		removed += cs.Size
+	}
+	if !cmd.dryRun && ctx.Verbose {
+		ctx.Err.Printf("Freed %s\n", formatSize(removed))
+	}
+	return nil
+}
+
+func (cmd *cacheCommand) runVerify(ctx *dep.Ctx, sm *gps.SourceMgr, css []gps.CachedSource) error {
+	for _, cs := range css {
+		// A dry run leaves the working trees as they are.
+		err := sm.VerifyCachedSource(context.TODO(), cs, !cmd.dryRun)
+		if err == nil {
+			continue
+		}
+
+		ctx.Err.Printf("%s is corrupt: %v\n", cachedSourceName(cs), err)
+		if err := cmd.remove(ctx, sm, cs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove removes the cached source, or only reports it if this is a dry run.
+func (cmd *cacheCommand) remove(ctx *dep.Ctx, sm *gps.SourceMgr, cs gps.CachedSource) error {
+	if cmd.dryRun {
+		ctx.Out.Printf("Would remove %s\n", cachedSourceName(cs))
+		return nil
+	}
+
+	if err := sm.RemoveCachedSource(cs); err != nil {
+		return err
+	}
+	ctx.Out.Printf("Removed %s\n", cachedSourceName(cs))
+	return nil
+}
+
+// lockCachePaths returns the locations in the cache of the sources for the
+// projects in the Gopkg.lock at path.
+func lockCachePaths(sm *gps.SourceMgr, path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open %s", path)
+	}
+	defer f.Close()
+
+	l, err := dep.ReadLock(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read %s", path)
+	}
+
+	var paths []string
+	for _, lp := range l.Projects() {
+		pp, err := sm.CachePathsFor(lp.Ident())
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not determine the cached source for %s", lp.Ident())
+		}
+		paths = append(paths, pp...)
+	}
+	return paths, nil
+}
+
+// staleCachedSources returns the cached sources that were last used before
+// cutoff, or that are not in referenced. A zero cutoff, or a nil referenced,
+// disables the respective check.
+func staleCachedSources(css []gps.CachedSource, cutoff time.Time, referenced map[string]bool) []gps.CachedSource {
+	var stale []gps.CachedSource
+	for _, cs := range css {
+		unused := !cutoff.IsZero() && cs.LastUsed.Before(cutoff)
+		unreferenced := referenced != nil && !referenced[cs.Path]
+		if unused || unreferenced {
+			stale = append(stale, cs)
+		}
+	}
+	return stale
+}
+
+func writeCachedSources(w io.Writer, css []gps.CachedSource) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tSIZE\tLAST USED")
+	for _, cs := range css {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", cachedSourceName(cs), formatSize(cs.Size), cs.LastUsed.Format("2006-01-02 15:04"))
+	}
+	tw.Flush()
+}
+
+// cachedSourceName returns the upstream URL of the cached source, or its
+// location in the cache if the URL is not known.
+func cachedSourceName(cs gps.CachedSource) string {
+	if cs.URL != "" {
+		return cs.URL
+	}
+	return filepath.Base(cs.Path) + " (unknown source)"
+}
+
+// formatSize formats a size in bytes for humans, using binary multiples.
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
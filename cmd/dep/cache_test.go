@@ -0,0 +1,98 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/dep/gps"
+)
+
+func TestStaleCachedSources(t *testing.T) {
+	now := time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)
+	old := gps.CachedSource{Path: "/cache/sources/old", LastUsed: now.AddDate(0, 0, -40)}
+	recent := gps.CachedSource{Path: "/cache/sources/recent", LastUsed: now.AddDate(0, 0, -2)}
+	css := []gps.CachedSource{old, recent}
+
+	cases := []struct {
+		name       string
+		cutoff     time.Time
+		referenced map[string]bool
+		want       []gps.CachedSource
+	}{
+		{
+			name:   "unused",
+			cutoff: now.AddDate(0, 0, -30),
+			want:   []gps.CachedSource{old},
+		},
+		{
+			name:       "unreferenced",
+			referenced: map[string]bool{old.Path: true},
+			want:       []gps.CachedSource{recent},
+		},
+		{
+			name:       "unused or unreferenced",
+			cutoff:     now.AddDate(0, 0, -30),
+			referenced: map[string]bool{old.Path: true},
+			want:       []gps.CachedSource{old, recent},
+		},
+		{
+			name:       "none referenced",
+			referenced: map[string]bool{},
+			want:       []gps.CachedSource{old, recent},
+		},
+		{
+			name: "no criteria",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := staleCachedSources(css, c.cutoff, c.referenced)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("unexpected stale sources:\n\t(GOT): %v\n\t(WNT): %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestWriteCachedSources(t *testing.T) {
+	used := time.Date(2018, 3, 1, 12, 30, 0, 0, time.UTC)
+	css := []gps.CachedSource{
+		{Path: "/cache/sources/https---github.com-foo-bar", Type: "git", URL: "https://github.com/foo/bar", Size: 2560, LastUsed: used},
+		{Path: "/cache/sources/junk", Size: 12, LastUsed: used},
+	}
+
+	want := "SOURCE                      SIZE     LAST USED\n" +
+		"https://github.com/foo/bar  2.5 KiB  2018-03-01 12:30\n" +
+		"junk (unknown source)       12 B     2018-03-01 12:30\n"
+
+	var buf bytes.Buffer
+	writeCachedSources(&buf, css)
+	if buf.String() != want {
+		t.Fatalf("unexpected output:\n\t(GOT): %q\n\t(WNT): %q", buf.String(), want)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	cases := map[int64]string{
+		0:                  "0 B",
+		1023:               "1023 B",
+		1024:               "1.0 KiB",
+		1536:               "1.5 KiB",
+		5 * 1024 * 1024:    "5.0 MiB",
+		3 << 30:            "3.0 GiB",
+		1<<40 + 1<<39 + 10: "1.5 TiB",
+	}
+
+	for n, want := range cases {
+		if got := formatSize(n); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/pkgtree"
+	"github.com/golang/dep/internal/fs"
+	"github.com/pkg/errors"
+)
+
+const checkShortHelp = `Check if imports, Gopkg.toml, and Gopkg.lock are in sync`
+const checkLongHelp = `
+Check determines if your project is in a good state. If problems are found, it
+prints a description of each one and exits with a non-zero code. Check never
+writes to Gopkg.toml, Gopkg.lock, or vendor/.
+
+Two kinds of checks are performed:
+
+  * Gopkg.lock is in sync with Gopkg.toml and the project's imports; that is,
+    its inputs-digest matches the one computed from the current inputs.
+  * vendor/ contains exactly the projects in Gopkg.lock, and each vendored
+    project matches the tree exported at its locked revision, after pruning.
+
+Vendored trees are compared against the digests recorded in Gopkg.lock. Projects
+without a recorded digest are compared against code already present in the
+local source cache. Check never touches the network: projects that are neither
+recorded nor cached are reported as unverifiable.
+
+Each problem is reported on its own line as a tab-separated pair of the
+problem kind and its subject. The -json flag reports the same list as a JSON
+array instead.
+`
+
+const (
+	checkLockOutOfSync  = "lock-out-of-sync"
+	checkDigestMismatch = "digest-mismatch"
+	checkMissingVendor  = "missing-from-vendor"
+	checkUnexpected     = "unexpected-in-vendor"
+	checkUnverifiable   = "unverifiable"
+)
+
+var errCheckFailed = errors.New("check found problems")
+
+func (cmd *checkCommand) Name() string { return "check" }
+func (cmd *checkCommand) Args() string {
+	return "[-q] [-json] [-skip-lock] [-skip-vendor]"
+}
+func (cmd *checkCommand) ShortHelp() string { return checkShortHelp }
+func (cmd *checkCommand) LongHelp() string  { return checkLongHelp }
+func (cmd *checkCommand) Hidden() bool      { return false }
+
+func (cmd *checkCommand) Register(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.quiet, "q", false, "suppress non-error output")
+	fs.BoolVar(&cmd.json, "json", false, "output problems in JSON format")
+	fs.BoolVar(&cmd.skipLock, "skip-lock", false, "skip checking that imports and Gopkg.toml are in sync with Gopkg.lock")
+	fs.BoolVar(&cmd.skipVendor, "skip-vendor", false, "skip checking that vendor is in sync with Gopkg.lock")
+}
+
+type checkCommand struct {
+	quiet                bool
+	json                 bool
+	skipLock, skipVendor bool
+}
+
+// checkProblem describes a single inconsistency found by dep check.
+type checkProblem struct {
+	Kind    string `json:"kind"`
+	Subject string `json:"subject"`
+}
+
+func (cmd *checkCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) != 0 {
+		return errors.New("dep check takes no arguments")
+	}
+
+	if cmd.skipLock && cmd.skipVendor {
+		return errors.New("cannot pass both -skip-lock and -skip-vendor")
+	}
+
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+
+	if p.Lock == nil {
+		return errors.Errorf("no %s found; run dep ensure to generate one", dep.LockName)
+	}
+
+	// Everything check needs is in Gopkg.lock, vendor/ or the source cache.
+	ctx.Offline = true
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	sm.UseDefaultSignalHandling()
+	defer sm.Release()
+
+	var problems []checkProblem
+
+	if !cmd.skipLock {
+		insync, err := checkLockInSync(p, sm)
+		if err != nil {
+			return err
+		}
+		if !insync {
+			problems = append(problems, checkProblem{Kind: checkLockOutOfSync, Subject: dep.LockName})
+		}
+	}
+
+	if !cmd.skipVendor {
+		statuses, err := checkVendorTree(p, sm)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, vendorProblems(statuses)...)
+	}
+
+	if len(problems) == 0 && !cmd.json {
+		if !cmd.quiet {
+			ctx.Out.Printf("%s, %s and vendor/ are in sync with the project's imports\n", dep.ManifestName, dep.LockName)
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := writeCheckProblems(&buf, problems, cmd.json); err != nil {
+		return err
+	}
+	ctx.Out.Print(buf.String())
+
+	if len(problems) > 0 {
+		return errCheckFailed
+	}
+	return nil
+}
+
+// checkLockInSync reports whether the inputs-digest recorded in the project's
+// lock matches the digest computed from the current manifest and imports.
+func checkLockInSync(p *dep.Project, sm gps.SourceManager) (bool, error) {
+	params := p.MakeParams()
+
+	var err error
+	params.RootPackageTree, err = p.ParseRootPackageTree()
+	if err != nil {
+		return false, err
+	}
+
+	s, err := gps.Prepare(params, sm)
+	if err != nil {
+		return false, errors.Wrap(err, "could not set up solver for input hashing")
+	}
+
+	return bytes.Equal(s.HashInputs(), p.Lock.InputsDigest()), nil
+}
+
+// checkVendorTree compares the digests of the projects in the project's vendor
+// directory against those they are expected to have. The expected digest of a
+// project is the one recorded in the lock, if it was recorded under the
+// manifest's current prune options; otherwise, the project is exported to a
+// temporary directory and pruned, and the digest of the result is used.
+//
+// sm is expected to be offline. Projects it cannot export because they are not
+// in its cache have no expected digest, and are reported as
+// pkgtree.EmptyDigestInLock if they are vendored.
+func checkVendorTree(p *dep.Project, sm gps.SourceManager) (map[string]pkgtree.VendorStatus, error) {
+	vpath := filepath.Join(p.AbsRoot, "vendor")
+	lps := p.Lock.Projects()
+
+	if exists, err := fs.IsDir(vpath); !exists {
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		statuses := make(map[string]pkgtree.VendorStatus, len(lps))
+		for _, lp := range lps {
+			statuses[string(lp.Ident().ProjectRoot)] = pkgtree.NotInTree
+		}
+		return statuses, nil
+	}
+
+	wantSums := make(map[string][]byte, len(lps))
+
+	// Export at the locked revisions, rather than the versions, so that the
+	// source's version list does not need to be brought up to date.
+	l := &dep.Lock{}
+	for _, lp := range lps {
+		pr := lp.Ident().ProjectRoot
+		if len(lp.VendorDigest()) > 0 && lp.PruneOptions() == p.Manifest.PruneOptions.PruneOptionsFor(pr) {
+			wantSums[string(pr)] = lp.VendorDigest()
+			continue
+		}
+		l.P = append(l.P, gps.NewLockedProject(lp.Ident(), lockedRevision(lp.Version()), lp.Packages()))
+	}
+
+	if len(l.P) > 0 {
+		td, err := ioutil.TempDir(os.TempDir(), "dep")
+		if err != nil {
+			return nil, errors.Wrap(err, "error while creating temp dir for exporting locked projects")
+		}
+		defer os.RemoveAll(td)
+
+		for _, lp := range l.P {
+			pr := string(lp.Ident().ProjectRoot)
+			to := filepath.Join(td, filepath.FromSlash(pr))
+			err := sm.ExportProject(context.TODO(), lp.Ident(), lp.Version(), to)
+			if _, ok := errors.Cause(err).(gps.OfflineError); ok {
+				wantSums[pr] = nil
+				continue
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not export %s from the source cache", pr)
+			}
+			if err := gps.PruneProject(to, lp, p.Manifest.PruneOptions.PruneOptionsFor(lp.Ident().ProjectRoot)); err != nil {
+				return nil, errors.Wrapf(err, "could not prune %s", pr)
+			}
+
+			sum, err := pkgtree.DigestFromDirectory(to)
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not compute digest of %s", pr)
+			}
+			wantSums[pr] = sum
+		}
+	}
+
+	return pkgtree.VerifyDepTree(vpath, wantSums)
+}
+
+// lockedRevision returns the underlying revision of a locked version, if it
+// has one.
+func lockedRevision(v gps.Version) gps.Version {
+	if pv, ok := v.(gps.PairedVersion); ok {
+		return pv.Revision()
+	}
+	return v
+}
+
+// vendorProblems converts the results of a vendor tree verification into a
+// sorted list of problems. Projects whose vendored tree matches are omitted.
+func vendorProblems(statuses map[string]pkgtree.VendorStatus) []checkProblem {
+	var problems []checkProblem
+	for path, status := range statuses {
+		var kind string
+		switch status {
+		case pkgtree.NotInLock:
+			kind = checkUnexpected
+		case pkgtree.NotInTree:
+			kind = checkMissingVendor
+		case pkgtree.DigestMismatchInLock:
+			kind = checkDigestMismatch
+		case pkgtree.EmptyDigestInLock:
+			kind = checkUnverifiable
+		default:
+			continue
+		}
+		problems = append(problems, checkProblem{Kind: kind, Subject: path})
+	}
+
+	sort.Slice(problems, func(i, j int) bool {
+		if problems[i].Subject != problems[j].Subject {
+			return problems[i].Subject < problems[j].Subject
+		}
+		return problems[i].Kind < problems[j].Kind
+	})
+	return problems
+}
+
+func writeCheckProblems(w io.Writer, problems []checkProblem, asJSON bool) error {
+	if asJSON {
+		if problems == nil {
+			problems = []checkProblem{}
+		}
+		return json.NewEncoder(w).Encode(problems)
+	}
+
+	for _, prob := range problems {
+		if _, err := fmt.Fprintf(w, "%s\t%s\n", prob.Kind, prob.Subject); err != nil {
+			return err
+		}
+	}
+	return nil
+}
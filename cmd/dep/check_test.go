@@ -0,0 +1,210 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"testing"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/pkgtree"
+	"github.com/golang/dep/internal/test"
+)
+
+func TestVendorProblems(t *testing.T) {
+	statuses := map[string]pkgtree.VendorStatus{
+		"github.com/alice/alice1": pkgtree.NoMismatch,
+		"github.com/alice/alice2": pkgtree.DigestMismatchInLock,
+		"github.com/bob":          pkgtree.NotInLock,
+		"github.com/carol/carol1": pkgtree.NotInTree,
+		"github.com/dave/dave1":   pkgtree.EmptyDigestInLock,
+	}
+
+	want := []checkProblem{
+		{Kind: checkDigestMismatch, Subject: "github.com/alice/alice2"},
+		{Kind: checkUnexpected, Subject: "github.com/bob"},
+		{Kind: checkMissingVendor, Subject: "github.com/carol/carol1"},
+		{Kind: checkUnverifiable, Subject: "github.com/dave/dave1"},
+	}
+
+	got := vendorProblems(statuses)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected problems:\n\t(GOT): %v\n\t(WNT): %v", got, want)
+	}
+}
+
+func TestWriteCheckProblems(t *testing.T) {
+	problems := []checkProblem{
+		{Kind: checkLockOutOfSync, Subject: dep.LockName},
+		{Kind: checkMissingVendor, Subject: "github.com/foo/bar"},
+	}
+
+	cases := []struct {
+		name     string
+		problems []checkProblem
+		json     bool
+		want     string
+	}{
+		{
+			name:     "text",
+			problems: problems,
+			want:     "lock-out-of-sync\tGopkg.lock\nmissing-from-vendor\tgithub.com/foo/bar\n",
+		},
+		{
+			name:     "json",
+			problems: problems,
+			json:     true,
+			want:     `[{"kind":"lock-out-of-sync","subject":"Gopkg.lock"},{"kind":"missing-from-vendor","subject":"github.com/foo/bar"}]` + "\n",
+		},
+		{
+			name: "json without problems",
+			json: true,
+			want: "[]\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeCheckProblems(&buf, c.problems, c.json); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != c.want {
+				t.Fatalf("unexpected output:\n\t(GOT): %q\n\t(WNT): %q", buf.String(), c.want)
+			}
+		})
+	}
+}
+
+func TestCheckVendorTreeWithoutVendor(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempDir("src")
+	p := &dep.Project{
+		AbsRoot:  h.Path("src"),
+		Manifest: dep.NewManifest(),
+		Lock: &dep.Lock{
+			P: []gps.LockedProject{
+				gps.NewLockedProject(
+					gps.ProjectIdentifier{ProjectRoot: "github.com/foo/bar"},
+					gps.NewVersion("v1.0.0").Pair("abc123"),
+					[]string{"."},
+				),
+			},
+		},
+	}
+
+	// Without a vendor directory, no SourceManager is needed to determine
+	// that every locked project is missing.
+	got, err := checkVendorTree(p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]pkgtree.VendorStatus{
+		"github.com/foo/bar": pkgtree.NotInTree,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected statuses:\n\t(GOT): %v\n\t(WNT): %v", got, want)
+	}
+}
+
+func TestCheckVendorTreeWithRecordedDigests(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempFile("src/vendor/github.com/foo/bar/bar.go", "package bar\n")
+	h.TempFile("src/vendor/github.com/foo/baz/baz.go", "package baz\n")
+	digest, err := pkgtree.DigestFromDirectory(h.Path("src/vendor/github.com/foo/bar"))
+	h.Must(err)
+
+	m := dep.NewManifest()
+	pr := gps.ProjectRoot("github.com/foo/bar")
+	p := &dep.Project{
+		AbsRoot:  h.Path("src"),
+		Manifest: m,
+		Lock: &dep.Lock{
+			P: []gps.LockedProject{
+				gps.NewLockedProject(
+					gps.ProjectIdentifier{ProjectRoot: pr},
+					gps.NewVersion("v1.0.0").Pair("abc123"),
+					[]string{"."},
+				).WithVendorDigest(m.PruneOptions.PruneOptionsFor(pr), digest),
+			},
+		},
+	}
+
+	// Every locked project has a digest recorded under the current prune
+	// options, so no SourceManager is needed.
+	got, err := checkVendorTree(p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]pkgtree.VendorStatus{
+		"github.com/foo/bar": pkgtree.NoMismatch,
+		"github.com/foo/baz": pkgtree.NotInLock,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected statuses:\n\t(GOT): %v\n\t(WNT): %v", got, want)
+	}
+}
+
+func TestCheckVendorTreeOffline(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempFile("src/vendor/github.com/foo/bar/bar.go", "package bar\n")
+	h.TempDir("cache")
+
+	p := &dep.Project{
+		AbsRoot:  h.Path("src"),
+		Manifest: dep.NewManifest(),
+		Lock: &dep.Lock{
+			P: []gps.LockedProject{
+				gps.NewLockedProject(
+					gps.ProjectIdentifier{ProjectRoot: "github.com/foo/bar"},
+					gps.NewVersion("v1.0.0").Pair("abc123"),
+					[]string{"."},
+				),
+				gps.NewLockedProject(
+					gps.ProjectIdentifier{ProjectRoot: "github.com/foo/qux"},
+					gps.NewVersion("v1.0.0").Pair("def456"),
+					[]string{"."},
+				),
+			},
+		},
+	}
+
+	ctx := &dep.Ctx{
+		Out:      log.New(ioutil.Discard, "", 0),
+		Err:      log.New(ioutil.Discard, "", 0),
+		Cachedir: h.Path("cache"),
+		Offline:  true,
+	}
+	sm, err := ctx.SourceManager()
+	h.Must(err)
+	defer sm.Release()
+
+	// Neither project has a recorded digest, nor is in the cache, so neither
+	// can be verified without fetching it.
+	got, err := checkVendorTree(p, sm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]pkgtree.VendorStatus{
+		"github.com/foo/bar": pkgtree.EmptyDigestInLock,
+		"github.com/foo/qux": pkgtree.NotInTree,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected statuses:\n\t(GOT): %v\n\t(WNT): %v", got, want)
+	}
+}
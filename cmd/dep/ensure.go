@@ -47,6 +47,16 @@ needed), but never touch vendor/.
 The effect of passing project spec arguments varies slightly depending on the
 combination of flags that are passed.
 
+If no solution can be found, -json-errors additionally writes a structured
+description of the failure, in JSON, to standard output. -trace-json writes a
+structured trace of the solving process to the given file, one JSON object per
+line, for later analysis.
+
+Projects may be sourced from local directories, to build against working copies
+that have not been pushed yet. As such a lock is of no use elsewhere, -ci, or
+setting DEPCI in the environment, makes ensure fail if any project in Gopkg.toml
+or Gopkg.lock is sourced from a local directory.
+
 
 Examples:
 
@@ -110,6 +120,13 @@ dep ensure -update -no-vendor
 
     As above, but only modify Gopkg.lock; leave vendor/ unchanged.
 
+dep ensure -update -strategy=minimal
+
+    Update all dependencies to the lowest versions allowed by Gopkg.toml and
+    the constraints of other dependencies, ignoring any versions recorded in
+    Gopkg.lock, or in the lock files of dependencies. The result depends only on
+    the constraints in play, which makes it reproducible.
+
 dep ensure -no-vendor -dry-run
 
     This fails with a non zero exit code if Gopkg.lock is not up to date with
@@ -125,7 +142,7 @@ var (
 
 func (cmd *ensureCommand) Name() string { return "ensure" }
 func (cmd *ensureCommand) Args() string {
-	return "[-update | -add] [-no-vendor | -vendor-only] [-dry-run] [-v] [<spec>...]"
+	return "[-update [-strategy <strategy>] | -add] [-no-vendor | -vendor-only] [-dry-run] [-json-errors] [-trace-json <file>] [-ci] [-v] [<spec>...]"
 }
 func (cmd *ensureCommand) ShortHelp() string { return ensureShortHelp }
 func (cmd *ensureCommand) LongHelp() string  { return ensureLongHelp }
@@ -138,6 +155,11 @@ func (cmd *ensureCommand) Register(fs *flag.FlagSet) {
 	fs.BoolVar(&cmd.vendorOnly, "vendor-only", false, "populate vendor/ from Gopkg.lock without updating it first")
 	fs.BoolVar(&cmd.noVendor, "no-vendor", false, "update Gopkg.lock (if needed), but do not update vendor/")
 	fs.BoolVar(&cmd.dryRun, "dry-run", false, "only report the changes that would be made")
+	fs.BoolVar(&cmd.jsonErrors, "json-errors", false, "output solve failures in JSON format")
+	fs.StringVar(&cmd.traceJSON, "trace-json", "", "write a trace of the solving process to `file`, as lines of JSON")
+	fs.StringVar(&cmd.strategy, "strategy", "", "with -update, the version selection strategy to use: upgrade (default) or minimal")
+	fs.BoolVar(&cmd.ci, "ci", false, "fail if any project is sourced from a local directory (also set by DEPCI)")
+	fs.IntVar(&cmd.prefetch, "prefetch", gps.DefaultPrefetchWorkers, "fetch up to `n` dependencies at once before solving; 0 disables prefetching")
 }
 
 type ensureCommand struct {
@@ -147,6 +169,11 @@ type ensureCommand struct {
 	noVendor   bool
 	vendorOnly bool
 	dryRun     bool
+	jsonErrors bool
+	traceJSON  string
+	ci         bool
+	strategy   string
+	prefetch   int
 }
 
 func (cmd *ensureCommand) Run(ctx *dep.Ctx, args []string) error {
@@ -164,6 +191,12 @@ func (cmd *ensureCommand) Run(ctx *dep.Ctx, args []string) error {
 		return err
 	}
 
+	if cmd.ci || ctx.CI {
+		if err := checkNoLocalSources(p); err != nil {
+			return err
+		}
+	}
+
 	sm, err := ctx.SourceManager()
 	if err != nil {
 		return err
@@ -179,6 +212,18 @@ func (cmd *ensureCommand) Run(ctx *dep.Ctx, args []string) error {
 	if ctx.Verbose {
 		params.TraceLogger = ctx.Err
 	}
+	if cmd.traceJSON != "" {
+		sink, err := createJSONTraceSink(cmd.traceJSON)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := sink.Close(); err != nil {
+				ctx.Err.Printf("Warning: %s\n", err)
+			}
+		}()
+		params.TraceSink = sink
+	}
 
 	if cmd.vendorOnly {
 		return cmd.runVendorOnly(ctx, args, p, sm, params)
@@ -219,6 +264,31 @@ func (cmd *ensureCommand) Run(ctx *dep.Ctx, args []string) error {
 	return cmd.runDefault(ctx, args, p, sm, params)
 }
 
+// prefetchDeps fetches the dependencies in the manifest and lock of params
+// concurrently, up to cmd.prefetch at once, so that the solver finds them in
+// the caches of sm rather than fetching them one by one.
+func (cmd *ensureCommand) prefetchDeps(sm gps.SourceManager, params gps.SolveParameters) error {
+	gsm, ok := sm.(*gps.SourceMgr)
+	if !ok || cmd.prefetch <= 0 {
+		return nil
+	}
+	return gsm.Prefetch(context.TODO(), params.Manifest, params.Lock, params.ProjectAnalyzer, cmd.prefetch)
+}
+
+// handleSolveFailure reports a failure to solve, additionally writing it out in
+// JSON if requested.
+func (cmd *ensureCommand) handleSolveFailure(ctx *dep.Ctx, err error) error {
+	err = handleAllTheFailuresOfTheWorld(err)
+	if err != nil && cmd.jsonErrors {
+		var buf bytes.Buffer
+		if jerr := writeSolveFailureJSON(&buf, errors.Cause(err)); jerr != nil {
+			return errors.Wrap(jerr, "failed to write solve failure as JSON")
+		}
+		ctx.Out.Print(buf.String())
+	}
+	return err
+}
+
 func (cmd *ensureCommand) validateFlags() error {
 	if cmd.add && cmd.update {
 		return errors.New("cannot pass both -add and -update")
@@ -236,9 +306,64 @@ func (cmd *ensureCommand) validateFlags() error {
 			return errors.New("really?")
 		}
 	}
+
+	if cmd.strategy != "" {
+		if !cmd.update {
+			return errors.New("-strategy only applies to -update")
+		}
+		if _, err := parseVersionStrategy(cmd.strategy); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// checkNoLocalSources returns an error if any project in the manifest or the
+// lock is sourced from a local directory.
+func checkNoLocalSources(p *dep.Project) error {
+	local := make(map[gps.ProjectRoot]string)
+	for _, pcs := range []gps.ProjectConstraints{p.Manifest.Constraints, p.Manifest.Ovr} {
+		for pr, pp := range pcs {
+			if gps.IsLocalSource(pp.Source) {
+				local[pr] = pp.Source
+			}
+		}
+	}
+	if p.Lock != nil {
+		for _, lp := range p.Lock.Projects() {
+			if id := lp.Ident(); gps.IsLocalSource(id.Source) {
+				local[id.ProjectRoot] = id.Source
+			}
+		}
+	}
+	if len(local) == 0 {
+		return nil
+	}
+
+	roots := make([]string, 0, len(local))
+	for pr := range local {
+		roots = append(roots, string(pr))
+	}
+	sort.Strings(roots)
+
+	var buf bytes.Buffer
+	for _, pr := range roots {
+		fmt.Fprintf(&buf, "\n  %s: %s", pr, local[gps.ProjectRoot(pr)])
+	}
+	return errors.Errorf("projects may not be sourced from local directories in CI mode:%s", buf.String())
+}
+
+// parseVersionStrategy parses the value of the -strategy flag.
+func parseVersionStrategy(s string) (gps.VersionStrategy, error) {
+	switch s {
+	case "", "upgrade":
+		return gps.UpgradeStrategy, nil
+	case "minimal":
+		return gps.MinimalStrategy, nil
+	}
+	return 0, errors.Errorf("unknown version strategy %q; must be upgrade or minimal", s)
+}
+
 func (cmd *ensureCommand) vendorBehavior() dep.VendorBehavior {
 	if cmd.noVendor {
 		return dep.VendorNever
@@ -298,9 +423,12 @@ func (cmd *ensureCommand) runDefault(ctx *dep.Ctx, args []string, p *dep.Project
 		return errors.New("Gopkg.lock was not up to date")
 	}
 
+	if err := cmd.prefetchDeps(sm, params); err != nil {
+		return err
+	}
 	solution, err := solver.Solve(context.TODO())
 	if err != nil {
-		return handleAllTheFailuresOfTheWorld(err)
+		return cmd.handleSolveFailure(ctx, err)
 	}
 
 	sw, err := dep.NewSafeWriter(nil, p.Lock, dep.LockFromSolution(solution), cmd.vendorBehavior(), p.Manifest.PruneOptions)
@@ -376,6 +504,11 @@ func (cmd *ensureCommand) runUpdate(ctx *dep.Ctx, args []string, p *dep.Project,
 		params.ChangeAll = true
 	}
 
+	params.Strategy, err = parseVersionStrategy(cmd.strategy)
+	if err != nil {
+		return err
+	}
+
 	if err := validateUpdateArgs(ctx, args, p, sm, &params); err != nil {
 		return err
 	}
@@ -385,12 +518,15 @@ func (cmd *ensureCommand) runUpdate(ctx *dep.Ctx, args []string, p *dep.Project,
 	if err != nil {
 		return errors.Wrap(err, "fastpath solver prepare")
 	}
+	if err := cmd.prefetchDeps(sm, params); err != nil {
+		return err
+	}
 	solution, err := solver.Solve(context.TODO())
 	if err != nil {
 		// TODO(sdboyer) special handling for warning cases as described in spec
 		// - e.g., named projects did not upgrade even though newer versions
 		// were available.
-		return handleAllTheFailuresOfTheWorld(err)
+		return cmd.handleSolveFailure(ctx, err)
 	}
 
 	sw, err := dep.NewSafeWriter(nil, p.Lock, dep.LockFromSolution(solution), cmd.vendorBehavior(), p.Manifest.PruneOptions)
@@ -646,10 +782,13 @@ func (cmd *ensureCommand) runAdd(ctx *dep.Ctx, args []string, p *dep.Project, sm
 	if err != nil {
 		return errors.Wrap(err, "fastpath solver prepare")
 	}
+	if err := cmd.prefetchDeps(sm, params); err != nil {
+		return err
+	}
 	solution, err := solver.Solve(context.TODO())
 	if err != nil {
 		// TODO(sdboyer) detect if the failure was specifically about some of the -add arguments
-		return handleAllTheFailuresOfTheWorld(err)
+		return cmd.handleSolveFailure(ctx, err)
 	}
 
 	// Prep post-actions and feedback from adds.
@@ -10,6 +10,7 @@ import (
 	"go/build"
 	"io/ioutil"
 	"log"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -45,6 +46,22 @@ func TestInvalidEnsureFlagCombinations(t *testing.T) {
 	}
 	ec.noVendor = false
 
+	ec.vendorOnly, ec.strategy = false, "minimal"
+	if err := ec.validateFlags(); err == nil {
+		t.Error("-strategy without -update should fail validation")
+	}
+
+	ec.update, ec.strategy = true, "newest"
+	if err := ec.validateFlags(); err == nil {
+		t.Error("-strategy with an unknown strategy should fail validation")
+	}
+
+	ec.strategy = "minimal"
+	if err := ec.validateFlags(); err != nil {
+		t.Errorf("-update with -strategy=minimal should pass validation, but got: %v", err)
+	}
+	ec.vendorOnly, ec.update, ec.strategy = true, false, ""
+
 	// Also verify that the plain ensure path takes no args. This is a shady
 	// test, as lots of other things COULD return errors, and we don't check
 	// anything other than the error being non-nil. For now, it works well
@@ -254,3 +271,33 @@ func TestValidateUpdateArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckNoLocalSources(t *testing.T) {
+	local, err := filepath.Abs("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &dep.Project{Manifest: dep.NewManifest()}
+	p.Manifest.Constraints["github.com/foo/remote"] = gps.ProjectProperties{Source: "https://github.com/fork/remote"}
+	if err := checkNoLocalSources(p); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	p.Lock = &dep.Lock{
+		P: []gps.LockedProject{
+			gps.NewLockedProject(
+				gps.ProjectIdentifier{ProjectRoot: "github.com/foo/bar", Source: local},
+				gps.NewVersion(gps.LocalVersion).Pair("abcdef"),
+				[]string{"."},
+			),
+		},
+	}
+	err = checkNoLocalSources(p)
+	if err == nil {
+		t.Fatal("expected a locked local source to be refused")
+	}
+	if !strings.Contains(err.Error(), "github.com/foo/bar: "+local) {
+		t.Errorf("expected the error to name the local source, got %q", err)
+	}
+}
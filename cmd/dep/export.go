@@ -0,0 +1,328 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/internal/modfile"
+	"github.com/pkg/errors"
+)
+
+const exportShortHelp = `Export the project's dependencies for use by another tool`
+const exportLongHelp = `
+Export writes the dependencies recorded in Gopkg.lock in the format of another
+dependency management tool. The only supported format is gomod, which writes
+go.mod and go.sum files to the project root, for migrating the project to Go
+modules.
+
+The go.mod file requires each project in Gopkg.lock at its locked version.
+Projects locked to a branch, a revision, or a tag that is not a semantic
+version are required at a pseudo-version identifying the locked revision.
+Projects that have an alternate source, or an [[override]] in Gopkg.toml, are
+also given a replace directive, so that the locked version is used throughout
+the build. Projects sourced from a local directory are replaced by that
+directory, which must then contain a go.mod file of its own.
+
+go.sum records the checksums of each required module version, computed from
+the locked revision of the project, so that the go command verifies it builds
+with the same code as dep. This exports every project in Gopkg.lock.
+
+go.mod can only express minimum versions, so constraints in Gopkg.toml that
+limit the range of versions, or track a branch, are lost; export warns about
+each of them. Run "go mod tidy" afterwards to add any requirements that only
+the go command can determine.
+`
+
+func (cmd *exportCommand) Name() string      { return "export" }
+func (cmd *exportCommand) Args() string      { return "[-format=gomod] [-dry-run] [-f]" }
+func (cmd *exportCommand) ShortHelp() string { return exportShortHelp }
+func (cmd *exportCommand) LongHelp() string  { return exportLongHelp }
+func (cmd *exportCommand) Hidden() bool      { return false }
+
+func (cmd *exportCommand) Register(fs *flag.FlagSet) {
+	fs.StringVar(&cmd.format, "format", "gomod", "format to export to; only gomod is supported")
+	fs.BoolVar(&cmd.dryRun, "dry-run", false, "print the exported files instead of writing them")
+	fs.BoolVar(&cmd.force, "f", false, "overwrite existing exported files")
+}
+
+type exportCommand struct {
+	format string
+	dryRun bool
+	force  bool
+}
+
+func (cmd *exportCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) > 0 {
+		return errors.New("dep export takes no arguments")
+	}
+	if cmd.format != "gomod" {
+		return errors.Errorf("unsupported export format %q; only gomod is supported", cmd.format)
+	}
+
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+	if p.Lock == nil {
+		return errors.Errorf("no %s found; run dep ensure to generate one", dep.LockName)
+	}
+
+	path := filepath.Join(p.AbsRoot, modfile.FileName)
+	sumPath := filepath.Join(p.AbsRoot, modfile.SumFileName)
+	if !cmd.dryRun && !cmd.force {
+		for _, path := range []string{path, sumPath} {
+			if _, err := os.Stat(path); err == nil {
+				return errors.Errorf("%s already exists; pass -f to overwrite it", path)
+			}
+		}
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	sm.UseDefaultSignalHandling()
+	defer sm.Release()
+
+	e := &gomodExporter{
+		revisionTime: sm.RevisionTime,
+		exportProject: func(id gps.ProjectIdentifier, v gps.Version, to string) error {
+			return sm.ExportProject(context.TODO(), id, v, to)
+		},
+		logger: ctx.Err,
+	}
+	f, sums, err := e.export(p)
+	if err != nil {
+		return err
+	}
+
+	if cmd.dryRun {
+		ctx.Out.Printf("%s:\n%s\n%s:\n%s", modfile.FileName, f.Format(), modfile.SumFileName, modfile.FormatSums(sums))
+		return nil
+	}
+	if err := ioutil.WriteFile(path, f.Format(), 0666); err != nil {
+		return errors.Wrapf(err, "failed to write %s", path)
+	}
+	return errors.Wrapf(ioutil.WriteFile(sumPath, modfile.FormatSums(sums), 0666), "failed to write %s", sumPath)
+}
+
+// gomodExporter converts a project's manifest and lock into go.mod and go.sum
+// files.
+type gomodExporter struct {
+	revisionTime  func(gps.ProjectIdentifier, gps.Revision) (time.Time, error)
+	exportProject func(gps.ProjectIdentifier, gps.Version, string) error
+	logger        *log.Logger
+}
+
+func (e *gomodExporter) export(p *dep.Project) (*modfile.File, []modfile.Sum, error) {
+	f := &modfile.File{Module: string(p.ImportRoot)}
+	var sums []modfile.Sum
+
+	locked := make(map[gps.ProjectRoot]bool)
+	for _, lp := range p.Lock.Projects() {
+		id := lp.Ident()
+		locked[id.ProjectRoot] = true
+
+		if gps.IsArchiveSource(id.Source) {
+			e.logger.Printf("Warning: %s is sourced from release archives, which go.mod cannot refer to; it is not exported\n", id.ProjectRoot)
+			continue
+		}
+
+		if dir, ok := gps.LocalSourceDir(id.Source); ok {
+			// A directory can only be referred to by a replace directive, which
+			// makes the required version irrelevant.
+			e.logger.Printf("Warning: replacing %s with the local directory %s; the directory must contain a go.mod file\n", id.ProjectRoot, dir)
+			f.Require = append(f.Require, modfile.Module{Path: string(id.ProjectRoot), Version: localModuleVersion})
+			f.Replace = append(f.Replace, modfile.Replace{
+				Old: modfile.Module{Path: string(id.ProjectRoot)},
+				New: modfile.Module{Path: localReplacePath(p.AbsRoot, dir)},
+			})
+			continue
+		}
+
+		v, err := e.version(lp)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "could not determine the module version of %s", id.ProjectRoot)
+		}
+		mod := modfile.Module{Path: string(id.ProjectRoot), Version: v}
+		f.Require = append(f.Require, mod)
+
+		// An override forces the locked version on the whole build, which only
+		// a replace directive does in go.mod.
+		_, overridden := p.Manifest.Ovr[id.ProjectRoot]
+		to := mod
+		if id.Source != "" {
+			to.Path = sourceModulePath(id.Source)
+		}
+		if to.Path != mod.Path || overridden {
+			f.Replace = append(f.Replace, modfile.Replace{
+				Old: modfile.Module{Path: mod.Path},
+				New: to,
+			})
+		}
+
+		// The go command checks the module it builds with, which is the
+		// replacement if there is one.
+		sum, err := e.sum(id, lp.Version(), to)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "could not compute the checksums of %s", id.ProjectRoot)
+		}
+		sums = append(sums, sum)
+	}
+
+	for _, pcs := range []gps.ProjectConstraints{p.Manifest.Constraints, p.Manifest.Ovr} {
+		roots := make([]string, 0, len(pcs))
+		for pr := range pcs {
+			roots = append(roots, string(pr))
+		}
+		sort.Strings(roots)
+
+		for _, pr := range roots {
+			c := pcs[gps.ProjectRoot(pr)].Constraint
+			if !locked[gps.ProjectRoot(pr)] {
+				e.logger.Printf("Warning: %s is in %s, but not in %s; it is not exported\n", pr, dep.ManifestName, dep.LockName)
+			} else if why := unrepresentableInGoMod(c); why != "" {
+				e.logger.Printf("Warning: the constraint %s on %s cannot be represented in go.mod: %s\n", c.ImpliedCaretString(), pr, why)
+			}
+		}
+	}
+
+	return f, sums, nil
+}
+
+// sum computes the go.sum checksums of the module version m from the tree of
+// the project at the locked version v.
+func (e *gomodExporter) sum(id gps.ProjectIdentifier, v gps.Version, m modfile.Module) (modfile.Sum, error) {
+	dir, err := ioutil.TempDir("", "dep-export")
+	if err != nil {
+		return modfile.Sum{}, errors.Wrap(err, "failed to create temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	tree := filepath.Join(dir, "tree")
+	if err := e.exportProject(id, v, tree); err != nil {
+		return modfile.Sum{}, err
+	}
+	return modfile.TreeSum(m, tree)
+}
+
+// version returns the version of the locked project to require in go.mod.
+func (e *gomodExporter) version(lp gps.LockedProject) (string, error) {
+	id := lp.Ident()
+	v := lp.Version()
+
+	if pv, ok := v.(gps.PairedVersion); ok && pv.Type() == gps.IsSemver && canonicalSemverRE.MatchString(pv.String()) {
+		tag := pv.String()
+		if major := strings.SplitN(tag[1:], ".", 2)[0]; major != "0" && major != "1" && !strings.HasPrefix(string(id.ProjectRoot), "gopkg.in/") {
+			// Without a /vN suffix on the import path, which a dep project does
+			// not use, major versions from v2 are only available to modules as
+			// incompatible versions.
+			e.logger.Printf("Warning: requiring %s at %s+incompatible; this fails if %s has adopted Go modules at that version\n", id.ProjectRoot, tag, id.ProjectRoot)
+			tag += "+incompatible"
+		}
+		return tag, nil
+	}
+
+	var rev gps.Revision
+	switch tv := v.(type) {
+	case gps.PairedVersion:
+		rev = tv.Revision()
+	case gps.Revision:
+		rev = tv
+	default:
+		return "", errors.Errorf("locked version %s has no revision", v)
+	}
+	if len(rev) < 12 {
+		return "", errors.Errorf("revision %s is too short for a pseudo-version", rev)
+	}
+
+	t, err := e.revisionTime(id, rev)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("v0.0.0-%s-%s", t.UTC().Format("20060102150405"), rev[:12]), nil
+}
+
+// localModuleVersion is the version at which modules replaced by a local
+// directory are required, as the go command does itself.
+const localModuleVersion = "v0.0.0-00010101000000-000000000000"
+
+// localReplacePath returns the path by which go.mod refers to the directory of
+// a local source: relative to the project root if it is within the same tree,
+// as that keeps the go.mod file usable on other machines.
+func localReplacePath(root, dir string) string {
+	if root != "" {
+		if rel, err := filepath.Rel(root, dir); err == nil {
+			rel = filepath.ToSlash(rel)
+			if strings.HasPrefix(rel, "../") {
+				return rel
+			}
+			if rel != "." {
+				return "./" + rel
+			}
+		}
+	}
+	return dir
+}
+
+// canonicalSemverRE matches the semantic version tags that the go command
+// recognizes as module versions.
+var canonicalSemverRE = regexp.MustCompile(`^v(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)(-[0-9A-Za-z.-]+)?$`)
+
+// minimumOnlyRE matches semver constraints that, like go.mod, only set a
+// minimum version within a major version.
+var minimumOnlyRE = regexp.MustCompile(`^(\^|>=)?v?[0-9][0-9A-Za-z.+-]*$`)
+
+// unrepresentableInGoMod returns why the constraint cannot be represented in
+// go.mod, or an empty string if it can be.
+func unrepresentableInGoMod(c gps.Constraint) string {
+	if c == nil || gps.IsAny(c) {
+		return ""
+	}
+
+	if v, ok := c.(gps.Version); ok {
+		switch v.Type() {
+		case gps.IsBranch:
+			return "go.mod cannot track a branch; the locked revision is required instead"
+		case gps.IsVersion:
+			return "go.mod cannot refer to tags that are not semantic versions; the locked revision is required instead"
+		default:
+			return "go.mod only records minimum versions; later versions may be selected"
+		}
+	}
+
+	if !minimumOnlyRE.MatchString(c.ImpliedCaretString()) {
+		return "go.mod only records minimum versions; the upper bound is lost"
+	}
+	return ""
+}
+
+var scpLikeRE = regexp.MustCompile(`^(?:[A-Za-z0-9_.-]+@)?([A-Za-z0-9_.-]+):(.+)$`)
+
+// sourceModulePath converts the source of a project, as given in Gopkg.toml,
+// to the module path by which go.mod refers to it.
+func sourceModulePath(source string) string {
+	path := source
+	if u, err := url.Parse(source); err == nil && u.Scheme != "" && u.Host != "" {
+		path = u.Host + u.Path
+	} else if m := scpLikeRE.FindStringSubmatch(source); m != nil {
+		path = m[1] + "/" + m[2]
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(path, "/"), ".git")
+}
@@ -0,0 +1,206 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/internal/modfile"
+	"github.com/pkg/errors"
+)
+
+func TestGomodExporter(t *testing.T) {
+	mkConstraint := func(s string) gps.Constraint {
+		c, err := gps.NewSemverConstraintIC(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	m := dep.NewManifest()
+	m.Constraints["github.com/foo/semver"] = gps.ProjectProperties{Constraint: mkConstraint("^1.2.0")}
+	m.Constraints["github.com/foo/branch"] = gps.ProjectProperties{Constraint: gps.NewBranch("master")}
+	m.Constraints["github.com/foo/range"] = gps.ProjectProperties{Constraint: mkConstraint(">=1.0.0, <1.5.0")}
+	m.Constraints["github.com/foo/unlocked"] = gps.ProjectProperties{Constraint: gps.Any()}
+	m.Ovr["github.com/foo/override"] = gps.ProjectProperties{Source: "https://github.com/fork/override.git"}
+
+	root, err := filepath.Abs(filepath.FromSlash("/src/github.com/golang/notexist"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	localDir := filepath.Join(root, "..", "local")
+
+	p := &dep.Project{
+		AbsRoot:    root,
+		ImportRoot: "github.com/golang/notexist",
+		Manifest:   m,
+		Lock: &dep.Lock{
+			P: []gps.LockedProject{
+				gps.NewLockedProject(
+					gps.ProjectIdentifier{ProjectRoot: "example.com/archive", Source: "https://example.com/archive-{version}.tar.gz#version=1.0.0"},
+					gps.NewVersion("1.0.0").Pair("0000000000000000000000000000000000000000000000000000000000000000"),
+					[]string{"."},
+				),
+				gps.NewLockedProject(
+					gps.ProjectIdentifier{ProjectRoot: "github.com/foo/branch"},
+					gps.NewBranch("master").Pair("0123456789abcdef0123456789abcdef01234567"),
+					[]string{"."},
+				),
+				gps.NewLockedProject(
+					gps.ProjectIdentifier{ProjectRoot: "github.com/foo/local", Source: localDir},
+					gps.NewVersion(gps.LocalVersion).Pair("abcdef0123456789"),
+					[]string{"."},
+				),
+				gps.NewLockedProject(
+					gps.ProjectIdentifier{ProjectRoot: "github.com/foo/major"},
+					gps.NewVersion("v2.1.0").Pair("1111111111111111111111111111111111111111"),
+					[]string{"."},
+				),
+				gps.NewLockedProject(
+					gps.ProjectIdentifier{ProjectRoot: "github.com/foo/override", Source: "https://github.com/fork/override.git"},
+					gps.NewVersion("v0.3.0").Pair("2222222222222222222222222222222222222222"),
+					[]string{"."},
+				),
+				gps.NewLockedProject(
+					gps.ProjectIdentifier{ProjectRoot: "github.com/foo/range"},
+					gps.NewVersion("v1.4.0").Pair("3333333333333333333333333333333333333333"),
+					[]string{"."},
+				),
+				gps.NewLockedProject(
+					gps.ProjectIdentifier{ProjectRoot: "github.com/foo/semver"},
+					gps.NewVersion("v1.2.3").Pair("4444444444444444444444444444444444444444"),
+					[]string{"."},
+				),
+				gps.NewLockedProject(
+					gps.ProjectIdentifier{ProjectRoot: "github.com/foo/source", Source: "git@github.com:fork/source.git"},
+					gps.Revision("5555555555555555555555555555555555555555"),
+					[]string{"."},
+				),
+				gps.NewLockedProject(
+					gps.ProjectIdentifier{ProjectRoot: "gopkg.in/yaml.v2"},
+					gps.NewVersion("v2.2.1").Pair("6666666666666666666666666666666666666666"),
+					[]string{"."},
+				),
+			},
+		},
+	}
+
+	exported := make(map[gps.ProjectRoot]gps.Version)
+	var warnings bytes.Buffer
+	e := &gomodExporter{
+		revisionTime: func(id gps.ProjectIdentifier, r gps.Revision) (time.Time, error) {
+			if id.ProjectRoot != "github.com/foo/branch" && id.ProjectRoot != "github.com/foo/source" {
+				return time.Time{}, errors.Errorf("unexpected revision time lookup for %s", id)
+			}
+			return time.Date(2018, 3, 1, 12, 30, 5, 0, time.FixedZone("", 3600)), nil
+		},
+		exportProject: func(id gps.ProjectIdentifier, v gps.Version, to string) error {
+			exported[id.ProjectRoot] = v
+			if err := os.MkdirAll(to, 0777); err != nil {
+				return err
+			}
+			return ioutil.WriteFile(filepath.Join(to, "doc.go"), []byte("// Package "+string(id.ProjectRoot)+"\n"), 0666)
+		},
+		logger: log.New(&warnings, "", 0),
+	}
+
+	f, sums, err := e.export(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `module github.com/golang/notexist
+
+require (
+	github.com/foo/branch v0.0.0-20180301113005-0123456789ab
+	github.com/foo/local v0.0.0-00010101000000-000000000000
+	github.com/foo/major v2.1.0+incompatible
+	github.com/foo/override v0.3.0
+	github.com/foo/range v1.4.0
+	github.com/foo/semver v1.2.3
+	github.com/foo/source v0.0.0-20180301113005-555555555555
+	gopkg.in/yaml.v2 v2.2.1
+)
+
+replace (
+	github.com/foo/local => ../local
+	github.com/foo/override => github.com/fork/override v0.3.0
+	github.com/foo/source => github.com/fork/source v0.0.0-20180301113005-555555555555
+)
+`
+	if got := string(f.Format()); got != want {
+		t.Errorf("unexpected go.mod:\n\t(GOT):\n%s\n\t(WNT):\n%s", got, want)
+	}
+
+	// Replaced projects are summed under their replacements, and local and
+	// archive sources not at all.
+	wantSums := []modfile.Module{
+		{Path: "github.com/foo/branch", Version: "v0.0.0-20180301113005-0123456789ab"},
+		{Path: "github.com/foo/major", Version: "v2.1.0+incompatible"},
+		{Path: "github.com/fork/override", Version: "v0.3.0"},
+		{Path: "github.com/foo/range", Version: "v1.4.0"},
+		{Path: "github.com/foo/semver", Version: "v1.2.3"},
+		{Path: "github.com/fork/source", Version: "v0.0.0-20180301113005-555555555555"},
+		{Path: "gopkg.in/yaml.v2", Version: "v2.2.1"},
+	}
+	var gotSums []modfile.Module
+	for _, s := range sums {
+		gotSums = append(gotSums, s.Module)
+		if !strings.HasPrefix(s.Hash, "h1:") || !strings.HasPrefix(s.GoModHash, "h1:") {
+			t.Errorf("unexpected checksums of %s: %s %s", s.Module, s.Hash, s.GoModHash)
+		}
+	}
+	if !reflect.DeepEqual(gotSums, wantSums) {
+		t.Errorf("unexpected summed modules:\n\t(GOT): %v\n\t(WNT): %v", gotSums, wantSums)
+	}
+	for _, lp := range p.Lock.Projects() {
+		if v, has := exported[lp.Ident().ProjectRoot]; has && v != lp.Version() {
+			t.Errorf("expected %s to be exported at %s, got %s", lp.Ident().ProjectRoot, lp.Version(), v)
+		}
+	}
+	if len(exported) != len(wantSums) {
+		t.Errorf("expected %d projects to be exported, got %d", len(wantSums), len(exported))
+	}
+
+	wantWarnings := `Warning: example.com/archive is sourced from release archives, which go.mod cannot refer to; it is not exported
+Warning: replacing github.com/foo/local with the local directory ` + localDir + `; the directory must contain a go.mod file
+Warning: requiring github.com/foo/major at v2.1.0+incompatible; this fails if github.com/foo/major has adopted Go modules at that version
+Warning: the constraint master on github.com/foo/branch cannot be represented in go.mod: go.mod cannot track a branch; the locked revision is required instead
+Warning: the constraint >=1.0.0, <1.5.0 on github.com/foo/range cannot be represented in go.mod: go.mod only records minimum versions; the upper bound is lost
+Warning: github.com/foo/unlocked is in Gopkg.toml, but not in Gopkg.lock; it is not exported
+`
+	if got := warnings.String(); got != wantWarnings {
+		t.Errorf("unexpected warnings:\n\t(GOT):\n%s\n\t(WNT):\n%s", got, wantWarnings)
+	}
+}
+
+func TestSourceModulePath(t *testing.T) {
+	cases := map[string]string{
+		"github.com/fork/bar":                  "github.com/fork/bar",
+		"https://github.com/fork/bar.git":      "github.com/fork/bar",
+		"https://github.com/fork/bar/":         "github.com/fork/bar",
+		"ssh://git@github.com/fork/bar.git":    "github.com/fork/bar",
+		"git@github.com:fork/bar.git":          "github.com/fork/bar",
+		"https://gopkg.in/fork/yaml.v2":        "gopkg.in/fork/yaml.v2",
+		"https://example.com/scm/repo/bar.git": "example.com/scm/repo/bar",
+	}
+
+	for source, want := range cases {
+		if got := sourceModulePath(source); got != want {
+			t.Errorf("sourceModulePath(%q) = %q, want %q", source, got, want)
+		}
+	}
+}
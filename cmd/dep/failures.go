@@ -6,6 +6,8 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"io"
 
 	"github.com/golang/dep/gps"
 	"github.com/pkg/errors"
@@ -21,3 +23,160 @@ func handleAllTheFailuresOfTheWorld(err error) error {
 
 	return errors.Wrap(err, "Solving failure")
 }
+
+// The kinds of solve failures reported in JSON.
+const (
+	failureNoVersion            = "no-version"
+	failureDisjointConstraint   = "disjoint-constraint"
+	failureConstraintNotAllowed = "constraint-not-allowed"
+	failureVersionNotAllowed    = "version-not-allowed"
+	failureProblemPackages      = "problem-packages"
+	failureOther                = "other"
+)
+
+// jsonSolveFailure is the JSON representation of a solve failure. Which fields
+// are populated depends on the kind of failure.
+type jsonSolveFailure struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+
+	Project    *jsonFailureProject `json:"project,omitempty"`
+	Depender   *jsonFailureAtom    `json:"depender,omitempty"`
+	Rejected   *jsonFailureAtom    `json:"rejected,omitempty"`
+	Constraint string              `json:"constraint,omitempty"`
+	Selected   string              `json:"selected,omitempty"`
+	Current    string              `json:"current,omitempty"`
+
+	Conflicting []jsonFailureConstraint `json:"conflicting,omitempty"`
+	Overlapping []jsonFailureConstraint `json:"overlapping,omitempty"`
+	Tried       []jsonFailedVersion     `json:"tried,omitempty"`
+	Packages    []jsonProblemPackage    `json:"packages,omitempty"`
+}
+
+type jsonFailureProject struct {
+	Root   string `json:"root"`
+	Source string `json:"source,omitempty"`
+}
+
+type jsonFailureAtom struct {
+	jsonFailureProject
+	Version  string `json:"version,omitempty"`
+	Revision string `json:"revision,omitempty"`
+}
+
+type jsonFailureConstraint struct {
+	Constraint   string          `json:"constraint"`
+	IntroducedBy jsonFailureAtom `json:"introducedBy"`
+}
+
+type jsonFailedVersion struct {
+	Version string            `json:"version"`
+	Failure *jsonSolveFailure `json:"failure"`
+}
+
+type jsonProblemPackage struct {
+	ImportPath string            `json:"importPath"`
+	Missing    bool              `json:"missing,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	RequiredBy []jsonFailureAtom `json:"requiredBy"`
+}
+
+// writeSolveFailureJSON writes the JSON representation of a solve failure.
+func writeSolveFailureJSON(w io.Writer, err error) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(toJSONSolveFailure(err))
+}
+
+func toJSONSolveFailure(err error) *jsonSolveFailure {
+	jf := &jsonSolveFailure{Message: err.Error()}
+
+	switch e := err.(type) {
+	case *gps.NoVersionError:
+		jf.Kind = failureNoVersion
+		jf.Project = toJSONFailureProject(e.Project)
+		for _, fv := range e.Tried {
+			jf.Tried = append(jf.Tried, jsonFailedVersion{
+				Version: fv.Version.String(),
+				Failure: toJSONSolveFailure(fv.Failure),
+			})
+		}
+	case *gps.DisjointConstraintFailure:
+		jf.Kind = failureDisjointConstraint
+		jf.Project = toJSONFailureProject(e.Project)
+		jf.Depender = toJSONFailureAtom(e.Depender)
+		jf.Constraint = e.Constraint.String()
+		if e.Current != nil {
+			jf.Current = e.Current.String()
+		}
+		jf.Conflicting = toJSONFailureConstraints(e.Conflicting)
+		jf.Overlapping = toJSONFailureConstraints(e.Overlapping)
+	case *gps.ConstraintNotAllowedFailure:
+		jf.Kind = failureConstraintNotAllowed
+		jf.Project = toJSONFailureProject(e.Project)
+		jf.Depender = toJSONFailureAtom(e.Depender)
+		jf.Constraint = e.Constraint.String()
+		jf.Selected = e.Selected.String()
+	case *gps.VersionNotAllowedFailure:
+		jf.Kind = failureVersionNotAllowed
+		jf.Rejected = toJSONFailureAtom(e.Rejected)
+		if e.Current != nil {
+			jf.Current = e.Current.String()
+		}
+		jf.Conflicting = toJSONFailureConstraints(e.Constraints)
+	case *gps.CheckeeHasProblemPackagesFailure:
+		jf.Kind = failureProblemPackages
+		jf.Rejected = toJSONFailureAtom(e.Rejected)
+		for _, pp := range e.Packages {
+			jpp := jsonProblemPackage{
+				ImportPath: pp.ImportPath,
+				Missing:    pp.Err == nil,
+				RequiredBy: []jsonFailureAtom{},
+			}
+			if pp.Err != nil {
+				jpp.Error = pp.Err.Error()
+			}
+			for _, a := range pp.RequiredBy {
+				jpp.RequiredBy = append(jpp.RequiredBy, *toJSONFailureAtom(a))
+			}
+			jf.Packages = append(jf.Packages, jpp)
+		}
+	default:
+		jf.Kind = failureOther
+	}
+
+	return jf
+}
+
+func toJSONFailureProject(id gps.ProjectIdentifier) *jsonFailureProject {
+	return &jsonFailureProject{
+		Root:   string(id.ProjectRoot),
+		Source: id.Source,
+	}
+}
+
+func toJSONFailureAtom(a gps.FailureAtom) *jsonFailureAtom {
+	ja := &jsonFailureAtom{jsonFailureProject: *toJSONFailureProject(a.Project)}
+	switch v := a.Version.(type) {
+	case nil:
+	case gps.PairedVersion:
+		ja.Version = v.String()
+		ja.Revision = v.Revision().String()
+	case gps.Revision:
+		ja.Revision = v.String()
+	default:
+		ja.Version = v.String()
+	}
+	return ja
+}
+
+func toJSONFailureConstraints(fcs []gps.FailureConstraint) []jsonFailureConstraint {
+	var jfcs []jsonFailureConstraint
+	for _, fc := range fcs {
+		jfcs = append(jfcs, jsonFailureConstraint{
+			Constraint:   fc.Constraint.String(),
+			IntroducedBy: *toJSONFailureAtom(fc.IntroducedBy),
+		})
+	}
+	return jfcs
+}
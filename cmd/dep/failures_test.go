@@ -0,0 +1,122 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/golang/dep/gps"
+)
+
+func TestWriteSolveFailureJSON(t *testing.T) {
+	mkc := func(body string) gps.Constraint {
+		c, err := gps.NewSemverConstraint(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	root := gps.FailureAtom{Project: gps.ProjectIdentifier{ProjectRoot: "example.com/root"}}
+	foo := gps.FailureAtom{
+		Project: gps.ProjectIdentifier{ProjectRoot: "github.com/foo/foo"},
+		Version: gps.NewVersion("v1.0.0").Pair("abc123"),
+	}
+	shared := gps.ProjectIdentifier{ProjectRoot: "github.com/shared/shared", Source: "https://example.com/shared"}
+
+	err := &gps.NoVersionError{
+		Project: foo.Project,
+		Tried: []gps.FailedVersion{
+			{
+				Version: foo.Version,
+				Failure: &gps.DisjointConstraintFailure{
+					Depender:   foo,
+					Project:    shared,
+					Constraint: mkc("^2.0.0"),
+					Conflicting: []gps.FailureConstraint{
+						{Constraint: mkc("^1.0.0"), IntroducedBy: root},
+					},
+					Current: mkc("^1.0.0"),
+				},
+			},
+			{
+				Version: gps.NewVersion("v0.9.0"),
+				Failure: &gps.CheckeeHasProblemPackagesFailure{
+					Rejected: gps.FailureAtom{Project: foo.Project, Version: gps.NewVersion("v0.9.0")},
+					Packages: []gps.ProblemPackage{
+						{ImportPath: "github.com/foo/foo/bar", RequiredBy: []gps.FailureAtom{root}},
+					},
+				},
+			},
+		},
+	}
+
+	want := `{
+  "kind": "no-version",
+  "message": "No versions of github.com/foo/foo met constraints:\n\tv1.0.0: Could not introduce github.com/foo/foo@v1.0.0, as it has a dependency on github.com/shared/shared (from https://example.com/shared) with constraint ^2.0.0, which has no overlap with existing constraint ^1.0.0 from (root)\n\tv0.9.0: Could not introduce github.com/foo/foo@v0.9.0, as its subpackage github.com/foo/foo/bar is missing. (Package is required by (root).)",
+  "project": {
+    "root": "github.com/foo/foo"
+  },
+  "tried": [
+    {
+      "version": "v1.0.0",
+      "failure": {
+        "kind": "disjoint-constraint",
+        "message": "Could not introduce github.com/foo/foo@v1.0.0, as it has a dependency on github.com/shared/shared (from https://example.com/shared) with constraint ^2.0.0, which has no overlap with existing constraint ^1.0.0 from (root)",
+        "project": {
+          "root": "github.com/shared/shared",
+          "source": "https://example.com/shared"
+        },
+        "depender": {
+          "root": "github.com/foo/foo",
+          "version": "v1.0.0",
+          "revision": "abc123"
+        },
+        "constraint": "^2.0.0",
+        "current": "^1.0.0",
+        "conflicting": [
+          {
+            "constraint": "^1.0.0",
+            "introducedBy": {
+              "root": "example.com/root"
+            }
+          }
+        ]
+      }
+    },
+    {
+      "version": "v0.9.0",
+      "failure": {
+        "kind": "problem-packages",
+        "message": "Could not introduce github.com/foo/foo@v0.9.0, as its subpackage github.com/foo/foo/bar is missing. (Package is required by (root).)",
+        "rejected": {
+          "root": "github.com/foo/foo",
+          "version": "v0.9.0"
+        },
+        "packages": [
+          {
+            "importPath": "github.com/foo/foo/bar",
+            "missing": true,
+            "requiredBy": [
+              {
+                "root": "example.com/root"
+              }
+            ]
+          }
+        ]
+      }
+    }
+  ]
+}
+`
+
+	var buf bytes.Buffer
+	if err := writeSolveFailureJSON(&buf, err); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want {
+		t.Fatalf("unexpected JSON:\n\t(GOT): %s\n\t(WNT): %s", buf.String(), want)
+	}
+}
@@ -27,7 +27,7 @@ specified, use the current directory.
 When configuration for another dependency management tool is detected, it is
 imported into the initial manifest and lock. Use the -skip-tools flag to
 disable this behavior. The following external tools are supported:
-glide, godep, vndr, govend, gb, gvt, govendor, glock.
+glide, godep, vndr, govend, gb, gvt, govendor, glock, and go.mod files.
 
 Any dependencies that are not constrained by external configuration use the
 GOPATH analysis below.
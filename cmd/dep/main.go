@@ -18,8 +18,10 @@ import (
 	"runtime/pprof"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
 	"github.com/golang/dep/internal/fs"
 )
 
@@ -89,7 +91,13 @@ func (c *Config) Run() int {
 		&initCommand{},
 		&statusCommand{},
 		&ensureCommand{},
+		&checkCommand{},
+		&whyCommand{},
+		&removeCommand{},
 		&pruneCommand{},
+		&cacheCommand{},
+		&exportCommand{},
+		&sbomCommand{},
 		&hashinCommand{},
 		&versionCommand{},
 	}
@@ -184,6 +192,7 @@ func (c *Config) Run() int {
 			flags := flag.NewFlagSet(cmdName, flag.ContinueOnError)
 			flags.SetOutput(c.Stderr)
 			verbose := flags.Bool("v", false, "enable verbose logging")
+			offline := flags.Bool("offline", false, "use only sources already in the cache, without contacting the network")
 
 			// Register the subcommand flags in there, too.
 			cmd.Register(flags)
@@ -216,12 +225,55 @@ func (c *Config) Run() int {
 				}
 			}
 
+			// go-get metadata is cached for a day, unless DEPDEDUCTIONTTL says
+			// otherwise; a zero duration disables the cache.
+			deductionTTL := 24 * time.Hour
+			if ttl := getEnv(c.Env, "DEPDEDUCTIONTTL"); ttl != "" {
+				d, err := time.ParseDuration(ttl)
+				if err != nil {
+					errLogger.Printf("dep: invalid DEPDEDUCTIONTTL: %v\n", err)
+					return errorExitCode
+				}
+				deductionTTL = d
+			}
+
+			// Mirrors come from the dep config file and DEPMIRRORS, the latter
+			// taking precedence.
+			mirrors, err := dep.ParseMirrors(getEnv(c.Env, "DEPMIRRORS"))
+			if err != nil {
+				errLogger.Printf("dep: invalid DEPMIRRORS: %v\n", err)
+				return errorExitCode
+			}
+			var (
+				rules  []gps.DeductionRule
+				forges []gps.Forge
+			)
+			if path := getEnv(c.Env, "DEPCONFIG"); path != "" {
+				config, err := dep.ReadConfig(path)
+				if err != nil {
+					errLogger.Printf("dep: %v\n", err)
+					return errorExitCode
+				}
+				mirrors = append(mirrors, config.Mirrors...)
+				rules = config.DeductionRules
+				forges = config.Forges
+			}
+
 			// Set up dep context.
 			ctx := &dep.Ctx{
 				Out:            outLogger,
 				Err:            errLogger,
 				Verbose:        *verbose,
 				DisableLocking: getEnv(c.Env, "DEPNOLOCK") != "",
+				Offline:        *offline || getEnv(c.Env, "DEPOFFLINE") != "",
+				CI:             getEnv(c.Env, "DEPCI") != "",
+				Proxy:          getEnv(c.Env, "DEPPROXY"),
+				Mirrors:        mirrors,
+				DeductionRules: rules,
+				Forges:         forges,
+				DeductionTTL:   deductionTTL,
+				Retry:          gps.DefaultRetryPolicy,
+				Memoize:        getEnv(c.Env, "DEPMEMOIZE") != "",
 				Cachedir:       cachedir,
 			}
 
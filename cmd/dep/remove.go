@@ -0,0 +1,208 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/pkgtree"
+	"github.com/pkg/errors"
+)
+
+const removeShortHelp = `Remove a dependency from the project`
+const removeLongHelp = `
+Remove drops the named projects from Gopkg.toml: their [[constraint]] and
+[[override]] stanzas are deleted, along with any entries in the required list
+for packages within them. The project is then re-solved, and Gopkg.lock and
+vendor/ are updated accordingly.
+
+Remove refuses to drop a project that the current project still imports, as it
+would just be pulled back in by the solver. Pass -f to drop its rules from
+Gopkg.toml anyway.
+
+Gopkg.toml is rewritten in its entirety; comments in it are not preserved.
+`
+
+func (cmd *removeCommand) Name() string { return "remove" }
+func (cmd *removeCommand) Args() string {
+	return "[-dry-run] [-f] <spec>..."
+}
+func (cmd *removeCommand) ShortHelp() string { return removeShortHelp }
+func (cmd *removeCommand) LongHelp() string  { return removeLongHelp }
+func (cmd *removeCommand) Hidden() bool      { return false }
+
+func (cmd *removeCommand) Register(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.dryRun, "dry-run", false, "only report the changes that would be made")
+	fs.BoolVar(&cmd.force, "f", false, "remove projects from Gopkg.toml even if they are still imported")
+}
+
+type removeCommand struct {
+	dryRun bool
+	force  bool
+}
+
+var errRemoveStillImported = errors.New("cannot remove projects that are still imported; remove the imports first, or pass -f")
+
+func (cmd *removeCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) == 0 {
+		return errors.New("must specify at least one project to remove")
+	}
+
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	sm.UseDefaultSignalHandling()
+	defer sm.Release()
+
+	ptree, err := p.ParseRootPackageTree()
+	if err != nil {
+		return err
+	}
+
+	roots := make(map[gps.ProjectRoot]bool, len(args))
+	for _, arg := range args {
+		pr, err := sm.DeduceProjectRoot(strings.TrimSuffix(arg, "/"))
+		if err != nil {
+			return errors.Wrapf(err, "could not determine project root for %s", arg)
+		}
+		if !inManifest(p.Manifest, pr) && !inLock(p.Lock, pr) {
+			return errors.Errorf("%s is not a dependency of this project", pr)
+		}
+		roots[pr] = true
+	}
+
+	var stillImported bool
+	ig := p.Manifest.IgnoredPackages()
+	for pr := range roots {
+		importers := importersOf(ptree, ig, pr)
+		if len(importers) == 0 {
+			continue
+		}
+
+		stillImported = true
+		ctx.Err.Printf("Warning: %s is still imported by:\n", pr)
+		for _, imp := range importers {
+			ctx.Err.Println("  ", imp)
+		}
+	}
+	if stillImported && !cmd.force {
+		return errRemoveStillImported
+	}
+
+	for pr := range roots {
+		removeFromManifest(p.Manifest, pr)
+	}
+
+	params := p.MakeParams()
+	params.RootPackageTree = ptree
+	if ctx.Verbose {
+		params.TraceLogger = ctx.Err
+	}
+
+	solver, err := gps.Prepare(params, sm)
+	if err != nil {
+		return errors.Wrap(err, "prepare solver")
+	}
+	solution, err := solver.Solve(context.TODO())
+	if err != nil {
+		return handleAllTheFailuresOfTheWorld(err)
+	}
+
+	sw, err := dep.NewSafeWriter(p.Manifest, p.Lock, dep.LockFromSolution(solution), dep.VendorOnChanged, p.Manifest.PruneOptions)
+	if err != nil {
+		return err
+	}
+
+	if cmd.dryRun {
+		return sw.PrintPreparedActions(ctx.Out, ctx.Verbose)
+	}
+
+	var logger *log.Logger
+	if ctx.Verbose {
+		logger = ctx.Err
+	}
+	return errors.Wrap(sw.Write(p.AbsRoot, sm, false, logger), "grouped write of manifest, lock and vendor")
+}
+
+// inProject reports whether the import path is within the project rooted at pr.
+func inProject(path string, pr gps.ProjectRoot) bool {
+	return path == string(pr) || strings.HasPrefix(path, string(pr)+"/")
+}
+
+// inManifest reports whether the manifest has any rules for the project.
+func inManifest(m *dep.Manifest, pr gps.ProjectRoot) bool {
+	if _, has := m.Constraints[pr]; has {
+		return true
+	}
+	if _, has := m.Ovr[pr]; has {
+		return true
+	}
+	for _, req := range m.Required {
+		if inProject(req, pr) {
+			return true
+		}
+	}
+	return false
+}
+
+// inLock reports whether the project is in the lock.
+func inLock(l *dep.Lock, pr gps.ProjectRoot) bool {
+	if l == nil {
+		return false
+	}
+	for _, lp := range l.Projects() {
+		if lp.Ident().ProjectRoot == pr {
+			return true
+		}
+	}
+	return false
+}
+
+// removeFromManifest deletes the constraint and override for the project from
+// the manifest, as well as any required packages within it.
+func removeFromManifest(m *dep.Manifest, pr gps.ProjectRoot) {
+	delete(m.Constraints, pr)
+	delete(m.Ovr, pr)
+
+	var required []string
+	for _, req := range m.Required {
+		if !inProject(req, pr) {
+			required = append(required, req)
+		}
+	}
+	m.Required = required
+}
+
+// importersOf returns the sorted list of non-ignored root packages that import,
+// including from tests, any package within the project rooted at pr.
+func importersOf(ptree pkgtree.PackageTree, ig *pkgtree.IgnoredRuleset, pr gps.ProjectRoot) []string {
+	var importers []string
+	for path, poe := range ptree.Packages {
+		if poe.Err != nil || ig.IsIgnored(path) {
+			continue
+		}
+
+		for _, imp := range append(poe.P.Imports[:len(poe.P.Imports):len(poe.P.Imports)], poe.P.TestImports...) {
+			if inProject(imp, pr) && !ig.IsIgnored(imp) {
+				importers = append(importers, path)
+				break
+			}
+		}
+	}
+	sort.Strings(importers)
+	return importers
+}
@@ -0,0 +1,66 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/pkgtree"
+)
+
+func TestRemoveFromManifest(t *testing.T) {
+	m := dep.NewManifest()
+	m.Constraints["github.com/foo/bar"] = gps.ProjectProperties{Constraint: gps.NewBranch("master")}
+	m.Constraints["github.com/foo/barbaz"] = gps.ProjectProperties{Constraint: gps.NewBranch("master")}
+	m.Ovr["github.com/foo/bar"] = gps.ProjectProperties{Source: "https://example.com/foo/bar"}
+	m.Required = []string{"github.com/foo/bar", "github.com/foo/bar/cmd/bar", "github.com/foo/barbaz"}
+
+	pr := gps.ProjectRoot("github.com/foo/bar")
+	if !inManifest(m, pr) {
+		t.Fatalf("expected %s to be in the manifest", pr)
+	}
+
+	removeFromManifest(m, pr)
+
+	if inManifest(m, pr) {
+		t.Fatalf("expected %s to no longer be in the manifest", pr)
+	}
+	if _, has := m.Constraints["github.com/foo/barbaz"]; !has {
+		t.Fatal("expected the constraint on a project with a common prefix to be kept")
+	}
+	if want := []string{"github.com/foo/barbaz"}; !reflect.DeepEqual(m.Required, want) {
+		t.Fatalf("unexpected required list:\n\t(GOT): %v\n\t(WNT): %v", m.Required, want)
+	}
+}
+
+func TestImportersOf(t *testing.T) {
+	ptree := pkgtree.PackageTree{
+		ImportRoot: "example.com/root",
+		Packages: map[string]pkgtree.PackageOrErr{
+			"example.com/root": {
+				P: pkgtree.Package{Imports: []string{"fmt", "github.com/foo/bar/baz"}},
+			},
+			"example.com/root/tested": {
+				P: pkgtree.Package{TestImports: []string{"github.com/foo/bar"}},
+			},
+			"example.com/root/ignored": {
+				P: pkgtree.Package{Imports: []string{"github.com/foo/bar"}},
+			},
+			"example.com/root/other": {
+				P: pkgtree.Package{Imports: []string{"github.com/foo/barbaz"}},
+			},
+		},
+	}
+	ig := pkgtree.NewIgnoredRuleset([]string{"example.com/root/ignored"})
+
+	got := importersOf(ptree, ig, "github.com/foo/bar")
+	want := []string{"example.com/root", "example.com/root/tested"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected importers:\n\t(GOT): %v\n\t(WNT): %v", got, want)
+	}
+}
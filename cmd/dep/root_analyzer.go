@@ -203,10 +203,12 @@ func (a *rootAnalyzer) FinalizeRootManifestAndLock(m *dep.Manifest, l *dep.Lock,
 	}
 }
 
-// Info provides metadata on the analyzer algorithm used during solve.
+// Info provides metadata on the analyzer algorithm used during solve. It
+// matches dep.Analyzer, which analyzes the dependencies once the project is
+// initialized.
 func (a *rootAnalyzer) Info() gps.ProjectAnalyzerInfo {
 	return gps.ProjectAnalyzerInfo{
 		Name:    "dep",
-		Version: 1,
+		Version: 2,
 	}
 }
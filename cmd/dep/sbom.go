@@ -0,0 +1,590 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+const sbomShortHelp = `Write a software bill of materials for the project's dependencies`
+const sbomLongHelp = `
+Sbom writes a software bill of materials (SBOM) listing the projects in
+Gopkg.lock to standard output, in one of these formats:
+
+  spdx       SPDX 2.3 tag-value
+  spdx-json  SPDX 2.3 JSON
+  cyclonedx  CycloneDX 1.4 JSON
+
+Each project is recorded with its locked version and revision, the URL of its
+source, the packages used from it, and its license. Licenses are detected from
+the license files of the project in vendor/, or, for projects not vendored, in
+a copy exported from the cache. Only common licenses are recognized; any other
+is recorded as NOASSERTION, to be reviewed.
+`
+
+// noAssertion is what SPDX records for information that is unknown.
+const noAssertion = "NOASSERTION"
+
+func (cmd *sbomCommand) Name() string      { return "sbom" }
+func (cmd *sbomCommand) Args() string      { return "[-format=spdx|spdx-json|cyclonedx]" }
+func (cmd *sbomCommand) ShortHelp() string { return sbomShortHelp }
+func (cmd *sbomCommand) LongHelp() string  { return sbomLongHelp }
+func (cmd *sbomCommand) Hidden() bool      { return false }
+
+func (cmd *sbomCommand) Register(fs *flag.FlagSet) {
+	fs.StringVar(&cmd.format, "format", "spdx", "format of the SBOM: spdx, spdx-json or cyclonedx")
+}
+
+type sbomCommand struct {
+	format string
+}
+
+func (cmd *sbomCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) > 0 {
+		return errors.New("dep sbom takes no arguments")
+	}
+	var write func(io.Writer, *sbom) error
+	switch cmd.format {
+	case "spdx":
+		write = writeSPDX
+	case "spdx-json":
+		write = writeSPDXJSON
+	case "cyclonedx":
+		write = writeCycloneDX
+	default:
+		return errors.Errorf("unsupported SBOM format %q; use spdx, spdx-json or cyclonedx", cmd.format)
+	}
+
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+	if p.Lock == nil {
+		return errors.Errorf("no %s found; run dep ensure to generate one", dep.LockName)
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	sm.UseDefaultSignalHandling()
+	defer sm.Release()
+
+	b := &sbomBuilder{
+		sourceURL: func(id gps.ProjectIdentifier) (string, error) {
+			return sbomSourceURL(sm, id)
+		},
+		license: func(lp gps.LockedProject) (string, error) {
+			return projectLicense(sm, filepath.Join(p.AbsRoot, "vendor"), lp)
+		},
+		logger:  ctx.Err,
+		created: time.Now(),
+	}
+	var buf bytes.Buffer
+	if err := write(&buf, b.build(p)); err != nil {
+		return err
+	}
+	ctx.Out.Print(buf.String())
+	return nil
+}
+
+// sbom is a software bill of materials for a project, independent of the
+// format it is written in.
+type sbom struct {
+	root     string
+	digest   []byte
+	created  time.Time
+	projects []sbomProject
+}
+
+// sbomProject is a project in Gopkg.lock, as recorded in an SBOM.
+type sbomProject struct {
+	root      string
+	version   string // The locked version, or the revision if there is none.
+	revision  string
+	sourceURL string   // noAssertion if it is unknown.
+	packages  []string // Import paths of the packages used.
+	license   string   // SPDX license identifier, or noAssertion.
+}
+
+// purl returns the package URL identifying the project at its version.
+func (sp sbomProject) purl() string {
+	return "pkg:golang/" + sp.root + "@" + sp.version
+}
+
+// sbomBuilder gathers the information about the projects in a lock that goes
+// into an SBOM.
+type sbomBuilder struct {
+	sourceURL func(gps.ProjectIdentifier) (string, error)
+	license   func(gps.LockedProject) (string, error)
+	logger    *log.Logger
+	created   time.Time
+}
+
+func (b *sbomBuilder) build(p *dep.Project) *sbom {
+	s := &sbom{
+		root:    string(p.ImportRoot),
+		digest:  p.Lock.SolveMeta.InputsDigest,
+		created: b.created.UTC(),
+	}
+
+	for _, lp := range p.Lock.Projects() {
+		id := lp.Ident()
+		sp := sbomProject{
+			root:      string(id.ProjectRoot),
+			sourceURL: noAssertion,
+			license:   noAssertion,
+		}
+
+		switch v := lp.Version().(type) {
+		case gps.PairedVersion:
+			sp.version = v.String()
+			sp.revision = string(v.Revision())
+		case gps.Revision:
+			sp.version = string(v)
+			sp.revision = string(v)
+		case gps.UnpairedVersion:
+			sp.version = v.String()
+		}
+
+		for _, pkg := range lp.Packages() {
+			if pkg == "." {
+				sp.packages = append(sp.packages, sp.root)
+			} else {
+				sp.packages = append(sp.packages, sp.root+"/"+pkg)
+			}
+		}
+
+		if u, err := b.sourceURL(id); err != nil {
+			b.logger.Printf("Warning: could not determine the source URL of %s: %v\n", id.ProjectRoot, err)
+		} else if u != "" {
+			sp.sourceURL = u
+		}
+		if l, err := b.license(lp); err != nil {
+			b.logger.Printf("Warning: could not detect the license of %s: %v\n", id.ProjectRoot, err)
+		} else if l != "" {
+			sp.license = l
+		}
+
+		s.projects = append(s.projects, sp)
+	}
+
+	sort.Slice(s.projects, func(i, j int) bool {
+		return s.projects[i].root < s.projects[j].root
+	})
+	return s
+}
+
+// sbomSourceURL returns the URL of the source of the project id: its source
+// if that is a URL, or else the first URL deduced from it, or from the project
+// root. Local directories have no URL.
+func sbomSourceURL(sm gps.SourceManager, id gps.ProjectIdentifier) (string, error) {
+	if gps.IsLocalSource(id.Source) {
+		return "", nil
+	}
+	if strings.Contains(id.Source, "://") {
+		return id.Source, nil
+	}
+
+	path := string(id.ProjectRoot)
+	if id.Source != "" {
+		path = id.Source
+	}
+	urls, err := sm.SourceURLsForPath(path)
+	if err != nil {
+		return "", err
+	}
+	if len(urls) == 0 {
+		return "", errors.Errorf("no source URLs for %s", path)
+	}
+	return urls[0].String(), nil
+}
+
+// projectLicense detects the license of the locked project from its copy
+// under vendor, or, if it is not there, a copy exported from sm.
+func projectLicense(sm gps.SourceManager, vendor string, lp gps.LockedProject) (string, error) {
+	dir := filepath.Join(vendor, filepath.FromSlash(string(lp.Ident().ProjectRoot)))
+	if _, err := os.Stat(dir); err == nil {
+		return detectLicense(dir)
+	}
+
+	tmp, err := ioutil.TempDir("", "dep-sbom")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	dir = filepath.Join(tmp, "src")
+	if err := sm.ExportProject(context.TODO(), lp.Ident(), lp.Version(), dir); err != nil {
+		return "", err
+	}
+	return detectLicense(dir)
+}
+
+// licenseFilePrefixes are the prefixes of the lower-cased names of the files
+// that licenses are detected from.
+var licenseFilePrefixes = []string{"license", "licence", "copying", "unlicense"}
+
+// licenseTexts recognize the licenses of projects by phrases of their texts,
+// with whitespace collapsed. Licenses that contain the phrases of others come
+// first.
+var licenseTexts = []struct {
+	id      string
+	phrases []string
+}{
+	{"Apache-2.0", []string{"Apache License", "Version 2.0"}},
+	{"MPL-2.0", []string{"Mozilla Public License", "2.0"}},
+	{"BSD-3-Clause", []string{"Redistribution and use in source and binary forms", "Neither the name"}},
+	{"BSD-2-Clause", []string{"Redistribution and use in source and binary forms"}},
+	{"MIT", []string{"Permission is hereby granted, free of charge"}},
+	{"ISC", []string{"Permission to use, copy, modify, and/or distribute this software for any purpose with or without fee is hereby granted"}},
+	{"Unlicense", []string{"This is free and unencumbered software released into the public domain"}},
+}
+
+var whitespaceRE = regexp.MustCompile(`\s+`)
+
+// detectLicense returns the SPDX identifier of the license in the top-level
+// license files of dir, or "" if there is none it recognizes.
+func detectLicense(dir string) (string, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, fi := range fis {
+		if fi.IsDir() || !hasLicenseFilePrefix(fi.Name()) {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return "", err
+		}
+		text := whitespaceRE.ReplaceAllString(string(b), " ")
+
+	licenses:
+		for _, l := range licenseTexts {
+			for _, phrase := range l.phrases {
+				if !strings.Contains(text, phrase) {
+					continue licenses
+				}
+			}
+			return l.id, nil
+		}
+	}
+	return "", nil
+}
+
+func hasLicenseFilePrefix(name string) bool {
+	name = strings.ToLower(name)
+	for _, prefix := range licenseFilePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// spdxID returns an SPDX element identifier for name, which may only contain
+// letters, numbers, . and -.
+func spdxID(name string) string {
+	return "SPDXRef-Package-" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		}
+		return '-'
+	}, name)
+}
+
+// spdxNamespace returns the unique URI of the SPDX document for s. It is
+// derived from the inputs digest, so that the same lock always yields the
+// same document.
+func (s *sbom) spdxNamespace() string {
+	return fmt.Sprintf("https://spdx.org/spdxdocs/%s-%s", strings.Replace(s.root, "/", "-", -1), hex.EncodeToString(s.digest))
+}
+
+// urlNamespace is the RFC 4122 namespace of name-based UUIDs for URLs.
+var urlNamespace = []byte{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+// serialNumber returns a URN holding the name-based UUID of the SPDX
+// namespace of s, for CycloneDX.
+func (s *sbom) serialNumber() string {
+	h := sha1.New()
+	h.Write(urlNamespace)
+	io.WriteString(h, s.spdxNamespace())
+	u := h.Sum(nil)[:16]
+	u[6] = u[6]&0x0f | 0x50 // Version 5, name-based with SHA-1.
+	u[8] = u[8]&0x3f | 0x80 // RFC 4122 variant.
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// writeSPDX writes s as an SPDX tag-value document.
+func writeSPDX(w io.Writer, s *sbom) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "SPDXVersion: SPDX-2.3\n")
+	fmt.Fprintf(&buf, "DataLicense: CC0-1.0\n")
+	fmt.Fprintf(&buf, "SPDXID: SPDXRef-DOCUMENT\n")
+	fmt.Fprintf(&buf, "DocumentName: %s\n", s.root)
+	fmt.Fprintf(&buf, "DocumentNamespace: %s\n", s.spdxNamespace())
+	fmt.Fprintf(&buf, "Creator: Tool: dep\n")
+	fmt.Fprintf(&buf, "Created: %s\n", s.created.Format(time.RFC3339))
+
+	fmt.Fprintf(&buf, "\nPackageName: %s\n", s.root)
+	fmt.Fprintf(&buf, "SPDXID: %s\n", spdxID(s.root))
+	fmt.Fprintf(&buf, "PackageDownloadLocation: %s\n", noAssertion)
+	fmt.Fprintf(&buf, "FilesAnalyzed: false\n")
+	fmt.Fprintf(&buf, "PrimaryPackagePurpose: APPLICATION\n")
+
+	for _, sp := range s.projects {
+		fmt.Fprintf(&buf, "\nPackageName: %s\n", sp.root)
+		fmt.Fprintf(&buf, "SPDXID: %s\n", spdxID(sp.root))
+		fmt.Fprintf(&buf, "PackageVersion: %s\n", sp.version)
+		fmt.Fprintf(&buf, "PackageDownloadLocation: %s\n", sp.sourceURL)
+		fmt.Fprintf(&buf, "FilesAnalyzed: false\n")
+		if sp.revision != "" {
+			fmt.Fprintf(&buf, "PackageSourceInfo: revision %s\n", sp.revision)
+		}
+		fmt.Fprintf(&buf, "PackageLicenseConcluded: %s\n", noAssertion)
+		fmt.Fprintf(&buf, "PackageLicenseDeclared: %s\n", sp.license)
+		fmt.Fprintf(&buf, "PackageCopyrightText: %s\n", noAssertion)
+		fmt.Fprintf(&buf, "PackageComment: <text>Packages used:\n%s</text>\n", strings.Join(sp.packages, "\n"))
+		fmt.Fprintf(&buf, "ExternalRef: PACKAGE-MANAGER purl %s\n", sp.purl())
+		fmt.Fprintf(&buf, "PrimaryPackagePurpose: LIBRARY\n")
+	}
+
+	fmt.Fprintf(&buf, "\nRelationship: SPDXRef-DOCUMENT DESCRIBES %s\n", spdxID(s.root))
+	for _, sp := range s.projects {
+		fmt.Fprintf(&buf, "Relationship: %s DEPENDS_ON %s\n", spdxID(s.root), spdxID(sp.root))
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Creators []string `json:"creators"`
+	Created  string   `json:"created"`
+}
+
+type spdxPackage struct {
+	Name                  string            `json:"name"`
+	SPDXID                string            `json:"SPDXID"`
+	VersionInfo           string            `json:"versionInfo,omitempty"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	SourceInfo            string            `json:"sourceInfo,omitempty"`
+	LicenseConcluded      string            `json:"licenseConcluded,omitempty"`
+	LicenseDeclared       string            `json:"licenseDeclared,omitempty"`
+	CopyrightText         string            `json:"copyrightText,omitempty"`
+	Comment               string            `json:"comment,omitempty"`
+	ExternalRefs          []spdxExternalRef `json:"externalRefs,omitempty"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// writeSPDXJSON writes s as an SPDX JSON document.
+func writeSPDXJSON(w io.Writer, s *sbom) error {
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              s.root,
+		DocumentNamespace: s.spdxNamespace(),
+		CreationInfo: spdxCreationInfo{
+			Creators: []string{"Tool: dep"},
+			Created:  s.created.Format(time.RFC3339),
+		},
+		Packages: []spdxPackage{{
+			Name:                  s.root,
+			SPDXID:                spdxID(s.root),
+			DownloadLocation:      noAssertion,
+			PrimaryPackagePurpose: "APPLICATION",
+		}},
+		Relationships: []spdxRelationship{{
+			SPDXElementID:      "SPDXRef-DOCUMENT",
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: spdxID(s.root),
+		}},
+	}
+
+	for _, sp := range s.projects {
+		pkg := spdxPackage{
+			Name:             sp.root,
+			SPDXID:           spdxID(sp.root),
+			VersionInfo:      sp.version,
+			DownloadLocation: sp.sourceURL,
+			LicenseConcluded: noAssertion,
+			LicenseDeclared:  sp.license,
+			CopyrightText:    noAssertion,
+			Comment:          "Packages used:\n" + strings.Join(sp.packages, "\n"),
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  sp.purl(),
+			}},
+			PrimaryPackagePurpose: "LIBRARY",
+		}
+		if sp.revision != "" {
+			pkg.SourceInfo = "revision " + sp.revision
+		}
+		doc.Packages = append(doc.Packages, pkg)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      spdxID(s.root),
+			RelationshipType:   "DEPENDS_ON",
+			RelatedSPDXElement: spdxID(sp.root),
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+type cdxBOM struct {
+	BOMFormat    string          `json:"bomFormat"`
+	SpecVersion  string          `json:"specVersion"`
+	SerialNumber string          `json:"serialNumber"`
+	Version      int             `json:"version"`
+	Metadata     cdxMetadata     `json:"metadata"`
+	Components   []cdxComponent  `json:"components"`
+	Dependencies []cdxDependency `json:"dependencies"`
+}
+
+type cdxMetadata struct {
+	Timestamp string       `json:"timestamp"`
+	Tools     []cdxTool    `json:"tools"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxTool struct {
+	Vendor string `json:"vendor"`
+	Name   string `json:"name"`
+}
+
+type cdxComponent struct {
+	Type               string           `json:"type"`
+	BOMRef             string           `json:"bom-ref"`
+	Name               string           `json:"name"`
+	Version            string           `json:"version,omitempty"`
+	Licenses           []cdxLicense     `json:"licenses,omitempty"`
+	PURL               string           `json:"purl,omitempty"`
+	ExternalReferences []cdxExternalRef `json:"externalReferences,omitempty"`
+	Properties         []cdxProperty    `json:"properties,omitempty"`
+}
+
+type cdxLicense struct {
+	License struct {
+		ID string `json:"id"`
+	} `json:"license"`
+}
+
+type cdxExternalRef struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// writeCycloneDX writes s as a CycloneDX JSON document.
+func writeCycloneDX(w io.Writer, s *sbom) error {
+	root := cdxComponent{
+		Type:   "application",
+		BOMRef: "pkg:golang/" + s.root,
+		Name:   s.root,
+	}
+	bom := cdxBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.4",
+		SerialNumber: s.serialNumber(),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: s.created.Format(time.RFC3339),
+			Tools:     []cdxTool{{Vendor: "golang", Name: "dep"}},
+			Component: root,
+		},
+		Components: []cdxComponent{},
+	}
+
+	deps := cdxDependency{Ref: root.BOMRef, DependsOn: []string{}}
+	for _, sp := range s.projects {
+		c := cdxComponent{
+			Type:    "library",
+			BOMRef:  sp.purl(),
+			Name:    sp.root,
+			Version: sp.version,
+			PURL:    sp.purl(),
+		}
+		if sp.license != noAssertion {
+			var l cdxLicense
+			l.License.ID = sp.license
+			c.Licenses = []cdxLicense{l}
+		}
+		if sp.sourceURL != noAssertion {
+			c.ExternalReferences = []cdxExternalRef{{Type: "vcs", URL: sp.sourceURL}}
+		}
+		if sp.revision != "" {
+			c.Properties = append(c.Properties, cdxProperty{Name: "dep:revision", Value: sp.revision})
+		}
+		for _, pkg := range sp.packages {
+			c.Properties = append(c.Properties, cdxProperty{Name: "dep:package", Value: pkg})
+		}
+		bom.Components = append(bom.Components, c)
+		deps.DependsOn = append(deps.DependsOn, c.BOMRef)
+	}
+	bom.Dependencies = []cdxDependency{deps}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(bom)
+}
@@ -0,0 +1,163 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/internal/test"
+	"github.com/pkg/errors"
+)
+
+func TestSBOMGolden(t *testing.T) {
+	h := test.NewHelper(t)
+	h.Parallel()
+	defer h.Cleanup()
+
+	f := h.GetTestFile("sbom/Gopkg.lock")
+	l, err := dep.ReadLock(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &dep.Project{
+		ImportRoot: "github.com/golang/notexist",
+		Lock:       l,
+	}
+
+	licenses := map[gps.ProjectRoot]string{
+		"github.com/foo/branch":      "Apache-2.0",
+		"github.com/sdboyer/deptest": "MIT",
+	}
+	var stderr bytes.Buffer
+	b := &sbomBuilder{
+		sourceURL: func(id gps.ProjectIdentifier) (string, error) {
+			if id.ProjectRoot == "github.com/foo/rev" {
+				return "", errors.New("no network")
+			}
+			if id.Source != "" {
+				return "https://" + id.Source, nil
+			}
+			return "https://" + string(id.ProjectRoot), nil
+		},
+		license: func(lp gps.LockedProject) (string, error) {
+			return licenses[lp.Ident().ProjectRoot], nil
+		},
+		logger:  log.New(&stderr, "", 0),
+		created: time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+	s := b.build(p)
+
+	want := "Warning: could not determine the source URL of github.com/foo/rev: no network\n"
+	if stderr.String() != want {
+		t.Errorf("unexpected warnings:\n\t(GOT): %q\n\t(WNT): %q", stderr.String(), want)
+	}
+
+	formats := []struct {
+		golden string
+		write  func(io.Writer, *sbom) error
+	}{
+		{"sbom/golden.spdx", writeSPDX},
+		{"sbom/golden.spdx.json", writeSPDXJSON},
+		{"sbom/golden.cdx.json", writeCycloneDX},
+	}
+	for _, format := range formats {
+		t.Run(format.golden, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := format.write(&buf, s); err != nil {
+				t.Fatal(err)
+			}
+
+			if *test.UpdateGolden {
+				if err := h.WriteTestFile(format.golden, buf.String()); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if want := h.GetTestFileString(format.golden); buf.String() != want {
+				t.Errorf("unexpected %s:\n\t(GOT):\n%s\n\t(WNT):\n%s", format.golden, buf.String(), want)
+			}
+			if format.golden != "sbom/golden.spdx" && !json.Valid(buf.Bytes()) {
+				t.Errorf("expected %s to be valid JSON", format.golden)
+			}
+		})
+	}
+}
+
+func TestDetectLicense(t *testing.T) {
+	h := test.NewHelper(t)
+	h.Parallel()
+	defer h.Cleanup()
+
+	cases := []struct {
+		name, file, text, want string
+	}{
+		{"mit", "LICENSE", "MIT License\n\nPermission is hereby granted, free of\ncharge, to any person", "MIT"},
+		{"apache", "LICENSE.txt", "Apache License\n  Version 2.0, January 2004", "Apache-2.0"},
+		{"bsd3", "LICENSE", "Redistribution and use in source and binary forms, with or without\nmodification... Neither the name of Google Inc.", "BSD-3-Clause"},
+		{"bsd2", "COPYING", "Redistribution and use in source and binary\nforms, with or without modification", "BSD-2-Clause"},
+		{"mpl", "LICENSE.md", "Mozilla Public License Version 2.0\n==================================", "MPL-2.0"},
+		{"unknown", "LICENSE", "All rights reserved.", ""},
+		{"readme", "README.md", "Permission is hereby granted, free of charge", ""},
+	}
+	for _, c := range cases {
+		h.TempFile(c.name+"/"+c.file, c.text)
+		got, err := detectLicense(h.Path(c.name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("unexpected license of %s: %q, want %q", c.name, got, c.want)
+		}
+	}
+
+	if _, err := detectLicense(filepath.Join(h.Path("."), "missing")); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestSBOMSourceURL(t *testing.T) {
+	h := test.NewHelper(t)
+	h.Parallel()
+	defer h.Cleanup()
+
+	h.TempDir("src")
+	ctx := &dep.Ctx{
+		GOPATH: h.Path("."),
+		Out:    log.New(ioutil.Discard, "", 0),
+		Err:    log.New(ioutil.Discard, "", 0),
+	}
+	sm, err := ctx.SourceManager()
+	h.Must(err)
+	defer sm.Release()
+
+	cases := []struct {
+		id   gps.ProjectIdentifier
+		want string
+	}{
+		{gps.ProjectIdentifier{ProjectRoot: "github.com/foo/bar"}, "https://github.com/foo/bar"},
+		{gps.ProjectIdentifier{ProjectRoot: "github.com/foo/bar", Source: "github.com/fork/bar"}, "https://github.com/fork/bar"},
+		{gps.ProjectIdentifier{ProjectRoot: "github.com/foo/bar", Source: "https://git.example.com/bar.git"}, "https://git.example.com/bar.git"},
+		{gps.ProjectIdentifier{ProjectRoot: "github.com/foo/bar", Source: h.Path("src")}, ""},
+	}
+	for _, c := range cases {
+		got, err := sbomSourceURL(sm, c.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("unexpected source URL of %v: %q, want %q", c.id, got, c.want)
+		}
+	}
+}
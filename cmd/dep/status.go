@@ -17,6 +17,7 @@ import (
 	"sync"
 	"text/tabwriter"
 
+	"github.com/Masterminds/semver"
 	"github.com/golang/dep"
 	"github.com/golang/dep/gps"
 	"github.com/golang/dep/gps/paths"
@@ -34,6 +35,18 @@ With no arguments, print the status of each dependency of the project.
   LATEST      Latest VCS revision available
   PKGS USED   Number of packages from this project that are actually used
 
+With -old, print only the dependencies that have newer versions, and how
+they would be upgraded.
+
+  PROJECT     Import path
+  CONSTRAINT  Version constraint, from the manifest
+  VERSION     Version chosen, from the lock
+  ALLOWED     Newest version allowed by the constraint on the project
+  LATEST      Newest version overall
+  UPGRADE     Kind of upgrade to the newest version: patch, minor or major
+              for semver versions, or the number of commits the branch has
+              moved ahead; marked if the constraint or an override blocks it
+
 Status returns exit code zero if all dependencies are in a "good state".
 `
 
@@ -81,9 +94,16 @@ type outputter interface {
 	MissingFooter() error
 }
 
-type tableOutput struct{ w *tabwriter.Writer }
+type tableOutput struct {
+	w   *tabwriter.Writer
+	old bool // Whether to show upgrades, rather than revisions and packages.
+}
 
 func (out *tableOutput) BasicHeader() error {
+	if out.old {
+		_, err := fmt.Fprintf(out.w, "PROJECT\tCONSTRAINT\tVERSION\tALLOWED\tLATEST\tUPGRADE\n")
+		return err
+	}
 	_, err := fmt.Fprintf(out.w, "PROJECT\tCONSTRAINT\tVERSION\tREVISION\tLATEST\tPKGS USED\n")
 	return err
 }
@@ -93,6 +113,18 @@ func (out *tableOutput) BasicFooter() error {
 }
 
 func (out *tableOutput) BasicLine(bs *BasicStatus) error {
+	if out.old {
+		_, err := fmt.Fprintf(out.w,
+			"%s\t%s\t%s\t%s\t%s\t%s\t\n",
+			bs.ProjectRoot,
+			bs.getConsolidatedConstraint(),
+			bs.getConsolidatedVersion(),
+			formatVersion(bs.LatestAllowed),
+			formatVersion(bs.LatestOverall),
+			bs.getConsolidatedUpgrade(),
+		)
+		return err
+	}
 	_, err := fmt.Fprintf(out.w,
 		"%s\t%s\t%s\t%s\t%s\t%d\t\n",
 		bs.ProjectRoot,
@@ -236,8 +268,6 @@ func (cmd *statusCommand) Run(ctx *dep.Ctx, args []string) error {
 	switch {
 	case cmd.missing:
 		return errors.Errorf("not implemented")
-	case cmd.old:
-		return errors.Errorf("not implemented")
 	case cmd.json:
 		out = &jsonOutput{
 			w: &buf,
@@ -259,7 +289,8 @@ func (cmd *statusCommand) Run(ctx *dep.Ctx, args []string) error {
 		}
 	default:
 		out = &tableOutput{
-			w: tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0),
+			w:   tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0),
+			old: cmd.old,
 		}
 	}
 
@@ -268,7 +299,7 @@ func (cmd *statusCommand) Run(ctx *dep.Ctx, args []string) error {
 		return errors.Errorf("no Gopkg.lock found. Run `dep ensure` to generate lock file")
 	}
 
-	hasMissingPkgs, errCount, err := runStatusAll(ctx, out, p, sm)
+	hasMissingPkgs, errCount, err := runStatusAll(ctx, out, p, sm, cmd.old)
 	if err != nil {
 		switch err {
 		case errFailedUpdate:
@@ -295,10 +326,21 @@ func (cmd *statusCommand) Run(ctx *dep.Ctx, args []string) error {
 
 	// Print the status output
 	ctx.Out.Print(buf.String())
+	warnLocalSources(ctx, p.Lock)
 
 	return nil
 }
 
+// warnLocalSources warns about the projects in the lock that are sourced from
+// local directories, as the lock is of no use on other machines.
+func warnLocalSources(ctx *dep.Ctx, l *dep.Lock) {
+	for _, lp := range l.Projects() {
+		if id := lp.Ident(); gps.IsLocalSource(id.Source) {
+			ctx.Err.Printf("Warning: %s is sourced from the local directory %s\n", id.ProjectRoot, id.Source)
+		}
+	}
+}
+
 func (cmd *statusCommand) validateFlags() error {
 	// Operating mode flags.
 	var opModes []string
@@ -342,6 +384,14 @@ type rawStatus struct {
 	Revision     string
 	Latest       string
 	PackageCount int
+	LocalSource  string `json:",omitempty"`
+
+	// Upgrades, only reported with -old.
+	LatestAllowed string      `json:",omitempty"`
+	LatestOverall string      `json:",omitempty"`
+	Upgrade       UpgradeType `json:",omitempty"`
+	CommitsAhead  int         `json:",omitempty"`
+	BlockedBy     string      `json:",omitempty"`
 }
 
 // BasicStatus contains all the information reported about a single dependency
@@ -354,8 +404,129 @@ type BasicStatus struct {
 	Revision     gps.Revision
 	Latest       gps.Version
 	PackageCount int
-	hasOverride  bool
-	hasError     bool
+	// LocalSource is the directory the project is sourced from, if it is a
+	// local one rather than a repository.
+	LocalSource string
+	// LatestAllowed is the newest version of the project allowed by the
+	// constraint on it, and LatestOverall the newest one of any. They are
+	// of the same type as Version, and revisions for branches.
+	LatestAllowed gps.Version
+	LatestOverall gps.Version
+	// Upgrade is the kind of upgrade from Version to LatestOverall, if there
+	// is one; for a branch, it is CommitsAhead commits, if they could be
+	// counted.
+	Upgrade      UpgradeType
+	CommitsAhead int
+	// BlockedBy is "constraint" or "override" if the one on the project does
+	// not allow LatestOverall.
+	BlockedBy   string
+	hasOverride bool
+	hasError    bool
+}
+
+// UpgradeType is the kind of upgrade of a dependency to its newest version.
+type UpgradeType string
+
+// Kinds of upgrades.
+const (
+	NoUpgrade     UpgradeType = ""
+	PatchUpgrade  UpgradeType = "patch"
+	MinorUpgrade  UpgradeType = "minor"
+	MajorUpgrade  UpgradeType = "major"
+	BranchUpgrade UpgradeType = "branch"
+)
+
+// semverUpgrade returns the kind of upgrade from one semver version to
+// another, which is none unless to is the greater.
+func semverUpgrade(from, to gps.Version) UpgradeType {
+	if from == nil || to == nil {
+		return NoUpgrade
+	}
+	fv, err := semver.NewVersion(from.String())
+	if err != nil {
+		return NoUpgrade
+	}
+	tv, err := semver.NewVersion(to.String())
+	if err != nil || !tv.GreaterThan(fv) {
+		return NoUpgrade
+	}
+
+	switch {
+	case tv.Major() != fv.Major():
+		return MajorUpgrade
+	case tv.Minor() != fv.Minor():
+		return MinorUpgrade
+	}
+	return PatchUpgrade
+}
+
+// upgradeConstraint returns the constraint that upgrades of the project are
+// measured against: that of the root, along with those the dependencies in cm
+// put on the project, unless an override is in effect.
+func (bs *BasicStatus) upgradeConstraint(cm constraintsCollection) gps.Constraint {
+	c := bs.Constraint
+	if bs.hasOverride {
+		return c
+	}
+	for _, pc := range cm[bs.ProjectRoot] {
+		c = pc.Constraint.Intersect(c)
+	}
+	return c
+}
+
+// findUpgrade sets the newest versions of the project in vl, which is sorted
+// for upgrade, overall and as allowed by c, and the kind of upgrade to the
+// newest one. Only semver versions and branches can be upgraded.
+func (bs *BasicStatus) findUpgrade(vl []gps.PairedVersion, c gps.Constraint) {
+	if bs.Version == nil {
+		return
+	}
+
+	// Whether c allows the newest version.
+	var allowed bool
+	switch bs.Version.Type() {
+	case gps.IsSemver:
+		for _, v := range vl {
+			if v.Type() != gps.IsSemver {
+				continue
+			}
+			if bs.LatestOverall == nil {
+				bs.LatestOverall = v
+				allowed = c.Matches(v)
+			}
+			if c.Matches(v) {
+				bs.LatestAllowed = v
+				break
+			}
+		}
+		bs.Upgrade = semverUpgrade(bs.Version, bs.LatestOverall)
+	case gps.IsBranch:
+		for _, v := range vl {
+			if v.Type() == gps.IsBranch && v.String() == bs.Version.String() {
+				bs.LatestOverall = v.Revision()
+				if allowed = c.Matches(v); allowed {
+					bs.LatestAllowed = v.Revision()
+				}
+				if v.Revision() != bs.Revision {
+					bs.Upgrade = BranchUpgrade
+				}
+				break
+			}
+		}
+	}
+
+	if bs.Upgrade != NoUpgrade && !allowed {
+		bs.BlockedBy = "constraint"
+		if bs.hasOverride {
+			bs.BlockedBy = "override"
+		}
+	}
+}
+
+// isOld reports whether the project is shown by -old: if it can be upgraded,
+// or whether it can is unknown.
+func (bs *BasicStatus) isOld() bool {
+	return bs.Upgrade != NoUpgrade || bs.hasError
 }
 
 func (bs *BasicStatus) getConsolidatedConstraint() string {
@@ -371,6 +542,9 @@ func (bs *BasicStatus) getConsolidatedConstraint() string {
 	if bs.hasOverride {
 		constraint += " (override)"
 	}
+	if bs.LocalSource != "" {
+		constraint += " (local)"
+	}
 
 	return constraint
 }
@@ -401,6 +575,24 @@ func (bs *BasicStatus) getConsolidatedLatest(revSize uint8) string {
 	return latest
 }
 
+func (bs *BasicStatus) getConsolidatedUpgrade() string {
+	if bs.hasError {
+		return "unknown"
+	}
+
+	upgrade := string(bs.Upgrade)
+	if bs.Upgrade == BranchUpgrade && bs.CommitsAhead > 0 {
+		upgrade = fmt.Sprintf("%d commits", bs.CommitsAhead)
+		if bs.CommitsAhead == 1 {
+			upgrade = "1 commit"
+		}
+	}
+	if bs.BlockedBy != "" {
+		upgrade += " (blocked by " + bs.BlockedBy + ")"
+	}
+	return upgrade
+}
+
 func (bs *BasicStatus) marshalJSON() *rawStatus {
 	return &rawStatus{
 		ProjectRoot:  bs.ProjectRoot,
@@ -409,7 +601,22 @@ func (bs *BasicStatus) marshalJSON() *rawStatus {
 		Revision:     string(bs.Revision),
 		Latest:       bs.getConsolidatedLatest(longRev),
 		PackageCount: bs.PackageCount,
+		LocalSource:  bs.LocalSource,
+
+		LatestAllowed: versionString(bs.LatestAllowed),
+		LatestOverall: versionString(bs.LatestOverall),
+		Upgrade:       bs.Upgrade,
+		CommitsAhead:  bs.CommitsAhead,
+		BlockedBy:     bs.BlockedBy,
+	}
+}
+
+// versionString returns the full string of v, or "" if it is nil.
+func versionString(v gps.Version) string {
+	if v == nil {
+		return ""
 	}
+	return v.String()
 }
 
 // MissingStatus contains information about all the missing packages in a project.
@@ -418,7 +625,9 @@ type MissingStatus struct {
 	MissingPackages []string
 }
 
-func runStatusAll(ctx *dep.Ctx, out outputter, p *dep.Project, sm gps.SourceManager) (hasMissingPkgs bool, errCount int, err error) {
+// runStatusAll reports the status of the project's dependencies to out. If old
+// is set, their upgrades are found, and only those that have any are reported.
+func runStatusAll(ctx *dep.Ctx, out outputter, p *dep.Project, sm gps.SourceManager, old bool) (hasMissingPkgs bool, errCount int, err error) {
 	// While the network churns on ListVersions() requests, statically analyze
 	// code from the current project.
 	ptree, err := p.ParseRootPackageTree()
@@ -495,6 +704,9 @@ func runStatusAll(ctx *dep.Ctx, out outputter, p *dep.Project, sm gps.SourceMana
 					ProjectRoot:  string(proj.Ident().ProjectRoot),
 					PackageCount: len(proj.Packages()),
 				}
+				if gps.IsLocalSource(proj.Ident().Source) {
+					bs.LocalSource = proj.Ident().Source
+				}
 
 				// Get children only for specific outputers
 				// in order to avoid slower status process.
@@ -536,6 +748,8 @@ func runStatusAll(ctx *dep.Ctx, out outputter, p *dep.Project, sm gps.SourceMana
 						bs.Constraint = c.Constraint.Intersect(bs.Constraint)
 					}
 				}
+				// Only the root's constraint is kept for display.
+				allowed := bs.upgradeConstraint(cm)
 
 				// Only if we have a non-rev and non-plain version do/can we display
 				// anything wrt the version's updateability.
@@ -573,6 +787,13 @@ func runStatusAll(ctx *dep.Ctx, out outputter, p *dep.Project, sm gps.SourceMana
 								break
 							}
 						}
+
+						if old {
+							bs.findUpgrade(vl, allowed)
+							if bs.Upgrade == BranchUpgrade {
+								bs.CommitsAhead = countCommitsAhead(sm, proj.Ident(), bs.Revision, bs.LatestOverall)
+							}
+						}
 					} else {
 						// Failed to fetch version list (could happen due to
 						// network issue).
@@ -635,7 +856,11 @@ func runStatusAll(ctx *dep.Ctx, out outputter, p *dep.Project, sm gps.SourceMana
 
 		// Use the collected BasicStatus in outputter.
 		for _, proj := range slp {
-			if err := out.BasicLine(bsMap[string(proj.Ident().ProjectRoot)]); err != nil {
+			bs := bsMap[string(proj.Ident().ProjectRoot)]
+			if old && !bs.isOld() {
+				continue
+			}
+			if err := out.BasicLine(bs); err != nil {
 				return false, 0, err
 			}
 		}
@@ -716,6 +941,21 @@ outer:
 	return hasMissingPkgs, 0, errInputDigestMismatch
 }
 
+// countCommitsAhead returns how many commits the revision to is ahead of from
+// in the source of id, or 0 if they cannot be counted.
+func countCommitsAhead(sm gps.SourceManager, id gps.ProjectIdentifier, from gps.Revision, to gps.Version) int {
+	gsm, ok := sm.(*gps.SourceMgr)
+	r, isRev := to.(gps.Revision)
+	if !ok || !isRev {
+		return 0
+	}
+	n, err := gsm.CommitsBetween(id, from, r)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
 func formatVersion(v gps.Version) string {
 	if v == nil {
 		return ""
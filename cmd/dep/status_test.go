@@ -188,6 +188,15 @@ func TestBasicStatusGetConsolidatedConstraint(t *testing.T) {
 			},
 			wantConstraint: "1.2.1 (override)",
 		},
+		{
+			name: "BasicStatus with Local Source and Override",
+			basicStatus: BasicStatus{
+				Constraint:  gps.Any(),
+				LocalSource: "/src/foo",
+				hasOverride: true,
+			},
+			wantConstraint: "* (override) (local)",
+		},
 		{
 			name: "BasicStatus with Revision Constraint",
 			basicStatus: BasicStatus{
@@ -300,6 +309,195 @@ func TestBasicStatusGetConsolidatedLatest(t *testing.T) {
 	}
 }
 
+func TestBasicStatusFindUpgrade(t *testing.T) {
+	vl := []gps.PairedVersion{
+		gps.NewVersion("v2.1.0").Pair("rev210"),
+		gps.NewVersion("v1.3.0").Pair("rev130"),
+		gps.NewVersion("v1.2.4").Pair("rev124"),
+		gps.NewVersion("v1.2.3").Pair("rev123"),
+		gps.NewBranch("master").Pair("revmaster"),
+	}
+	gps.SortPairedForUpgrade(vl)
+	caret, _ := gps.NewSemverConstraint("^1.2.0")
+	tilde, _ := gps.NewSemverConstraint("~1.2.0")
+
+	testCases := []struct {
+		name        string
+		basicStatus BasicStatus
+		constraint  gps.Constraint
+		wantAllowed gps.Version
+		wantOverall gps.Version
+		wantUpgrade string
+	}{
+		{
+			name:        "up to date",
+			basicStatus: BasicStatus{Version: gps.NewVersion("v2.1.0"), Revision: "rev210"},
+			constraint:  gps.Any(),
+			wantAllowed: vl[0],
+			wantOverall: vl[0],
+			wantUpgrade: "",
+		},
+		{
+			name:        "major",
+			basicStatus: BasicStatus{Version: gps.NewVersion("v1.3.0"), Revision: "rev130"},
+			constraint:  gps.Any(),
+			wantAllowed: vl[0],
+			wantOverall: vl[0],
+			wantUpgrade: "major",
+		},
+		{
+			name:        "major blocked by constraint",
+			basicStatus: BasicStatus{Version: gps.NewVersion("v1.2.3"), Revision: "rev123"},
+			constraint:  caret,
+			wantAllowed: vl[1],
+			wantOverall: vl[0],
+			wantUpgrade: "major (blocked by constraint)",
+		},
+		{
+			name:        "major blocked by override",
+			basicStatus: BasicStatus{Version: gps.NewVersion("v1.2.3"), Revision: "rev123", hasOverride: true},
+			constraint:  tilde,
+			wantAllowed: vl[2],
+			wantOverall: vl[0],
+			wantUpgrade: "major (blocked by override)",
+		},
+		{
+			name:        "branch ahead",
+			basicStatus: BasicStatus{Version: gps.NewBranch("master"), Revision: "revold", CommitsAhead: 3},
+			constraint:  gps.NewBranch("master"),
+			wantAllowed: gps.Revision("revmaster"),
+			wantOverall: gps.Revision("revmaster"),
+			wantUpgrade: "3 commits",
+		},
+		{
+			name:        "branch at head",
+			basicStatus: BasicStatus{Version: gps.NewBranch("master"), Revision: "revmaster"},
+			constraint:  gps.NewBranch("master"),
+			wantAllowed: gps.Revision("revmaster"),
+			wantOverall: gps.Revision("revmaster"),
+			wantUpgrade: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bs := tc.basicStatus
+			bs.findUpgrade(vl, tc.constraint)
+			if !reflect.DeepEqual(bs.LatestAllowed, tc.wantAllowed) || !reflect.DeepEqual(bs.LatestOverall, tc.wantOverall) {
+				t.Errorf("unexpected latest versions: \n\t(GOT) %v, %v \n\t(WNT) %v, %v", bs.LatestAllowed, bs.LatestOverall, tc.wantAllowed, tc.wantOverall)
+			}
+			if got := bs.getConsolidatedUpgrade(); got != tc.wantUpgrade {
+				t.Errorf("unexpected upgrade: \n\t(GOT) %v \n\t(WNT) %v", got, tc.wantUpgrade)
+			}
+			if got := bs.isOld(); got != (tc.wantUpgrade != "") {
+				t.Errorf("unexpected isOld: %v", got)
+			}
+		})
+	}
+}
+
+func TestBasicStatusUpgradeConstraint(t *testing.T) {
+	caret, _ := gps.NewSemverConstraint("^1.2.0")
+	tilde, _ := gps.NewSemverConstraint("~1.2.0")
+	cm := constraintsCollection{
+		"github.com/foo/bar": {
+			{Project: "github.com/baz/qux", Constraint: tilde},
+		},
+	}
+
+	testCases := []struct {
+		name        string
+		basicStatus BasicStatus
+		want        gps.Constraint
+	}{
+		{
+			name:        "constrained by a dependency",
+			basicStatus: BasicStatus{ProjectRoot: "github.com/foo/bar", Constraint: caret},
+			want:        tilde,
+		},
+		{
+			name:        "override",
+			basicStatus: BasicStatus{ProjectRoot: "github.com/foo/bar", Constraint: caret, hasOverride: true},
+			want:        caret,
+		},
+		{
+			name:        "no dependency constraints",
+			basicStatus: BasicStatus{ProjectRoot: "github.com/foo/baz", Constraint: caret},
+			want:        caret,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.basicStatus.upgradeConstraint(cm); got.String() != tc.want.String() {
+				t.Errorf("unexpected constraint: \n\t(GOT) %v \n\t(WNT) %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSemverUpgrade(t *testing.T) {
+	testCases := []struct {
+		from, to string
+		want     UpgradeType
+	}{
+		{"v1.2.3", "v1.2.4", PatchUpgrade},
+		{"v1.2.3", "v1.3.0", MinorUpgrade},
+		{"v1.2.3", "v2.0.0", MajorUpgrade},
+		{"v0.1.0", "v0.2.0", MinorUpgrade},
+		{"v1.0.0-beta.1", "v1.0.0", PatchUpgrade},
+		{"v1.2.3", "v1.2.3", NoUpgrade},
+		{"v1.2.3", "v1.2.0", NoUpgrade},
+	}
+
+	for _, tc := range testCases {
+		if got := semverUpgrade(gps.NewVersion(tc.from), gps.NewVersion(tc.to)); got != tc.want {
+			t.Errorf("unexpected upgrade from %s to %s: \n\t(GOT) %q \n\t(WNT) %q", tc.from, tc.to, got, tc.want)
+		}
+	}
+	if got := semverUpgrade(gps.NewVersion("v1.0.0"), nil); got != NoUpgrade {
+		t.Errorf("unexpected upgrade to nil: %q", got)
+	}
+}
+
+func TestOldStatusOutput(t *testing.T) {
+	bs := BasicStatus{
+		ProjectRoot:   "github.com/foo/bar",
+		Constraint:    gps.Any(),
+		Version:       gps.NewVersion("v1.2.3"),
+		Revision:      "rev123",
+		LatestAllowed: gps.NewVersion("v1.3.0").Pair("rev130"),
+		LatestOverall: gps.NewVersion("v2.1.0").Pair("rev210"),
+		Upgrade:       MajorUpgrade,
+		BlockedBy:     "constraint",
+	}
+
+	var buf bytes.Buffer
+	tableout := &tableOutput{w: tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0), old: true}
+	tableout.BasicHeader()
+	tableout.BasicLine(&bs)
+	tableout.BasicFooter()
+	want := "PROJECT             CONSTRAINT  VERSION  ALLOWED  LATEST  UPGRADE\n" +
+		"github.com/foo/bar  *           v1.2.3   v1.3.0   v2.1.0  major (blocked by constraint)  \n"
+	if buf.String() != want {
+		t.Errorf("unexpected table output: \n\t(GOT) %q \n\t(WNT) %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	jsonout := &jsonOutput{w: &buf}
+	jsonout.BasicHeader()
+	jsonout.BasicLine(&bs)
+	jsonout.BasicFooter()
+	for _, want := range []string{`"LatestAllowed":"v1.3.0"`, `"LatestOverall":"v2.1.0"`, `"Upgrade":"major"`, `"BlockedBy":"constraint"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Did not find expected JSON status: \n\t(GOT) %v \n\t(WNT) %v", buf.String(), want)
+		}
+	}
+	if strings.Contains(buf.String(), "CommitsAhead") {
+		t.Errorf("expected CommitsAhead to be omitted: %v", buf.String())
+	}
+}
+
 func TestCollectConstraints(t *testing.T) {
 	ver1, _ := gps.NewSemverConstraintIC("v1.0.0")
 	ver08, _ := gps.NewSemverConstraintIC("v0.8.0")
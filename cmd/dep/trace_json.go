@@ -0,0 +1,100 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+// jsonTraceEvent is the JSON representation of a gps.TraceEvent.
+type jsonTraceEvent struct {
+	Kind     gps.TraceEventKind `json:"kind"`
+	Depth    int                `json:"depth"`
+	Attempts int                `json:"attempts"`
+
+	*jsonFailureAtom
+	Packages     []string `json:"packages,omitempty"`
+	PackagesOnly bool     `json:"packagesOnly,omitempty"`
+
+	Continue      bool `json:"continue,omitempty"`
+	QueueLength   int  `json:"queueLength,omitempty"`
+	QueueComplete bool `json:"queueComplete,omitempty"`
+	Unselected    int  `json:"unselected"`
+
+	Failure *jsonSolveFailure `json:"failure,omitempty"`
+}
+
+// jsonTraceSink is a gps.TraceSink that writes each event it receives as a
+// single line of JSON.
+type jsonTraceSink struct {
+	w   *bufio.Writer
+	c   io.Closer
+	enc *json.Encoder
+	// err is the first error encountered while writing; once set, further
+	// events are dropped.
+	err error
+}
+
+// createJSONTraceSink creates a jsonTraceSink writing to the named file.
+func createJSONTraceSink(path string) (*jsonTraceSink, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create trace file")
+	}
+	return newJSONTraceSink(f, f), nil
+}
+
+func newJSONTraceSink(w io.Writer, c io.Closer) *jsonTraceSink {
+	bw := bufio.NewWriter(w)
+	return &jsonTraceSink{
+		w:   bw,
+		c:   c,
+		enc: json.NewEncoder(bw),
+	}
+}
+
+func (s *jsonTraceSink) TraceEvent(ev gps.TraceEvent) {
+	if s.err != nil {
+		return
+	}
+
+	jev := jsonTraceEvent{
+		Kind:          ev.Kind,
+		Depth:         ev.Depth,
+		Attempts:      ev.Attempts,
+		Packages:      ev.Packages,
+		PackagesOnly:  ev.PackagesOnly,
+		Continue:      ev.Continue,
+		QueueLength:   ev.QueueLength,
+		QueueComplete: ev.QueueComplete,
+		Unselected:    ev.Unselected,
+	}
+	if ev.Project.ProjectRoot != "" {
+		jev.jsonFailureAtom = toJSONFailureAtom(gps.FailureAtom{Project: ev.Project, Version: ev.Version})
+	}
+	if ev.Failure != nil {
+		jev.Failure = toJSONSolveFailure(ev.Failure)
+	}
+
+	s.err = s.enc.Encode(jev)
+}
+
+// Close flushes any buffered events and closes the underlying writer. It
+// returns the first error encountered while writing, if any.
+func (s *jsonTraceSink) Close() error {
+	if s.err == nil {
+		s.err = s.w.Flush()
+	}
+	if err := s.c.Close(); s.err == nil {
+		s.err = err
+	}
+	return errors.Wrap(s.err, "failed to write solver trace")
+}
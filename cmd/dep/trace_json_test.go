@@ -0,0 +1,70 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/golang/dep/gps"
+)
+
+func TestJSONTraceSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := newJSONTraceSink(&buf, ioutil.NopCloser(nil))
+
+	foo := gps.ProjectIdentifier{ProjectRoot: "github.com/foo/foo"}
+	sink.TraceEvent(gps.TraceEvent{
+		Kind:     gps.TraceSelectRoot,
+		Depth:    0,
+		Project:  gps.ProjectIdentifier{ProjectRoot: "example.com/root"},
+		Packages: []string{"example.com/root"},
+	})
+	sink.TraceEvent(gps.TraceEvent{
+		Kind:          gps.TraceCheckQueue,
+		Depth:         1,
+		Project:       foo,
+		QueueLength:   2,
+		QueueComplete: true,
+		Unselected:    1,
+	})
+	sink.TraceEvent(gps.TraceEvent{
+		Kind:       gps.TraceFailure,
+		Depth:      1,
+		Project:    foo,
+		Version:    gps.NewVersion("v1.0.0").Pair("abc123"),
+		Unselected: 1,
+		Failure: &gps.CheckeeHasProblemPackagesFailure{
+			Rejected: gps.FailureAtom{Project: foo, Version: gps.NewVersion("v1.0.0").Pair("abc123")},
+			Packages: []gps.ProblemPackage{{
+				ImportPath: "github.com/foo/foo/bar",
+				RequiredBy: []gps.FailureAtom{{Project: gps.ProjectIdentifier{ProjectRoot: "example.com/root"}}},
+			}},
+		},
+	})
+	sink.TraceEvent(gps.TraceEvent{
+		Kind:     gps.TraceFinish,
+		Depth:    2,
+		Attempts: 1,
+	})
+
+	// Nothing is written until the sink is closed.
+	if buf.Len() != 0 {
+		t.Fatalf("expected events to be buffered, got %q", buf.String())
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"kind":"select-root","depth":0,"attempts":0,"root":"example.com/root","packages":["example.com/root"],"unselected":0}
+{"kind":"check-queue","depth":1,"attempts":0,"root":"github.com/foo/foo","queueLength":2,"queueComplete":true,"unselected":1}
+{"kind":"failure","depth":1,"attempts":0,"root":"github.com/foo/foo","version":"v1.0.0","revision":"abc123","unselected":1,"failure":{"kind":"problem-packages","message":"Could not introduce github.com/foo/foo@v1.0.0, as its subpackage github.com/foo/foo/bar is missing. (Package is required by (root).)","rejected":{"root":"github.com/foo/foo","version":"v1.0.0","revision":"abc123"},"packages":[{"importPath":"github.com/foo/foo/bar","missing":true,"requiredBy":[{"root":"example.com/root"}]}]}}
+{"kind":"finish","depth":2,"attempts":1,"unselected":0}
+`
+	if buf.String() != want {
+		t.Fatalf("unexpected trace:\n\t(GOT): %s\n\t(WNT): %s", buf.String(), want)
+	}
+}
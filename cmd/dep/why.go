@@ -0,0 +1,335 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/paths"
+	"github.com/golang/dep/gps/pkgtree"
+	"github.com/pkg/errors"
+)
+
+const whyShortHelp = `Explain why a package or project is in the dependency graph`
+const whyLongHelp = `
+Why prints the shortest chains of imports that lead from the current project's
+packages to the given import path. If the path is the root of a project in
+Gopkg.lock, chains leading to any package in that project are printed.
+
+Imports are followed through the packages of dependencies at the versions
+recorded in Gopkg.lock. Why also reports when the path is only reached through
+the imports of test files, when it is pulled in by a required entry in
+Gopkg.toml, and when it is excluded by an ignored entry in Gopkg.toml.
+`
+
+func (cmd *whyCommand) Name() string      { return "why" }
+func (cmd *whyCommand) Args() string      { return "<import path>" }
+func (cmd *whyCommand) ShortHelp() string { return whyShortHelp }
+func (cmd *whyCommand) LongHelp() string  { return whyLongHelp }
+func (cmd *whyCommand) Hidden() bool      { return false }
+
+func (cmd *whyCommand) Register(fs *flag.FlagSet) {}
+
+type whyCommand struct{}
+
+func (cmd *whyCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) != 1 {
+		return errors.New("dep why takes exactly one import path")
+	}
+
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+
+	if p.Lock == nil {
+		return errors.Errorf("no %s found; run dep ensure to generate one", dep.LockName)
+	}
+
+	ptree, err := p.ParseRootPackageTree()
+	if err != nil {
+		return err
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	sm.UseDefaultSignalHandling()
+	defer sm.Release()
+
+	g := newWhyGraph(ptree, p.Lock, p.Manifest.IgnoredPackages(), sm.ListPackages)
+	res, err := g.explain(strings.TrimSuffix(args[0], "/"), p.Manifest.Required)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	writeWhyResult(&buf, res, ptree.ImportRoot)
+	ctx.Out.Print(buf.String())
+	return nil
+}
+
+// whyChain is a chain of imports leading to the target of dep why. The first
+// element of Path is either a root package, or an entry from the manifest's
+// required list.
+type whyChain struct {
+	Required bool
+	Path     []string
+}
+
+// whyResult is the explanation of why a package or project is in the
+// dependency graph.
+type whyResult struct {
+	Target string
+	// IgnoredBy is the ignored rule excluding the target, if there is one.
+	IgnoredBy string
+	// TestsOnly indicates that the target is only reached through the test
+	// imports of root packages.
+	TestsOnly bool
+	Chains    []whyChain
+}
+
+// whyGraph is the import graph of the current project and its locked
+// dependencies. The packages of dependencies are listed lazily, as they are
+// reached.
+type whyGraph struct {
+	root         pkgtree.PackageTree
+	lock         gps.Lock
+	ignore       *pkgtree.IgnoredRuleset
+	listPackages func(gps.ProjectIdentifier, gps.Version) (pkgtree.PackageTree, error)
+	trees        map[gps.ProjectRoot]pkgtree.PackageTree
+}
+
+func newWhyGraph(root pkgtree.PackageTree, l gps.Lock, ig *pkgtree.IgnoredRuleset, list func(gps.ProjectIdentifier, gps.Version) (pkgtree.PackageTree, error)) *whyGraph {
+	return &whyGraph{
+		root:         root,
+		lock:         l,
+		ignore:       ig,
+		listPackages: list,
+		trees:        make(map[gps.ProjectRoot]pkgtree.PackageTree),
+	}
+}
+
+// explain finds the shortest chains of imports from the root packages, and
+// the required packages, to target. Test imports of root packages are only
+// followed if the target cannot be reached without them.
+func (g *whyGraph) explain(target string, required []string) (*whyResult, error) {
+	res := &whyResult{Target: target}
+
+	if g.ignore.IsIgnored(target) {
+		for _, rule := range g.ignore.ToSlice() {
+			if rule == target || (strings.HasSuffix(rule, "*") && strings.HasPrefix(target, rule[:len(rule)-1])) {
+				res.IgnoredBy = rule
+				break
+			}
+		}
+		return res, nil
+	}
+
+	matches := func(path string) bool { return path == target }
+	if g.isLockedRoot(target) {
+		matches = func(path string) bool {
+			return path == target || strings.HasPrefix(path, target+"/")
+		}
+	}
+
+	req := make([]string, len(required))
+	copy(req, required)
+	sort.Strings(req)
+
+	for _, tests := range []bool{false, true} {
+		rm, _ := g.root.ToReachMap(true, tests, false, g.ignore)
+		roots := make([]string, 0, len(rm))
+		for pkg := range rm {
+			roots = append(roots, pkg)
+		}
+		sort.Strings(roots)
+
+		var chains []whyChain
+		for _, sources := range []struct {
+			paths    []string
+			required bool
+		}{{roots, false}, {req, true}} {
+			for _, src := range sources.paths {
+				if g.ignore.IsIgnored(src) {
+					continue
+				}
+				path, err := g.shortestPath(src, matches, tests)
+				if err != nil {
+					return nil, err
+				}
+				if path != nil {
+					chains = append(chains, whyChain{Required: sources.required, Path: path})
+				}
+			}
+		}
+
+		if len(chains) == 0 {
+			continue
+		}
+
+		// Only keep the shortest of the chains.
+		shortest := len(chains[0].Path)
+		for _, c := range chains {
+			if len(c.Path) < shortest {
+				shortest = len(c.Path)
+			}
+		}
+		for _, c := range chains {
+			if len(c.Path) == shortest {
+				res.Chains = append(res.Chains, c)
+			}
+		}
+		res.TestsOnly = tests
+		return res, nil
+	}
+
+	return res, nil
+}
+
+// shortestPath returns the shortest chain of imports from src to a package for
+// which matches returns true, or nil if there is none.
+func (g *whyGraph) shortestPath(src string, matches func(string) bool, tests bool) ([]string, error) {
+	prev := map[string]string{src: ""}
+	queue := []string{src}
+
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		if matches(cur) {
+			var path []string
+			for p := cur; p != ""; p = prev[p] {
+				path = append([]string{p}, path...)
+			}
+			return path, nil
+		}
+
+		imps, err := g.imports(cur, tests)
+		if err != nil {
+			return nil, err
+		}
+		for _, imp := range imps {
+			if _, seen := prev[imp]; !seen {
+				prev[imp] = cur
+				queue = append(queue, imp)
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// imports returns the sorted, non-ignored, non-standard library imports of the
+// package at path. Test imports are only included for root packages, and only
+// if tests is true.
+func (g *whyGraph) imports(path string, tests bool) ([]string, error) {
+	var imps []string
+	if poe, has := g.root.Packages[path]; has {
+		if poe.Err != nil {
+			return nil, nil
+		}
+		imps = poe.P.Imports
+		if tests {
+			imps = append(imps[:len(imps):len(imps)], poe.P.TestImports...)
+		}
+	} else {
+		lp, has := g.lockedProjectFor(path)
+		if !has {
+			return nil, nil
+		}
+
+		pr := lp.Ident().ProjectRoot
+		ptree, has := g.trees[pr]
+		if !has {
+			var err error
+			ptree, err = g.listPackages(lp.Ident(), lp.Version())
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to list packages in %s", pr)
+			}
+			g.trees[pr] = ptree
+		}
+
+		poe, has := ptree.Packages[path]
+		if !has || poe.Err != nil {
+			return nil, nil
+		}
+		imps = poe.P.Imports
+	}
+
+	seen := make(map[string]bool, len(imps))
+	var ret []string
+	for _, imp := range imps {
+		if seen[imp] || imp == path || paths.IsStandardImportPath(imp) || g.ignore.IsIgnored(imp) {
+			continue
+		}
+		seen[imp] = true
+		ret = append(ret, imp)
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
+
+// lockedProjectFor returns the locked project containing the package at path.
+func (g *whyGraph) lockedProjectFor(path string) (gps.LockedProject, bool) {
+	var ret gps.LockedProject
+	var found bool
+	for _, lp := range g.lock.Projects() {
+		root := string(lp.Ident().ProjectRoot)
+		if path != root && !strings.HasPrefix(path, root+"/") {
+			continue
+		}
+		if !found || len(root) > len(ret.Ident().ProjectRoot) {
+			ret, found = lp, true
+		}
+	}
+	return ret, found
+}
+
+// isLockedRoot reports whether path is the root of a project in the lock.
+func (g *whyGraph) isLockedRoot(path string) bool {
+	for _, lp := range g.lock.Projects() {
+		if string(lp.Ident().ProjectRoot) == path {
+			return true
+		}
+	}
+	return false
+}
+
+func writeWhyResult(w io.Writer, res *whyResult, root string) {
+	fmt.Fprintf(w, "# %s\n", res.Target)
+
+	switch {
+	case res.IgnoredBy != "":
+		fmt.Fprintf(w, "(ignored by the %q rule in %s)\n", res.IgnoredBy, dep.ManifestName)
+		return
+	case len(res.Chains) == 0:
+		fmt.Fprintf(w, "(not imported by %s, nor required in %s)\n", root, dep.ManifestName)
+		return
+	case res.TestsOnly:
+		fmt.Fprintln(w, "(only imported by tests)")
+	}
+
+	for i, c := range res.Chains {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		for j, pkg := range c.Path {
+			if j == 0 && c.Required {
+				fmt.Fprintf(w, "%s (required in %s)\n", pkg, dep.ManifestName)
+			} else {
+				fmt.Fprintln(w, pkg)
+			}
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/pkgtree"
+	"github.com/pkg/errors"
+)
+
+func whyTestTree(root string, pkgs map[string][]string, testImports map[string][]string) pkgtree.PackageTree {
+	ptree := pkgtree.PackageTree{ImportRoot: root, Packages: make(map[string]pkgtree.PackageOrErr)}
+	for path, imps := range pkgs {
+		ptree.Packages[path] = pkgtree.PackageOrErr{
+			P: pkgtree.Package{
+				Name:        "foo",
+				ImportPath:  path,
+				Imports:     imps,
+				TestImports: testImports[path],
+			},
+		}
+	}
+	return ptree
+}
+
+func TestWhyGraphExplain(t *testing.T) {
+	root := whyTestTree("example.com/root", map[string][]string{
+		"example.com/root":     {"fmt", "example.com/root/sub", "github.com/a/a"},
+		"example.com/root/sub": {"github.com/b/b/pkg"},
+	}, map[string][]string{
+		"example.com/root": {"github.com/t/t"},
+	})
+
+	deps := map[gps.ProjectRoot]pkgtree.PackageTree{
+		"github.com/a/a": whyTestTree("github.com/a/a", map[string][]string{
+			"github.com/a/a": {"github.com/c/c", "github.com/ig/ig"},
+		}, nil),
+		"github.com/b/b": whyTestTree("github.com/b/b", map[string][]string{
+			"github.com/b/b":     {},
+			"github.com/b/b/pkg": {"github.com/c/c"},
+		}, nil),
+		"github.com/c/c": whyTestTree("github.com/c/c", map[string][]string{
+			"github.com/c/c": {"os"},
+		}, nil),
+		"github.com/r/r": whyTestTree("github.com/r/r", map[string][]string{
+			"github.com/r/r/cmd": {"github.com/b/b"},
+		}, nil),
+		"github.com/t/t": whyTestTree("github.com/t/t", map[string][]string{
+			"github.com/t/t": {},
+		}, nil),
+		"github.com/ig/ig": whyTestTree("github.com/ig/ig", map[string][]string{
+			"github.com/ig/ig": {},
+		}, nil),
+	}
+
+	var l gps.SimpleLock
+	for pr := range deps {
+		l = append(l, gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: pr}, gps.Revision("abc123"), nil))
+	}
+	list := func(id gps.ProjectIdentifier, v gps.Version) (pkgtree.PackageTree, error) {
+		if ptree, has := deps[id.ProjectRoot]; has {
+			return ptree, nil
+		}
+		return pkgtree.PackageTree{}, errors.Errorf("no such project %s", id)
+	}
+	ig := pkgtree.NewIgnoredRuleset([]string{"github.com/ig/*"})
+	required := []string{"github.com/r/r/cmd"}
+
+	cases := []struct {
+		target string
+		want   *whyResult
+	}{
+		{
+			target: "github.com/a/a",
+			want: &whyResult{
+				Target: "github.com/a/a",
+				Chains: []whyChain{
+					{Path: []string{"example.com/root", "github.com/a/a"}},
+				},
+			},
+		},
+		{
+			target: "github.com/c/c",
+			want: &whyResult{
+				Target: "github.com/c/c",
+				Chains: []whyChain{
+					{Path: []string{"example.com/root", "github.com/a/a", "github.com/c/c"}},
+					{Path: []string{"example.com/root/sub", "github.com/b/b/pkg", "github.com/c/c"}},
+				},
+			},
+		},
+		{
+			// Project roots match any package in the project.
+			target: "github.com/b/b",
+			want: &whyResult{
+				Target: "github.com/b/b",
+				Chains: []whyChain{
+					{Path: []string{"example.com/root/sub", "github.com/b/b/pkg"}},
+					{Required: true, Path: []string{"github.com/r/r/cmd", "github.com/b/b"}},
+				},
+			},
+		},
+		{
+			target: "github.com/t/t",
+			want: &whyResult{
+				Target:    "github.com/t/t",
+				TestsOnly: true,
+				Chains: []whyChain{
+					{Path: []string{"example.com/root", "github.com/t/t"}},
+				},
+			},
+		},
+		{
+			target: "github.com/ig/ig",
+			want:   &whyResult{Target: "github.com/ig/ig", IgnoredBy: "github.com/ig/*"},
+		},
+		{
+			target: "github.com/nope/nope",
+			want:   &whyResult{Target: "github.com/nope/nope"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.target, func(t *testing.T) {
+			g := newWhyGraph(root, l, ig, list)
+			got, err := g.explain(c.target, required)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("unexpected result:\n\t(GOT): %#v\n\t(WNT): %#v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestWriteWhyResult(t *testing.T) {
+	cases := []struct {
+		name string
+		res  *whyResult
+		want string
+	}{
+		{
+			name: "chains",
+			res: &whyResult{
+				Target:    "github.com/b/b",
+				TestsOnly: true,
+				Chains: []whyChain{
+					{Path: []string{"example.com/root", "github.com/b/b"}},
+					{Required: true, Path: []string{"github.com/r/r", "github.com/b/b"}},
+				},
+			},
+			want: `# github.com/b/b
+(only imported by tests)
+example.com/root
+github.com/b/b
+
+github.com/r/r (required in Gopkg.toml)
+github.com/b/b
+`,
+		},
+		{
+			name: "ignored",
+			res:  &whyResult{Target: "github.com/b/b", IgnoredBy: "github.com/b/*"},
+			want: "# github.com/b/b\n(ignored by the \"github.com/b/*\" rule in Gopkg.toml)\n",
+		},
+		{
+			name: "unreached",
+			res:  &whyResult{Target: "github.com/b/b"},
+			want: "# github.com/b/b\n(not imported by example.com/root, nor required in Gopkg.toml)\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writeWhyResult(&buf, c.res, "example.com/root")
+			if buf.String() != c.want {
+				t.Fatalf("unexpected output:\n\t(GOT): %q\n\t(WNT): %q", buf.String(), c.want)
+			}
+		})
+	}
+}
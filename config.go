@@ -0,0 +1,111 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/golang/dep/gps"
+	"github.com/pelletier/go-toml"
+	"github.com/pkg/errors"
+)
+
+// Config holds the settings of dep itself, which apply to every project
+// rather than being kept in Gopkg.toml. It is read from the TOML file named by
+// the DEPCONFIG environment variable.
+type Config struct {
+	// Mirrors redirect fetches from repositories to mirrors of them.
+	Mirrors []gps.Mirror
+	// DeductionRules statically deduce the import paths on some hosts.
+	DeductionRules []gps.DeductionRule
+	// Forges are the self-hosted GitLab and Gitea hosts.
+	Forges []gps.Forge
+}
+
+type rawConfig struct {
+	Mirrors    []rawMirror    `toml:"mirror,omitempty"`
+	Deductions []rawDeduction `toml:"deduction,omitempty"`
+	Forges     []rawForge     `toml:"forge,omitempty"`
+}
+
+type rawMirror struct {
+	Prefix string `toml:"prefix"`
+	URL    string `toml:"url"`
+}
+
+type rawDeduction struct {
+	Prefix string `toml:"prefix"`
+	Depth  int    `toml:"depth"`
+	VCS    string `toml:"vcs"`
+	URL    string `toml:"url"`
+}
+
+type rawForge struct {
+	Host string `toml:"host"`
+	Type string `toml:"type"`
+}
+
+// ReadConfig reads the Config in the file at path.
+func ReadConfig(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read dep config")
+	}
+
+	raw := rawConfig{}
+	if err := toml.Unmarshal(b, &raw); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse %s as TOML", path)
+	}
+
+	c := &Config{}
+	for _, rm := range raw.Mirrors {
+		m := gps.Mirror{Prefix: rm.Prefix, URL: rm.URL}
+		if err := gps.ValidateMirror(m); err != nil {
+			return nil, errors.Wrapf(err, "invalid mirror in %s", path)
+		}
+		c.Mirrors = append(c.Mirrors, m)
+	}
+	for _, rd := range raw.Deductions {
+		r := gps.DeductionRule{Prefix: rd.Prefix, Depth: rd.Depth, VCS: rd.VCS, URL: rd.URL}
+		if err := gps.ValidateDeductionRule(r); err != nil {
+			return nil, errors.Wrapf(err, "invalid deduction rule in %s", path)
+		}
+		c.DeductionRules = append(c.DeductionRules, r)
+	}
+	for _, rf := range raw.Forges {
+		f := gps.Forge{Host: rf.Host, Type: rf.Type}
+		if err := gps.ValidateForge(f); err != nil {
+			return nil, errors.Wrapf(err, "invalid forge in %s", path)
+		}
+		c.Forges = append(c.Forges, f)
+	}
+	return c, nil
+}
+
+// ParseMirrors parses mirrors written as a comma-separated list of
+// prefix=url pairs, as in the DEPMIRRORS environment variable:
+//
+//	https://github.com/=https://git.example.com/github/,https://go.googlesource.com/=https://git.example.com/go/
+func ParseMirrors(s string) ([]gps.Mirror, error) {
+	var mirrors []gps.Mirror
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		i := strings.IndexByte(pair, '=')
+		if i < 0 {
+			return nil, errors.Errorf("invalid mirror %q: expected prefix=url", pair)
+		}
+		m := gps.Mirror{Prefix: pair[:i], URL: pair[i+1:]}
+		if err := gps.ValidateMirror(m); err != nil {
+			return nil, err
+		}
+		mirrors = append(mirrors, m)
+	}
+	return mirrors, nil
+}
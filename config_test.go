@@ -0,0 +1,106 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/internal/test"
+)
+
+func TestReadConfig(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempFile("config.toml", `
+[[mirror]]
+  prefix = "https://github.com/"
+  url = "https://git.example.com/github/"
+
+[[mirror]]
+  prefix = "https://go.googlesource.com/"
+  url = "https://git.example.com/go/"
+
+[[deduction]]
+  prefix = "git.example.com"
+  depth = 4
+  vcs = "git"
+  url = "ssh://git@git.example.com/{path}.git"
+
+[[forge]]
+  host = "gitlab.example.com"
+  type = "gitlab"
+`)
+	c, err := ReadConfig(h.Path("config.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []gps.Mirror{
+		{Prefix: "https://github.com/", URL: "https://git.example.com/github/"},
+		{Prefix: "https://go.googlesource.com/", URL: "https://git.example.com/go/"},
+	}
+	if !reflect.DeepEqual(c.Mirrors, want) {
+		t.Fatalf("unexpected mirrors:\n\t(GOT): %v\n\t(WNT): %v", c.Mirrors, want)
+	}
+	wantRules := []gps.DeductionRule{
+		{Prefix: "git.example.com", Depth: 4, VCS: "git", URL: "ssh://git@git.example.com/{path}.git"},
+	}
+	if !reflect.DeepEqual(c.DeductionRules, wantRules) {
+		t.Fatalf("unexpected deduction rules:\n\t(GOT): %v\n\t(WNT): %v", c.DeductionRules, wantRules)
+	}
+	wantForges := []gps.Forge{{Host: "gitlab.example.com", Type: "gitlab"}}
+	if !reflect.DeepEqual(c.Forges, wantForges) {
+		t.Fatalf("unexpected forges:\n\t(GOT): %v\n\t(WNT): %v", c.Forges, wantForges)
+	}
+
+	bad := map[string]string{
+		"mirror without a prefix": `
+[[mirror]]
+  url = "https://git.example.com/github/"
+`,
+		"deduction without a vcs": `
+[[deduction]]
+  prefix = "git.example.com"
+  depth = 4
+  url = "ssh://git@git.example.com/{path}.git"
+`,
+		"forge of an unknown type": `
+[[forge]]
+  host = "git.example.com"
+  type = "github"
+`,
+	}
+	for name, config := range bad {
+		h.TempFile("bad.toml", config)
+		if _, err := ReadConfig(h.Path("bad.toml")); err == nil {
+			t.Errorf("expected a %s to be rejected", name)
+		}
+	}
+}
+
+func TestParseMirrors(t *testing.T) {
+	got, err := ParseMirrors("https://github.com/=https://git.example.com/github/, ssh://git@github.com/=https://git.example.com/github/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []gps.Mirror{
+		{Prefix: "https://github.com/", URL: "https://git.example.com/github/"},
+		{Prefix: "ssh://git@github.com/", URL: "https://git.example.com/github/"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected mirrors:\n\t(GOT): %v\n\t(WNT): %v", got, want)
+	}
+
+	if got, err := ParseMirrors(""); err != nil || len(got) != 0 {
+		t.Fatalf("expected no mirrors, got %v, %v", got, err)
+	}
+	for _, s := range []string{"https://github.com/", "=https://git.example.com/", "https://github.com/=mirror"} {
+		if _, err := ParseMirrors(s); err == nil {
+			t.Errorf("expected ParseMirrors(%q) to fail", s)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/golang/dep/gps"
 	"github.com/golang/dep/internal/fs"
@@ -34,13 +35,22 @@ import (
 //	}
 //
 type Ctx struct {
-	WorkingDir     string      // Where to execute.
-	GOPATH         string      // Selected Go path, containing WorkingDir.
-	GOPATHs        []string    // Other Go paths.
-	Out, Err       *log.Logger // Required loggers.
-	Verbose        bool        // Enables more verbose logging.
-	DisableLocking bool        // When set, no lock file will be created to protect against simultaneous dep processes.
-	Cachedir       string      // Cache directory loaded from environment.
+	WorkingDir     string              // Where to execute.
+	GOPATH         string              // Selected Go path, containing WorkingDir.
+	GOPATHs        []string            // Other Go paths.
+	Out, Err       *log.Logger         // Required loggers.
+	Verbose        bool                // Enables more verbose logging.
+	DisableLocking bool                // When set, no lock file will be created to protect against simultaneous dep processes.
+	Offline        bool                // When set, sources are only read from the cache, never from the network.
+	CI             bool                // When set, projects may not be sourced from local directories.
+	Proxy          string              // Base URL of a Go module proxy to fetch projects from, if any.
+	Mirrors        []gps.Mirror        // Rewrites of repository URLs, for fetching from mirrors.
+	DeductionRules []gps.DeductionRule // Static deduction of import paths on custom hosts.
+	Forges         []gps.Forge         // Self-hosted GitLab and Gitea hosts.
+	DeductionTTL   time.Duration       // How long go-get metadata is cached on disk; not at all if zero.
+	Retry          gps.RetryPolicy     // How failed network operations are retried.
+	Memoize        bool                // When set, solutions are kept in the cache, for identical solves to reuse.
+	Cachedir       string              // Cache directory loaded from environment.
 }
 
 // SetPaths sets the WorkingDir and GOPATHs fields. If GOPATHs is empty, then
@@ -98,11 +108,40 @@ func (c *Ctx) SourceManager() (*gps.SourceMgr, error) {
 		}
 	}
 
-	return gps.NewSourceManager(gps.SourceManagerConfig{
-		Cachedir:       cachedir,
-		Logger:         c.Out,
-		DisableLocking: c.DisableLocking,
+	// Retries are only worth mentioning when asked for details.
+	retry := c.Retry
+	if c.Verbose {
+		retry.Logger = c.Err
+	}
+
+	sm, err := gps.NewSourceManager(gps.SourceManagerConfig{
+		Cachedir:          cachedir,
+		Logger:            c.Out,
+		DisableLocking:    c.DisableLocking,
+		Offline:           c.Offline,
+		Proxy:             c.Proxy,
+		Mirrors:           c.Mirrors,
+		DeductionCacheTTL: c.DeductionTTL,
+		Retry:             retry,
+		MemoizeSolutions:  c.Memoize,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range c.DeductionRules {
+		if err := sm.RegisterPathDeducer(r.Prefix, r); err != nil {
+			sm.Release()
+			return nil, err
+		}
+	}
+	for _, f := range c.Forges {
+		if err := sm.RegisterForge(f); err != nil {
+			sm.Release()
+			return nil, err
+		}
+	}
+	return sm, nil
 }
 
 // LoadProject starts from the current working directory and searches up the
@@ -160,6 +199,7 @@ func (c *Ctx) LoadProject() (*Project, error) {
 	if err != nil {
 		return nil, errors.Wrapf(err, "error while parsing %s", mp)
 	}
+	p.Manifest.resolveLocalSources(p.AbsRoot)
 
 	lp := filepath.Join(p.AbsRoot, LockName)
 	lf, err := os.Open(lp)
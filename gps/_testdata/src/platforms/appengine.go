@@ -0,0 +1,11 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build appengine
+
+package platforms
+
+import "google.golang.org/appengine"
+
+var _ = appengine.IsDevAppServer
@@ -0,0 +1,15 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package platforms
+
+import (
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestPid(t *testing.T) {
+	_ = unix.Getpid
+}
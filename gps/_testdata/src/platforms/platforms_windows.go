@@ -0,0 +1,16 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package platforms
+
+import (
+	"sort"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	_ = sort.Strings
+	_ = windows.Handle(0)
+)
@@ -0,0 +1,12 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin linux,!android
+// +build !appengine
+
+package platforms
+
+import "golang.org/x/sys/unix"
+
+var _ = unix.Getpid
@@ -0,0 +1,585 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// archiveVersionPlaceholder is replaced by the version in the URL of an
+// archive source.
+const archiveVersionPlaceholder = "{version}"
+
+const (
+	// archiveMarkerFile records, in the cache directory of an archive source,
+	// the source it holds archives of.
+	archiveMarkerFile = "archive-source"
+	// archiveVersionsFile records the versions of an archive source seen so
+	// far, with the sha256 of their archives, one per line.
+	archiveVersionsFile = "versions"
+)
+
+var sha256HexRE = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// archiveSpec describes a source made of release archives published over
+// HTTP. It is written as the URL of the archives, in which {version} stands
+// for the version, followed by a fragment saying where the versions come
+// from: either an index listing them, or a single pinned version.
+//
+//	https://example.com/foo/foo-{version}.tar.gz#index=https://example.com/foo/versions
+//	https://example.com/foo/foo-{version}.zip#version=1.2.0
+//
+// The index is a plain text file with one version per line, followed by the
+// sha256 of its archive, so that listing the versions does not require
+// downloading every archive. Blank lines and lines starting with # are
+// ignored.
+type archiveSpec struct {
+	source   string // the source, as written
+	template string // the URL of the archives
+	index    string // the URL of the index, if any
+	version  string // the pinned version, if any
+	zip      bool   // whether the archives are zip files, rather than gzipped tarballs
+}
+
+// IsArchiveSource reports whether the source of a ProjectIdentifier refers to
+// release archives published over HTTP, rather than a repository.
+func IsArchiveSource(source string) bool {
+	_, ok, _ := parseArchiveSource(source)
+	return ok
+}
+
+// parseArchiveSource parses a source that refers to release archives. ok is
+// false if the source does not refer to archives at all; an error is returned
+// if it does, but is not valid.
+func parseArchiveSource(source string) (spec archiveSpec, ok bool, err error) {
+	u, err := url.Parse(source)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return archiveSpec{}, false, nil
+	}
+
+	lpath := strings.ToLower(u.Path)
+	switch {
+	case strings.HasSuffix(lpath, ".tar.gz"), strings.HasSuffix(lpath, ".tgz"):
+	case strings.HasSuffix(lpath, ".zip"):
+		spec.zip = true
+	default:
+		return archiveSpec{}, false, nil
+	}
+
+	spec.source = source
+	spec.template = source
+	if i := strings.IndexByte(source, '#'); i >= 0 {
+		spec.template = source[:i]
+	}
+
+	opts, err := url.ParseQuery(u.Fragment)
+	if err != nil {
+		return archiveSpec{}, true, errors.Wrapf(err, "invalid options in archive source %s", source)
+	}
+	spec.index, spec.version = opts.Get("index"), opts.Get("version")
+	switch {
+	case spec.index != "" && spec.version != "":
+		return archiveSpec{}, true, errors.Errorf("archive source %s cannot have both an index and a pinned version", source)
+	case spec.index == "" && spec.version == "":
+		return archiveSpec{}, true, errors.Errorf("archive source %s needs an index or a pinned version, as #index=<url> or #version=<version>", source)
+	case spec.index != "" && !strings.Contains(spec.template, archiveVersionPlaceholder):
+		return archiveSpec{}, true, errors.Errorf("archive source %s has an index, but its URL does not contain %s", source, archiveVersionPlaceholder)
+	}
+
+	return spec, true, nil
+}
+
+// archiveURL returns the URL of the archive of the version.
+func (spec archiveSpec) archiveURL(version string) string {
+	return strings.Replace(spec.template, archiveVersionPlaceholder, url.PathEscape(version), -1)
+}
+
+type maybeArchiveSource struct {
+	spec    archiveSpec
+	offline bool
+}
+
+func (m maybeArchiveSource) try(ctx context.Context, cachedir string) (source, error) {
+	s := &archiveSource{
+		spec:    m.spec,
+		offline: m.offline,
+		revs:    make(map[Revision]string),
+	}
+	s.unpackedTrees = unpackedTrees{dir: m.cachePath(cachedir), fetchTree: s.fetchRevision}
+	return s, nil
+}
+
+func (m maybeArchiveSource) cachePath(cachedir string) string {
+	return sourceCachePath(cachedir, m.spec.source)
+}
+
+func (m maybeArchiveSource) URL() *url.URL {
+	u, _ := url.Parse(m.spec.source)
+	return u
+}
+
+func (m maybeArchiveSource) String() string {
+	return fmt.Sprintf("%T: %s", m, m.spec.source)
+}
+
+// archiveSource is a source made of release archives published over HTTP.
+// The revision of a version is the sha256 of its archive, which is therefore
+// what Gopkg.lock records; an archive that no longer matches it is rejected.
+//
+// Each archive is unpacked into the cache directory of the source once, in a
+// directory named after its revision. A single top-level directory in the
+// archive, as is customary, is stripped.
+type archiveSource struct {
+	unpackedTrees
+	spec    archiveSpec
+	offline bool
+	// revs maps the revisions seen so far to their versions.
+	revs map[Revision]string
+}
+
+func (s *archiveSource) existsLocally(ctx context.Context) bool {
+	_, err := os.Stat(filepath.Join(s.dir, archiveMarkerFile))
+	return err == nil
+}
+
+func (s *archiveSource) existsUpstream(ctx context.Context) bool {
+	u := s.spec.index
+	if u == "" {
+		u = s.spec.archiveURL(s.spec.version)
+	}
+	resp, err := httpGet(ctx, u)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
+
+func (s *archiveSource) upstreamURL() string {
+	return s.spec.source
+}
+
+func (s *archiveSource) initLocal(ctx context.Context) error {
+	if err := os.MkdirAll(s.dir, 0777); err != nil {
+		return errors.Wrapf(err, "failed to create cache directory for %s", s.spec.source)
+	}
+	return ioutil.WriteFile(filepath.Join(s.dir, archiveMarkerFile), []byte(s.spec.source), 0666)
+}
+
+// updateLocal is a no-op, as archives are fetched as they are needed.
+func (*archiveSource) updateLocal(ctx context.Context) error {
+	return nil
+}
+
+func (*archiveSource) maybeClean(ctx context.Context) error {
+	return nil
+}
+
+func (s *archiveSource) listVersions(ctx context.Context) ([]PairedVersion, error) {
+	versions, sums, err := s.upstreamVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cached, err := s.readVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	pvs := make([]PairedVersion, 0, len(versions))
+	for _, v := range versions {
+		rev, has := sums[v]
+		if !has {
+			rev, has = cached[v]
+		}
+		if !has {
+			// The pinned version comes without its sha256, so its archive has
+			// to be fetched to find it out.
+			if rev, err = s.fetch(ctx, v, ""); err != nil {
+				return nil, err
+			}
+		}
+		pvs = append(pvs, NewVersion(v).Pair(rev))
+	}
+
+	s.recordRevisions(pvs)
+	return pvs, s.writeVersions(pvs)
+}
+
+func (s *archiveSource) listCachedVersions(ctx context.Context) ([]PairedVersion, error) {
+	pvs, err := readVersionsFile(filepath.Join(s.dir, archiveVersionsFile))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read cached versions of %s", s.spec.source)
+	}
+	s.recordRevisions(pvs)
+	return pvs, nil
+}
+
+// upstreamVersions returns the versions offered upstream, along with the
+// sha256 of their archives, as the index records them. The sha256 of a pinned
+// version is not known.
+func (s *archiveSource) upstreamVersions(ctx context.Context) ([]string, map[string]Revision, error) {
+	if s.spec.version != "" {
+		return []string{s.spec.version}, nil, nil
+	}
+	if s.offline {
+		return nil, nil, OfflineError{What: s.spec.index}
+	}
+
+	resp, err := httpGet(ctx, s.spec.index)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	var versions []string
+	sums := make(map[string]Revision)
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 1:
+			return nil, nil, errors.Errorf("index %s does not give the sha256 of the archive of version %s", s.spec.index, fields[0])
+		case len(fields) != 2 || !sha256HexRE.MatchString(fields[1]):
+			return nil, nil, errors.Errorf("invalid line in index %s: %q", s.spec.index, line)
+		}
+		sums[fields[0]] = Revision(fields[1])
+		versions = append(versions, fields[0])
+	}
+	if err := sc.Err(); err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to read index %s", s.spec.index)
+	}
+	return versions, sums, nil
+}
+
+func (s *archiveSource) recordRevisions(pvs []PairedVersion) {
+	for _, pv := range pvs {
+		s.revs[pv.Revision()] = pv.String()
+	}
+}
+
+func (s *archiveSource) readVersions() (map[string]Revision, error) {
+	pvs, err := s.listCachedVersions(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]Revision, len(pvs))
+	for _, pv := range pvs {
+		m[pv.String()] = pv.Revision()
+	}
+	return m, nil
+}
+
+func (s *archiveSource) writeVersions(pvs []PairedVersion) error {
+	return writeVersionsFile(filepath.Join(s.dir, archiveVersionsFile), pvs)
+}
+
+// fetchRevision fetches and unpacks the archive with the revision.
+func (s *archiveSource) fetchRevision(ctx context.Context, r Revision) error {
+	// The revision may come from Gopkg.lock, before the versions have been
+	// listed.
+	v, has := s.revs[r]
+	if !has {
+		if _, err := s.listCachedVersions(ctx); err != nil {
+			return err
+		}
+		v, has = s.revs[r]
+	}
+	if !has && !s.offline {
+		if _, err := s.listVersions(ctx); err != nil {
+			return err
+		}
+		v, has = s.revs[r]
+	}
+	if !has {
+		return errors.Errorf("checksum mismatch: no archive of %s has sha256 %s; it may have been republished with different contents", s.spec.source, r)
+	}
+
+	_, err := s.fetch(ctx, v, r)
+	return err
+}
+
+// fetch downloads the archive of the version and unpacks it, returning its
+// sha256. If want is not empty, the archive must have that sha256.
+func (s *archiveSource) fetch(ctx context.Context, version string, want Revision) (Revision, error) {
+	if s.offline {
+		return "", OfflineError{What: fmt.Sprintf("version %q of %s", version, s.spec.source)}
+	}
+
+	f, err := ioutil.TempFile(s.dir, "download-")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create temporary file in %s", s.dir)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	u := s.spec.archiveURL(version)
+	resp, err := httpGet(ctx, u)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, h), resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to download %s", u)
+	}
+
+	rev := Revision(hex.EncodeToString(h.Sum(nil)))
+	if want != "" && rev != want {
+		return "", errors.Errorf("checksum mismatch for version %s of %s: expected sha256 %s, but the archive has %s", version, s.spec.source, want, rev)
+	}
+
+	if s.hasTree(rev) {
+		return rev, nil
+	}
+
+	err = s.placeTree(rev, u, func(tmp string) (string, error) {
+		var err error
+		if s.spec.zip {
+			err = unpackZip(f, tmp, "")
+		} else {
+			err = unpackTarGz(f, tmp)
+		}
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to unpack %s", u)
+		}
+		return archiveRoot(tmp)
+	})
+	return rev, err
+}
+
+func (s *archiveSource) revisionPresentIn(r Revision) (bool, error) {
+	if _, has := s.revs[r]; has {
+		return true, nil
+	}
+	return s.hasTree(r), nil
+}
+
+func (s *archiveSource) disambiguateRevision(ctx context.Context, r Revision) (Revision, error) {
+	if present, _ := s.revisionPresentIn(r); !present {
+		return "", errors.Errorf("no version of %s has an archive with sha256 %s", s.spec.source, r)
+	}
+	return r, nil
+}
+
+func (s *archiveSource) revisionTime(ctx context.Context, r Revision) (time.Time, error) {
+	return time.Time{}, errors.Errorf("%s is made of release archives, so its revisions have no time", s.spec.source)
+}
+
+func (s *archiveSource) commitsBetween(ctx context.Context, from, to Revision) (int, error) {
+	return 0, errors.Errorf("%s is made of release archives, so it has no commits", s.spec.source)
+}
+
+func (*archiveSource) sourceType() string {
+	return "archive"
+}
+
+func (*archiveSource) existsCallsListVersions() bool {
+	return false
+}
+
+func (*archiveSource) listVersionsRequiresLocal() bool {
+	return true
+}
+
+// httpGet makes a GET request for the URL, failing unless the response is a
+// success.
+func httpGet(ctx context.Context, u string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to build HTTP request for URL %q", u)
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed HTTP request to URL %q", u)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, httpStatusError{url: u, code: resp.StatusCode, status: resp.Status}
+	}
+	return resp, nil
+}
+
+// readVersionsFile reads the versions, and their revisions, recorded by
+// writeVersionsFile. A missing file holds no versions.
+func readVersionsFile(path string) ([]PairedVersion, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var pvs []PairedVersion
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		pvs = append(pvs, NewVersion(fields[0]).Pair(Revision(fields[1])))
+	}
+	return pvs, sc.Err()
+}
+
+// writeVersionsFile records the versions and their revisions in path, one
+// per line.
+func writeVersionsFile(path string, pvs []PairedVersion) error {
+	var buf bytes.Buffer
+	for _, pv := range pvs {
+		fmt.Fprintf(&buf, "%s %s\n", pv, pv.Revision())
+	}
+	return errors.Wrapf(ioutil.WriteFile(path, buf.Bytes(), 0666), "failed to write %s", path)
+}
+
+// archiveRoot returns the directory within dir that holds the unpacked tree:
+// the single top-level directory of the archive, if it has one, or dir
+// itself.
+func archiveRoot(dir string) (string, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(fis) == 1 && fis[0].IsDir() {
+		return filepath.Join(dir, fis[0].Name()), nil
+	}
+	return dir, nil
+}
+
+// archiveEntryPath returns the path at which an archive entry is unpacked
+// into dir, refusing entries that would end up outside of it.
+func archiveEntryPath(dir, name string) (string, error) {
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if path != dir && !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+		return "", errors.Errorf("archive entry %q is outside of the archive", name)
+	}
+	return path, nil
+}
+
+// writeArchiveFile writes the contents of an archive entry to path. Only the
+// executable bit of the entry's mode is kept.
+func writeArchiveFile(path string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+
+	perm := os.FileMode(0666)
+	if mode&0111 != 0 {
+		perm = 0777
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// unpackTarGz unpacks the gzipped tarball into dir. Only directories and
+// regular files are unpacked.
+func unpackTarGz(f *os.File, dir string) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		path, err := archiveEntryPath(dir, hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, 0777)
+		case tar.TypeReg, tar.TypeRegA:
+			err = writeArchiveFile(path, tr, hdr.FileInfo().Mode())
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// unpackZip unpacks the zip file into dir, stripping prefix from the names of
+// its entries, all of which must have it. Only directories and regular files
+// are unpacked.
+func unpackZip(f *os.File, dir, prefix string) error {
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(f, fi.Size())
+	if err != nil {
+		return err
+	}
+
+	for _, zf := range zr.File {
+		if !strings.HasPrefix(zf.Name, prefix) {
+			return errors.Errorf("archive entry %q is not within %s", zf.Name, prefix)
+		}
+		path, err := archiveEntryPath(dir, strings.TrimPrefix(zf.Name, prefix))
+		if err != nil {
+			return err
+		}
+
+		mode := zf.Mode()
+		switch {
+		case mode.IsDir():
+			err = os.MkdirAll(path, 0777)
+		case mode.IsRegular():
+			var rc io.ReadCloser
+			if rc, err = zf.Open(); err == nil {
+				err = writeArchiveFile(path, rc, mode)
+				rc.Close()
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,335 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func mkTarGz(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range sortedKeys(files) {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name])), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func mkZip(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range sortedKeys(files) {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// archiveServer serves files over HTTP, counting the requests for each path.
+type archiveServer struct {
+	*httptest.Server
+	mu    sync.Mutex
+	files map[string][]byte
+	hits  map[string]int
+}
+
+func newArchiveServer(files map[string][]byte) *archiveServer {
+	as := &archiveServer{files: files, hits: make(map[string]int)}
+	as.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		as.mu.Lock()
+		defer as.mu.Unlock()
+		as.hits[r.URL.Path]++
+		b, ok := as.files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(b)
+	}))
+	return as
+}
+
+func (as *archiveServer) set(path string, b []byte) {
+	as.mu.Lock()
+	as.files[path] = b
+	as.mu.Unlock()
+}
+
+func (as *archiveServer) hitsFor(path string) int {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	return as.hits[path]
+}
+
+func TestParseArchiveSource(t *testing.T) {
+	cases := map[string]struct {
+		ok, err bool
+		spec    archiveSpec
+	}{
+		"https://example.com/foo-{version}.tar.gz#index=https://example.com/versions": {
+			ok: true,
+			spec: archiveSpec{
+				template: "https://example.com/foo-{version}.tar.gz",
+				index:    "https://example.com/versions",
+			},
+		},
+		"http://example.com/foo-1.0.zip#version=1.0": {
+			ok: true,
+			spec: archiveSpec{
+				template: "http://example.com/foo-1.0.zip",
+				version:  "1.0",
+				zip:      true,
+			},
+		},
+		"https://example.com/foo.tgz#version=v2": {
+			ok: true,
+			spec: archiveSpec{
+				template: "https://example.com/foo.tgz",
+				version:  "v2",
+			},
+		},
+		"https://example.com/foo-{version}.tar.gz":                              {ok: true, err: true},
+		"https://example.com/foo.tar.gz#index=https://example.com/versions":     {ok: true, err: true},
+		"https://example.com/foo-{version}.zip#index=https://e.com/i&version=1": {ok: true, err: true},
+		"https://github.com/foo/bar":                                            {},
+		"github.com/foo/bar.zip":                                                {},
+		"git@github.com:foo/bar.git":                                            {},
+	}
+
+	for source, want := range cases {
+		spec, ok, err := parseArchiveSource(source)
+		if ok != want.ok || (err != nil) != want.err {
+			t.Errorf("parseArchiveSource(%q) = %v, %v; want %v, error %v", source, ok, err, want.ok, want.err)
+			continue
+		}
+		if ok && err == nil {
+			want.spec.source = source
+			if spec != want.spec {
+				t.Errorf("parseArchiveSource(%q) = %+v, want %+v", source, spec, want.spec)
+			}
+		}
+	}
+}
+
+func TestArchiveSource(t *testing.T) {
+	v1 := mkTarGz(t, map[string]string{
+		"foo-1.0.0/foo.go":     "package foo\n\nimport _ \"github.com/sdboyer/deptest\"\n",
+		"foo-1.0.0/bar/bar.go": "package bar\n",
+	})
+	v2 := mkTarGz(t, map[string]string{
+		"foo-1.1.0/foo.go": "package foo\n",
+	})
+
+	as := newArchiveServer(map[string][]byte{
+		"/foo-1.0.0.tar.gz": v1,
+		"/foo-1.1.0.tar.gz": v2,
+		"/versions":         []byte("# releases\n1.0.0 " + sha256Hex(v1) + "\n1.1.0 " + sha256Hex(v2) + "\n"),
+	})
+	defer as.Close()
+
+	sm, clean := mkNaiveSM(t)
+	defer clean()
+
+	id := ProjectIdentifier{
+		ProjectRoot: "example.com/foo",
+		Source:      as.URL + "/foo-{version}.tar.gz#index=" + as.URL + "/versions",
+	}
+	vl, err := sm.ListVersions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SortPairedForUpgrade(vl)
+	want := []PairedVersion{
+		NewVersion("1.1.0").Pair(Revision(sha256Hex(v2))),
+		NewVersion("1.0.0").Pair(Revision(sha256Hex(v1))),
+	}
+	if len(vl) != len(want) || vl[0] != want[0] || vl[1] != want[1] {
+		t.Fatalf("unexpected versions:\n\t(GOT): %v\n\t(WNT): %v", vl, want)
+	}
+	for _, path := range []string{"/foo-1.0.0.tar.gz", "/foo-1.1.0.tar.gz"} {
+		if n := as.hitsFor(path); n != 0 {
+			t.Errorf("expected listing the versions not to fetch %s, but it was fetched %d times", path, n)
+		}
+	}
+
+	ptree, err := sm.ListPackages(id, want[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, has := ptree.Packages["example.com/foo/bar"]; !has || len(ptree.Packages) != 2 {
+		t.Fatalf("unexpected packages: %v", ptree.Packages)
+	}
+	if n := as.hitsFor("/foo-1.0.0.tar.gz"); n != 1 {
+		t.Errorf("expected the archive of 1.0.0 to be fetched once, but it was fetched %d times", n)
+	}
+
+	if _, _, err := sm.GetManifestAndLock(id, want[0], naiveAnalyzer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "archivesource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	to := filepath.Join(dir, "foo")
+	if err := sm.ExportProject(context.Background(), id, want[1], to); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(to, "bar", "bar.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	css, err := sm.CachedSources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(css) != 1 || css[0].Type != "archive" || css[0].URL != id.Source {
+		t.Fatalf("unexpected cached sources: %+v", css)
+	}
+	if err := sm.VerifyCachedSource(context.Background(), css[0], true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchiveSourceChecksumMismatch(t *testing.T) {
+	orig := mkZip(t, map[string]string{"foo.go": "package foo\n"})
+	as := newArchiveServer(map[string][]byte{"/foo.zip": orig})
+	defer as.Close()
+
+	sm, clean := mkNaiveSM(t)
+	defer clean()
+
+	id := ProjectIdentifier{
+		ProjectRoot: "example.com/foo",
+		Source:      as.URL + "/foo.zip#version=1.0.0",
+	}
+	locked := NewVersion("1.0.0").Pair(Revision(sha256Hex(orig)))
+
+	// The archive is republished with different contents, after the original
+	// was recorded in a lock.
+	as.set("/foo.zip", mkZip(t, map[string]string{"foo.go": "package foo // changed\n"}))
+
+	err := sm.ExportProject(context.Background(), id, locked, filepath.Join(sm.cachedir, "export"))
+	if err == nil {
+		t.Fatal("expected exporting a republished archive to fail")
+	}
+	if !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected a checksum mismatch, got: %s", err)
+	}
+}
+
+func TestArchiveSourceIndexWithoutSums(t *testing.T) {
+	as := newArchiveServer(map[string][]byte{
+		"/foo-1.0.0.tar.gz": mkTarGz(t, map[string]string{"foo-1.0.0/foo.go": "package foo\n"}),
+		"/versions":         []byte("1.0.0\n"),
+	})
+	defer as.Close()
+
+	sm, clean := mkNaiveSM(t)
+	defer clean()
+
+	id := ProjectIdentifier{
+		ProjectRoot: "example.com/foo",
+		Source:      as.URL + "/foo-{version}.tar.gz#index=" + as.URL + "/versions",
+	}
+	if _, err := sm.ListVersions(id); err == nil {
+		t.Fatal("expected an index without the sha256 of an archive to be rejected")
+	}
+	if n := as.hitsFor("/foo-1.0.0.tar.gz"); n != 0 {
+		t.Errorf("expected the archive not to be fetched, but it was fetched %d times", n)
+	}
+}
+
+func TestArchiveSourceOffline(t *testing.T) {
+	archive := mkTarGz(t, map[string]string{"foo.go": "package foo\n"})
+	as := newArchiveServer(map[string][]byte{"/foo.tar.gz": archive})
+	defer as.Close()
+
+	sm, clean := mkNaiveSM(t)
+	defer clean()
+
+	id := ProjectIdentifier{
+		ProjectRoot: "example.com/foo",
+		Source:      as.URL + "/foo.tar.gz#version=1.0.0",
+	}
+	vl, err := sm.ListVersions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vl) != 1 {
+		t.Fatalf("expected a single version, got %v", vl)
+	}
+
+	// Once the archive is in the cache, an offline SourceMgr can serve it.
+	sm.Release()
+	osm, err := NewSourceManager(SourceManagerConfig{Cachedir: sm.cachedir, Offline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer osm.Release()
+	as.Close()
+
+	ovl, err := osm.ListVersions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ovl) != 1 || ovl[0] != vl[0] {
+		t.Fatalf("unexpected offline versions %v, want %v", ovl, vl)
+	}
+	if _, err := osm.ListPackages(id, ovl[0]); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -150,7 +150,10 @@ func (b *bridge) ListPackages(id ProjectIdentifier, v Version) (pkgtree.PackageT
 	b.s.mtr.push("b-list-pkgs")
 	pt, err := b.sm.ListPackages(id, v)
 	b.s.mtr.pop()
-	return pt, err
+	if err != nil {
+		return pt, err
+	}
+	return pt.ForPlatforms(b.s.rd.plats), nil
 }
 
 func (b *bridge) ExportProject(id ProjectIdentifier, v Version, path string) error {
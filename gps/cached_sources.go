@@ -0,0 +1,227 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/Masterminds/vcs"
+	"github.com/pkg/errors"
+)
+
+// CachedSource describes a local copy of an upstream source, kept in the
+// SourceMgr's cache directory.
+type CachedSource struct {
+	// Path is the location of the copy on disk.
+	Path string
+	// Type is the kind of VCS repository the copy is - "git", "hg", "bzr" or
+	// "svn" - or "archive" or "proxy" for the unpacked trees of an archive
+	// source or a module proxy source. It is empty if it could not be
+	// determined, in which case the copy is unusable.
+	Type string
+	// URL is the upstream location of the source, if it could be determined.
+	URL string
+	// Size is the total size, in bytes, of the files in the copy.
+	Size int64
+	// LastUsed is the last time the copy was used by a SourceMgr.
+	LastUsed time.Time
+}
+
+// CachedSources returns the local copies of sources kept in the cache
+// directory, ordered by path.
+func (sm *SourceMgr) CachedSources() ([]CachedSource, error) {
+	if atomic.LoadInt32(&sm.releasing) == 1 {
+		return nil, ErrSourceManagerIsReleased
+	}
+
+	dir := filepath.Join(sm.cachedir, "sources")
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read source cache directory %s", dir)
+	}
+
+	var css []CachedSource
+	for _, fi := range fis {
+		// Other caches, such as bolt.db, live alongside the sources.
+		if !fi.IsDir() {
+			continue
+		}
+
+		cs := CachedSource{
+			Path:     filepath.Join(dir, fi.Name()),
+			LastUsed: fi.ModTime(),
+		}
+		if cs.Size, err = dirSize(cs.Path); err != nil {
+			return nil, err
+		}
+		if typ, source, ok := unpackedSourceIn(cs.Path); ok {
+			cs.Type = typ
+			cs.URL = source
+		} else if r, err := openCachedRepo(cs.Path); err == nil {
+			cs.Type = string(r.Vcs())
+			cs.URL = r.Remote()
+		}
+		css = append(css, cs)
+	}
+
+	sort.Slice(css, func(i, j int) bool {
+		return css[i].Path < css[j].Path
+	})
+	return css, nil
+}
+
+// CachePathsFor returns the locations in the cache directory at which local
+// copies of the source for the given ProjectIdentifier may be kept. The
+// copies need not exist.
+func (sm *SourceMgr) CachePathsFor(id ProjectIdentifier) ([]string, error) {
+	if atomic.LoadInt32(&sm.releasing) == 1 {
+		return nil, ErrSourceManagerIsReleased
+	}
+
+	deduced, err := sm.deduceCoord.deduceRootPath(context.TODO(), id.normalizedSource())
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, mb := range deduced.mb {
+		// Local sources are used in place, rather than kept in the cache.
+		if path := mb.cachePath(sm.cachedir); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// VerifyCachedSource checks that the local copy of a source is an intact
+// repository. If clean is set, its working tree is cleaned up if necessary,
+// and an error is returned if the copy cannot be recovered; otherwise the
+// copy is left as it is, and only checked to be a repository.
+func (sm *SourceMgr) VerifyCachedSource(ctx context.Context, cs CachedSource, clean bool) error {
+	if atomic.LoadInt32(&sm.releasing) == 1 {
+		return ErrSourceManagerIsReleased
+	}
+
+	// Archives and module zips are only ever unpacked into place once
+	// complete, so there is nothing to verify.
+	if _, _, ok := unpackedSourceIn(cs.Path); ok {
+		return nil
+	}
+
+	r, err := openCachedRepo(cs.Path)
+	if err != nil {
+		return err
+	}
+
+	ec, ok := r.(ensureCleaner)
+	if !ok || !clean {
+		return nil
+	}
+	return unwrapVcsErr(ec.ensureClean(ctx))
+}
+
+// RemoveCachedSource deletes the local copy of a source from the cache
+// directory. The source will be fetched again from upstream the next time it
+// is needed.
+//
+// The source must not be in use by the SourceMgr.
+func (sm *SourceMgr) RemoveCachedSource(cs CachedSource) error {
+	if atomic.LoadInt32(&sm.releasing) == 1 {
+		return ErrSourceManagerIsReleased
+	}
+
+	dir := filepath.Join(sm.cachedir, "sources")
+	if filepath.Dir(filepath.Clean(cs.Path)) != dir {
+		return errors.Errorf("%s is not a source in the cache directory %s", cs.Path, dir)
+	}
+
+	return errors.Wrapf(os.RemoveAll(cs.Path), "failed to remove %s", cs.Path)
+}
+
+// openCachedRepo opens the repository at path, of whichever VCS type it turns
+// out to be, using the upstream URL it records.
+func openCachedRepo(path string) (ctxRepo, error) {
+	typ, err := vcs.DetectVcsFromFS(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "no repository found at %s", path)
+	}
+
+	switch typ {
+	case vcs.Git:
+		r, err := vcs.NewGitRepo("", path)
+		if err != nil {
+			return nil, unwrapVcsErr(err)
+		}
+		return &gitRepo{r}, nil
+	case vcs.Hg:
+		r, err := vcs.NewHgRepo("", path)
+		if err != nil {
+			return nil, unwrapVcsErr(err)
+		}
+		return &hgRepo{r}, nil
+	case vcs.Bzr:
+		r, err := vcs.NewBzrRepo("", path)
+		if err != nil {
+			return nil, unwrapVcsErr(err)
+		}
+		return &bzrRepo{r}, nil
+	case vcs.Svn:
+		r, err := vcs.NewSvnRepo("", path)
+		if err != nil {
+			return nil, unwrapVcsErr(err)
+		}
+		return &svnRepo{r}, nil
+	}
+
+	return nil, errors.Errorf("unsupported repository type %q at %s", typ, path)
+}
+
+// unpackedSourceIn returns the type and URL of the source whose unpacked
+// trees are kept at path, if it is an archive or module proxy source.
+func unpackedSourceIn(path string) (typ, source string, ok bool) {
+	markers := []struct{ file, typ string }{
+		{archiveMarkerFile, "archive"},
+		{proxyMarkerFile, "proxy"},
+	}
+	for _, m := range markers {
+		if b, err := ioutil.ReadFile(filepath.Join(path, m.file)); err == nil {
+			return m.typ, string(b), true
+		}
+	}
+	return "", "", false
+}
+
+// markSourceUsed records that the local copy of a source at path is being used
+// by updating its modification time, which CachedSources reports as LastUsed.
+func markSourceUsed(path string) {
+	if path == "" {
+		return
+	}
+	now := time.Now()
+	// Failing to record the time only affects garbage collection of the cache,
+	// so it is not worth failing over.
+	_ = os.Chtimes(path, now, now)
+}
+
+// dirSize returns the total size of the regular files within dir.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			size += fi.Size()
+		}
+		return nil
+	})
+	return size, errors.Wrapf(err, "failed to determine size of %s", dir)
+}
@@ -0,0 +1,104 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/dep/internal/test"
+)
+
+func TestSourceMgrCachedSources(t *testing.T) {
+	requiresBins(t, "git")
+
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("smcache")
+
+	// Offline, so that nothing is fetched from upstream.
+	sm, err := NewSourceManager(SourceManagerConfig{
+		Cachedir: h.Path("smcache"),
+		Offline:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Release()
+
+	paths, err := sm.CachePathsFor(mkPI("github.com/sdboyer/deptest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("expected cache paths for github.com/sdboyer/deptest")
+	}
+
+	// A usable repository, one that is not a repository at all, and a file
+	// that is not a source.
+	repoPath := paths[0]
+	h.TempDir(filepath.Join("smcache", "sources", filepath.Base(repoPath)))
+	h.RunGit(repoPath, "init")
+	h.RunGit(repoPath, "remote", "add", "origin", "https://github.com/sdboyer/deptest")
+	h.TempFile(filepath.Join("smcache", "sources", "junk", "file"), "junk")
+	h.TempFile(filepath.Join("smcache", "sources", "bolt.db"), "")
+
+	css, err := sm.CachedSources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(css) != 2 {
+		t.Fatalf("expected 2 cached sources, got %d: %v", len(css), css)
+	}
+
+	var repo, junk CachedSource
+	for _, cs := range css {
+		if cs.Path == repoPath {
+			repo = cs
+		} else {
+			junk = cs
+		}
+	}
+	if repo.Type != "git" || repo.URL != "https://github.com/sdboyer/deptest" {
+		t.Errorf("unexpected type %q and URL %q for git source", repo.Type, repo.URL)
+	}
+	if junk.Path != h.Path(filepath.Join("smcache", "sources", "junk")) || junk.Type != "" || junk.URL != "" {
+		t.Errorf("unexpected cached source for junk directory: %v", junk)
+	}
+	if junk.Size != 4 {
+		t.Errorf("expected size of junk directory to be 4, got %d", junk.Size)
+	}
+
+	ctx := context.Background()
+	untracked := filepath.Join(repoPath, "untracked")
+	h.TempFile(filepath.Join("smcache", "sources", filepath.Base(repoPath), "untracked"), "")
+	if err := sm.VerifyCachedSource(ctx, repo, false); err != nil {
+		t.Errorf("unexpected error verifying git source: %v", err)
+	}
+	if _, err := os.Stat(untracked); err != nil {
+		t.Errorf("expected the working tree to be left as it was without clean, got %v", err)
+	}
+	if err := sm.VerifyCachedSource(ctx, repo, true); err != nil {
+		t.Errorf("unexpected error verifying git source: %v", err)
+	}
+	if _, err := os.Stat(untracked); !os.IsNotExist(err) {
+		t.Errorf("expected the working tree to be cleaned up, got %v", err)
+	}
+	if err := sm.VerifyCachedSource(ctx, junk, true); err == nil {
+		t.Error("expected error verifying junk directory")
+	}
+
+	if err := sm.RemoveCachedSource(junk); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(junk.Path); !os.IsNotExist(err) {
+		t.Errorf("expected junk directory to be removed, got %v", err)
+	}
+	if err := sm.RemoveCachedSource(CachedSource{Path: h.Path("smcache")}); err == nil {
+		t.Error("expected error removing a path that is not a cached source")
+	}
+}
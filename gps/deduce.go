@@ -26,6 +26,9 @@ var (
 	hgSchemes      = []string{"https", "ssh", "http"}
 	svnSchemes     = []string{"https", "http", "svn", "svn+ssh"}
 	gopkginSchemes = []string{"https", "http"}
+	// GitLab and Gitea serve git repositories over neither git:// nor plain
+	// ssh as any user other than git.
+	forgeSchemes = []string{"https", "ssh", "http"}
 )
 
 const gopkgUnstableSuffix = "-unstable"
@@ -72,6 +75,7 @@ var (
 	glpRegex = regexp.MustCompile(`^(?P<root>git\.launchpad\.net(/[A-Za-z0-9_.\-]+))((?:/[A-Za-z0-9_.\-]+)*)$`)
 	//gcRegex      = regexp.MustCompile(`^(?P<root>code\.google\.com/[pr]/(?P<project>[a-z0-9\-]+)(\.(?P<subrepo>[a-z0-9\-]+))?)(/[A-Za-z0-9_.\-]+)*$`)
 	jazzRegex         = regexp.MustCompile(`^(?P<root>hub\.jazz\.net(/git/[a-z0-9]+/[A-Za-z0-9_.\-]+))((?:/[A-Za-z0-9_.\-]+)*)$`)
+	forgeElemRegex    = regexp.MustCompile(`^[A-Za-z0-9_.\-]+$`)
 	apacheRegex       = regexp.MustCompile(`^(?P<root>git\.apache\.org(/[a-z0-9_.\-]+\.git))((?:/[A-Za-z0-9_.\-]+)*)$`)
 	vcsExtensionRegex = regexp.MustCompile(`^(?P<root>([a-z0-9.\-]+\.)+[a-z0-9.\-]+(:[0-9]+)?/[A-Za-z0-9_.\-/~]*?\.(?P<vcs>bzr|git|hg|svn))((?:/[A-Za-z0-9_.\-]+)*)$`)
 )
@@ -92,6 +96,7 @@ func pathDeducerTrie() *deducerTrie {
 	dxt.Insert("git.launchpad.net/", launchpadGitDeducer{regexp: glpRegex})
 	dxt.Insert("hub.jazz.net/", jazzDeducer{regexp: jazzRegex})
 	dxt.Insert("git.apache.org/", apacheDeducer{regexp: apacheRegex})
+	dxt.Insert("gitlab.com/", gitlabDeducer{host: "gitlab.com"})
 
 	return dxt
 }
@@ -467,6 +472,122 @@ func (m apacheDeducer) deduceSource(path string, u *url.URL) (maybeSources, erro
 	return mb, nil
 }
 
+// gitlabDeducer deduces the import paths on gitlab.com, or on a self-hosted
+// GitLab. Projects there may be nested in any number of subgroups, so their
+// roots can only be told from paths made of just a group and a project, or in
+// which the project has a .git suffix:
+//
+//	gitlab.com/group/project
+//	gitlab.com/group/sub/project.git/pkg
+//
+// Other paths are left to go-get metadata.
+type gitlabDeducer struct {
+	host string
+}
+
+func (m gitlabDeducer) deduceRoot(path string) (string, error) {
+	elems, err := forgePathElems(m.host, path)
+	if err != nil {
+		return "", err
+	}
+
+	for i, elem := range elems {
+		if strings.HasSuffix(elem, ".git") {
+			if i == 0 {
+				return "", fmt.Errorf("%s is not a valid path for a source on %s", path, m.host)
+			}
+			return m.host + "/" + strings.Join(elems[:i+1], "/"), nil
+		}
+	}
+	if len(elems) == 2 {
+		return path, nil
+	}
+	// There is no telling whether the path goes through subgroups, or into
+	// the packages of a project.
+	return "", errNoKnownPathMatch
+}
+
+func (m gitlabDeducer) deduceSource(path string, u *url.URL) (maybeSources, error) {
+	root, err := m.deduceRoot(path)
+	if err != nil {
+		return nil, err
+	}
+	return forgeSources(m.host, root, u)
+}
+
+// giteaDeducer deduces the import paths on a self-hosted Gitea, whose
+// projects are always made of an owner and a repository.
+type giteaDeducer struct {
+	host string
+}
+
+func (m giteaDeducer) deduceRoot(path string) (string, error) {
+	elems, err := forgePathElems(m.host, path)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasSuffix(elems[0], ".git") {
+		return "", fmt.Errorf("%s is not a valid path for a source on %s", path, m.host)
+	}
+	return m.host + "/" + elems[0] + "/" + elems[1], nil
+}
+
+func (m giteaDeducer) deduceSource(path string, u *url.URL) (maybeSources, error) {
+	root, err := m.deduceRoot(path)
+	if err != nil {
+		return nil, err
+	}
+	return forgeSources(m.host, root, u)
+}
+
+// forgePathElems returns the elements of path after host, of which there must
+// be at least two.
+func forgePathElems(host, path string) ([]string, error) {
+	if !strings.HasPrefix(path, host+"/") {
+		return nil, fmt.Errorf("%s is not a valid path for a source on %s", path, host)
+	}
+	elems := strings.Split(strings.TrimPrefix(path, host+"/"), "/")
+	if len(elems) < 2 {
+		return nil, fmt.Errorf("%s is not a valid path for a source on %s", path, host)
+	}
+	for _, elem := range elems {
+		if !forgeElemRegex.MatchString(elem) {
+			return nil, fmt.Errorf("%s is not a valid path for a source on %s", path, host)
+		}
+	}
+	return elems, nil
+}
+
+// forgeSources returns the maybeSources for the git repository of the project
+// at root on a GitLab or Gitea host.
+func forgeSources(host, root string, u *url.URL) (maybeSources, error) {
+	u.Host = host
+	u.Path = strings.TrimPrefix(root, host)
+
+	if u.Scheme == "ssh" && u.User != nil && u.User.Username() != "git" {
+		return nil, fmt.Errorf("%s ssh must be accessed via the 'git' user; %s was provided", host, u.User.Username())
+	} else if u.Scheme != "" {
+		if !validateVCSScheme(u.Scheme, "git") {
+			return nil, fmt.Errorf("%s is not a valid scheme for accessing a git repository", u.Scheme)
+		}
+		if u.Scheme == "ssh" {
+			u.User = url.User("git")
+		}
+		return maybeSources{maybeGitSource{url: u}}, nil
+	}
+
+	mb := make(maybeSources, len(forgeSchemes))
+	for k, scheme := range forgeSchemes {
+		u2 := *u
+		if scheme == "ssh" {
+			u2.User = url.User("git")
+		}
+		u2.Scheme = scheme
+		mb[k] = maybeGitSource{url: &u2}
+	}
+	return mb, nil
+}
+
 type vcsExtensionDeducer struct {
 	regexp *regexp.Regexp
 }
@@ -552,6 +673,9 @@ func (m vcsExtensionDeducer) deduceSource(path string, u *url.URL) (maybeSources
 // primarily intended for testing purposes.
 type deducer interface {
 	deduceRootPath(ctx context.Context, path string) (pathDeduction, error)
+	// forget discards the deduction of root, which turned out to be
+	// unusable, so that it is deduced afresh next time.
+	forget(root string)
 }
 
 type deductionCoordinator struct {
@@ -559,13 +683,20 @@ type deductionCoordinator struct {
 	mut      sync.RWMutex
 	rootxt   *radix.Tree
 	deducext *deducerTrie
+	offline  bool   // if set, never fetch go get metadata
+	proxy    string // if set, the module proxy to try before repositories
+	mirrors  mirrorTable
+	cache    *deductionCache // if set, where go get metadata persists
 }
 
-func newDeductionCoordinator(superv *supervisor) *deductionCoordinator {
+func newDeductionCoordinator(superv *supervisor, c SourceManagerConfig) *deductionCoordinator {
 	dc := &deductionCoordinator{
 		suprvsr:  superv,
 		rootxt:   radix.New(),
 		deducext: pathDeducerTrie(),
+		offline:  c.Offline,
+		proxy:    c.Proxy,
+		mirrors:  newMirrorTable(c.Mirrors),
 	}
 
 	return dc
@@ -626,6 +757,9 @@ func (dc *deductionCoordinator) deduceRootPath(ctx context.Context, path string)
 	hmd := &httpMetadataDeducer{
 		basePath: path,
 		suprvsr:  dc.suprvsr,
+		finish:   dc.finish,
+		cache:    dc.cache,
+		offline:  dc.offline,
 		// The vanity deducer will call this func with a completed
 		// pathDeduction if it succeeds in finding one. We process it
 		// back through the action channel to ensure serialized
@@ -647,6 +781,21 @@ func (dc *deductionCoordinator) deduceRootPath(ctx context.Context, path string)
 	return hmd.deduce(ctx, path)
 }
 
+// forget removes the deduction of root from the rootxt, and discards any go
+// get metadata cached for it.
+func (dc *deductionCoordinator) forget(root string) {
+	dc.mut.Lock()
+	if data, has := dc.rootxt.Get(root); has {
+		// An hmd still in flight is left alone.
+		if _, ok := data.(maybeSources); ok {
+			dc.rootxt.Delete(root)
+		}
+	}
+	dc.mut.Unlock()
+
+	dc.cache.invalidate(root)
+}
+
 // pathDeduction represents the results of a successful import path deduction -
 // a root path, plus a maybeSource that can be used to attempt to connect to
 // the source.
@@ -658,6 +807,25 @@ type pathDeduction struct {
 var errNoKnownPathMatch = errors.New("no known path match")
 
 func (dc *deductionCoordinator) deduceKnownPaths(path string) (pathDeduction, error) {
+	// A source that is a local directory is used as it is.
+	if dir, ok := LocalSourceDir(path); ok {
+		return pathDeduction{
+			root: path,
+			mb:   maybeSources{maybeLocalSource{dir: dir}},
+		}, nil
+	}
+
+	// So is one that refers to release archives.
+	if spec, ok, err := parseArchiveSource(path); ok {
+		if err != nil {
+			return pathDeduction{}, err
+		}
+		return pathDeduction{
+			root: path,
+			mb:   maybeSources{maybeArchiveSource{spec: spec, offline: dc.offline}},
+		}, nil
+	}
+
 	u, path, err := normalizeURI(path)
 	if err != nil {
 		return pathDeduction{}, err
@@ -666,18 +834,23 @@ func (dc *deductionCoordinator) deduceKnownPaths(path string) (pathDeduction, er
 	// First, try the root path-based matches
 	if _, mtch, has := dc.deducext.LongestPrefix(path); has {
 		root, err := mtch.deduceRoot(path)
-		if err != nil {
-			return pathDeduction{}, err
-		}
-		mb, err := mtch.deduceSource(path, u)
-		if err != nil {
+		switch {
+		case err == errNoKnownPathMatch:
+			// The deducer cannot tell the root from the path alone; go-get
+			// metadata may.
+		case err != nil:
 			return pathDeduction{}, err
-		}
+		default:
+			mb, err := mtch.deduceSource(path, u)
+			if err != nil {
+				return pathDeduction{}, err
+			}
 
-		return pathDeduction{
-			root: root,
-			mb:   mb,
-		}, nil
+			return pathDeduction{
+				root: root,
+				mb:   dc.finish(root, u, mb),
+			}, nil
+		}
 	}
 
 	// Next, try the vcs extension-based (infix) matcher
@@ -690,13 +863,31 @@ func (dc *deductionCoordinator) deduceKnownPaths(path string) (pathDeduction, er
 
 		return pathDeduction{
 			root: root,
-			mb:   mb,
+			mb:   dc.finish(root, u, mb),
 		}, nil
 	}
 
 	return pathDeduction{}, errNoKnownPathMatch
 }
 
+// finish completes the maybeSources deduced for the project at root, from
+// the import path or URL u: the URLs of repositories are rewritten by the
+// mirrors, and the module proxy is put ahead of them.
+func (dc *deductionCoordinator) finish(root string, u *url.URL, mb maybeSources) maybeSources {
+	return dc.proxied(root, u, mb.mirrored(dc.mirrors))
+}
+
+// proxied puts the module proxy, if there is one, ahead of the maybeSources
+// deduced for the project at root, so that the repositories are only
+// consulted for modules the proxy does not have. Sources given as explicit
+// URLs name a repository, and always go to it.
+func (dc *deductionCoordinator) proxied(root string, u *url.URL, mb maybeSources) maybeSources {
+	if dc.proxy == "" || u.Scheme != "" {
+		return mb
+	}
+	return append(maybeSources{maybeProxySource{proxy: dc.proxy, module: root, offline: dc.offline}}, mb...)
+}
+
 type httpMetadataDeducer struct {
 	once       sync.Once
 	deduced    pathDeduction
@@ -704,6 +895,9 @@ type httpMetadataDeducer struct {
 	basePath   string
 	returnFunc func(pathDeduction)
 	suprvsr    *supervisor
+	finish     func(root string, u *url.URL, mb maybeSources) maybeSources
+	cache      *deductionCache
+	offline    bool
 }
 
 func (hmd *httpMetadataDeducer) deduce(ctx context.Context, path string) (pathDeduction, error) {
@@ -718,20 +912,30 @@ func (hmd *httpMetadataDeducer) deduce(ctx context.Context, path string) (pathDe
 
 		pd := pathDeduction{}
 
-		// Make the HTTP call to attempt to retrieve go-get metadata
-		var root, vcs, reporoot string
-		err = hmd.suprvsr.do(ctx, path, ctHTTPMetadata, func(ctx context.Context) error {
-			root, vcs, reporoot, err = getMetadata(ctx, path, u.Scheme)
+		// Unless it was cached, make the HTTP call to attempt to retrieve
+		// go-get metadata. Offline, cached metadata is used even if it has
+		// expired.
+		md, cached := hmd.cache.get(u.Scheme, path, hmd.offline)
+		if !cached {
+			if hmd.offline {
+				hmd.deduceErr = OfflineError{What: "go get metadata for " + opath}
+				return
+			}
+
+			err = hmd.suprvsr.do(ctx, path, ctHTTPMetadata, func(ctx context.Context) error {
+				md.root, md.vcs, md.repoRoot, err = getMetadata(ctx, path, u.Scheme)
+				if err != nil {
+					err = errors.Wrapf(err, "unable to read metadata")
+				}
+				return err
+			})
 			if err != nil {
-				err = errors.Wrapf(err, "unable to read metadata")
+				err = errors.Wrapf(err, "unable to deduce repository and source type for %q", opath)
+				hmd.deduceErr = err
+				return
 			}
-			return err
-		})
-		if err != nil {
-			err = errors.Wrapf(err, "unable to deduce repository and source type for %q", opath)
-			hmd.deduceErr = err
-			return
 		}
+		root, vcs, reporoot := md.root, md.vcs, md.repoRoot
 		pd.root = root
 
 		// If we got something back at all, then it supersedes the actual input for
@@ -768,6 +972,10 @@ func (hmd *httpMetadataDeducer) deduce(ctx context.Context, path string) (pathDe
 			hmd.deduceErr = errors.Errorf("unsupported vcs type %s in go-get metadata from %s", vcs, path)
 			return
 		}
+		pd.mb = hmd.finish(root, u, pd.mb)
+		if !cached {
+			hmd.cache.put(u.Scheme, path, md)
+		}
 
 		hmd.deduced = pd
 		// All data is assigned for other goroutines that may be waiting. Now,
@@ -852,6 +1060,12 @@ func doFetchMetadata(ctx context.Context, scheme, path string) (io.ReadCloser, e
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed HTTP request to URL %q", url)
 		}
+		// Metadata may come with any status, as it does in 404 pages; only
+		// the ones saying the server cannot answer for now are failures.
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			resp.Body.Close()
+			return nil, httpStatusError{url: url, code: resp.StatusCode, status: resp.Status}
+		}
 
 		return resp.Body, nil
 	default:
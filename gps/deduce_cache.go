@@ -0,0 +1,196 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"encoding/binary"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+)
+
+// deductionBucket is the top-level bucket of the bolt cache holding go-get
+// metadata. Unlike the names of the buckets of sources, it is neither an
+// import path, a URL nor a directory.
+var deductionBucket = []byte("go-get")
+
+// Keys of the fields of a cached deduction, in the bucket named by the path
+// it was fetched for.
+var (
+	deductionRootKey = []byte("root")
+	deductionVCSKey  = []byte("vcs")
+	deductionRepoKey = []byte("repo")
+	deductionTimeKey = []byte("time")
+)
+
+// deductionCache persists the go-get metadata fetched for import paths in
+// the bolt cache, so that later runs need not fetch it again until it
+// expires. Its methods are safe for concurrent use, and do nothing on a nil
+// *deductionCache.
+//
+// Implementation:
+//
+//	Bucket: "go-get"
+//	Sub-Bucket: "<scheme>://<path>", or "<path>" if no scheme was given
+//	Keys/Values: "root", "vcs", "repo" and "time", the unix time it was
+//	fetched at
+type deductionCache struct {
+	*boltCache
+	ttl time.Duration
+}
+
+// newDeductionCache returns a deductionCache whose entries are valid for ttl.
+func (c *boltCache) newDeductionCache(ttl time.Duration) *deductionCache {
+	return &deductionCache{boltCache: c, ttl: ttl}
+}
+
+// cachedMetadata is the go-get metadata of a project root.
+type cachedMetadata struct {
+	root, vcs, repoRoot string
+}
+
+// deductionKey returns the name of the bucket holding the metadata fetched
+// for path with the scheme.
+func deductionKey(scheme, path string) []byte {
+	if scheme == "" {
+		return []byte(path)
+	}
+	return []byte(scheme + "://" + path)
+}
+
+// get returns the metadata cached for path with the scheme, if any was fetched
+// within the ttl. If stale is set, expired metadata is returned too.
+//
+// Metadata fetched for a parent of path is only returned if path is within
+// the root it reports, as the root reported for path itself may be longer.
+func (c *deductionCache) get(scheme, path string, stale bool) (cachedMetadata, bool) {
+	if c == nil {
+		return cachedMetadata{}, false
+	}
+
+	var md cachedMetadata
+	err := c.db.View(func(tx *bolt.Tx) error {
+		db := tx.Bucket(deductionBucket)
+		if db == nil {
+			return nil
+		}
+
+		for p := path; p != ""; p = parentPath(p) {
+			b := db.Bucket(deductionKey(scheme, p))
+			if b == nil {
+				continue
+			}
+			root := string(b.Get(deductionRootKey))
+			if root == "" || (path != root && !strings.HasPrefix(path, root+"/")) {
+				continue
+			}
+			ts := b.Get(deductionTimeKey)
+			if len(ts) != 8 {
+				return nil
+			}
+			fetched := time.Unix(int64(binary.BigEndian.Uint64(ts)), 0)
+			if !stale && time.Since(fetched) > c.ttl {
+				return nil
+			}
+			md = cachedMetadata{
+				root:     root,
+				vcs:      string(b.Get(deductionVCSKey)),
+				repoRoot: string(b.Get(deductionRepoKey)),
+			}
+			return nil
+		}
+		return nil
+	})
+	if err != nil {
+		c.logger.Println(errors.Wrapf(err, "failed to get go-get metadata for %q from cache", path))
+		return cachedMetadata{}, false
+	}
+	return md, md.root != ""
+}
+
+// put stores md, fetched just now for path with the scheme.
+func (c *deductionCache) put(scheme, path string, md cachedMetadata) {
+	if c == nil {
+		return
+	}
+
+	ts := make([]byte, 8)
+	binary.BigEndian.PutUint64(ts, uint64(time.Now().Unix()))
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		db, err := tx.CreateBucketIfNotExists(deductionBucket)
+		if err != nil {
+			return err
+		}
+		name := deductionKey(scheme, path)
+		if db.Bucket(name) != nil {
+			if err := db.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		b, err := db.CreateBucket(name)
+		if err != nil {
+			return err
+		}
+		if err := b.Put(deductionRootKey, []byte(md.root)); err != nil {
+			return err
+		}
+		if err := b.Put(deductionVCSKey, []byte(md.vcs)); err != nil {
+			return err
+		}
+		if err := b.Put(deductionRepoKey, []byte(md.repoRoot)); err != nil {
+			return err
+		}
+		return b.Put(deductionTimeKey, ts)
+	})
+	if err != nil {
+		c.logger.Println(errors.Wrapf(err, "failed to cache go-get metadata for %q", path))
+	}
+}
+
+// invalidate discards all the metadata cached that reports root.
+func (c *deductionCache) invalidate(root string) {
+	if c == nil {
+		return
+	}
+
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		db := tx.Bucket(deductionBucket)
+		if db == nil {
+			return nil
+		}
+
+		var names [][]byte
+		err := db.ForEach(func(k, v []byte) error {
+			if b := db.Bucket(k); b != nil && string(b.Get(deductionRootKey)) == root {
+				names = append(names, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := db.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.logger.Println(errors.Wrapf(err, "failed to discard cached go-get metadata for %q", root))
+	}
+}
+
+// parentPath returns path without its last element, or "" if it has only
+// one.
+func parentPath(path string) string {
+	i := strings.LastIndexByte(path, '/')
+	if i < 0 {
+		return ""
+	}
+	return path[:i]
+}
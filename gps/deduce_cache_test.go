@@ -0,0 +1,181 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/dep/internal/test"
+)
+
+func TestDeductionCache(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("cache")
+
+	bc, err := newBoltCache(h.Path("cache"), 0, log.New(test.Writer{TB: t}, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.close()
+	c := bc.newDeductionCache(time.Hour)
+
+	md := cachedMetadata{root: "example.com/vanity", vcs: "git", repoRoot: "https://git.example.com/vanity"}
+	c.put("", "example.com/vanity/pkg", md)
+
+	for _, path := range []string{"example.com/vanity/pkg", "example.com/vanity/pkg/sub"} {
+		got, ok := c.get("", path, false)
+		if !ok || got != md {
+			t.Errorf("get(%q) = %v, %v, want %v", path, got, ok, md)
+		}
+	}
+	// Only the path the metadata was fetched for, and the paths within it, are
+	// known to be in its root.
+	for _, path := range []string{"example.com", "example.com/vanity", "example.com/vanity/other", "example.com/vanityfair", "example.com/other"} {
+		if got, ok := c.get("", path, false); ok {
+			t.Errorf("get(%q) = %v, want nothing", path, got)
+		}
+	}
+	// Metadata fetched with a scheme is only used for that scheme.
+	if got, ok := c.get("https", "example.com/vanity/pkg", false); ok {
+		t.Errorf("expected metadata fetched without a scheme to be left out for https, got %v", got)
+	}
+	hmd := cachedMetadata{root: "example.com/vanity", vcs: "git", repoRoot: "http://git.example.com/vanity"}
+	c.put("http", "example.com/vanity", hmd)
+	if got, ok := c.get("http", "example.com/vanity/other", false); !ok || got != hmd {
+		t.Errorf("get(http, example.com/vanity/other) = %v, %v, want %v", got, ok, hmd)
+	}
+	if got, ok := c.get("", "example.com/vanity/other", false); ok {
+		t.Errorf("expected metadata fetched with http to be left out without a scheme, got %v", got)
+	}
+
+	// Metadata fetched for a parent is not used for paths outside the root
+	// it reports.
+	long := cachedMetadata{root: "example.com/long/root", vcs: "git", repoRoot: "https://git.example.com/long"}
+	c.put("", "example.com/long", long)
+	if got, ok := c.get("", "example.com/long/other", false); ok {
+		t.Errorf("expected metadata of a longer root to be left out, got %v", got)
+	}
+	if got, ok := c.get("", "example.com/long/root/pkg", false); !ok || got != long {
+		t.Errorf("get(example.com/long/root/pkg) = %v, %v, want %v", got, ok, long)
+	}
+
+	// Expired metadata is only returned when stale metadata is asked for.
+	expired := bc.newDeductionCache(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if got, ok := expired.get("", "example.com/vanity/pkg", false); ok {
+		t.Errorf("expected expired metadata to be ignored, got %v", got)
+	}
+	if _, ok := expired.get("", "example.com/vanity/pkg", true); !ok {
+		t.Error("expected stale metadata to be returned")
+	}
+
+	// All the metadata reporting a root is discarded with it.
+	c.invalidate("example.com/vanity")
+	if got, ok := c.get("", "example.com/vanity/pkg", true); ok {
+		t.Errorf("expected invalidated metadata to be gone, got %v", got)
+	}
+	if got, ok := c.get("http", "example.com/vanity", true); ok {
+		t.Errorf("expected invalidated metadata to be gone, got %v", got)
+	}
+	if _, ok := c.get("", "example.com/long/root", true); !ok {
+		t.Error("expected the metadata of other roots to be kept")
+	}
+
+	// A nil deductionCache caches nothing.
+	var nc *deductionCache
+	nc.put("", md.root, md)
+	nc.invalidate(md.root)
+	if _, ok := nc.get("", md.root, true); ok {
+		t.Error("expected a nil cache to be empty")
+	}
+}
+
+func TestCachedDeduction(t *testing.T) {
+	requiresBins(t, "git")
+
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("repo")
+	repoPath := h.Path("repo")
+	h.RunGit(repoPath, "init")
+	h.RunGit(repoPath, "config", "--local", "user.email", "test@example.com")
+	h.RunGit(repoPath, "config", "--local", "user.name", "Test author")
+	h.TempFile("repo/vanity.go", "package vanity\n")
+	h.RunGit(repoPath, "add", "vanity.go")
+	h.RunGit(repoPath, "commit", "--message=Initial commit")
+	h.RunGit(repoPath, "tag", "v1.0.0")
+
+	h.TempDir("smcache")
+	cfg := SourceManagerConfig{
+		Cachedir:          h.Path("smcache"),
+		Logger:            log.New(test.Writer{TB: t}, "", 0),
+		DeductionCacheTTL: time.Hour,
+	}
+	sm, err := NewSourceManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With its go-get metadata cached, the project is fetched without
+	// example.com ever being contacted.
+	sm.deduceCoord.cache.put("", "example.com/vanity", cachedMetadata{
+		root:     "example.com/vanity",
+		vcs:      "git",
+		repoRoot: "file://" + filepath.ToSlash(repoPath),
+	})
+	sm.deduceCoord.cache.put("", "example.com/gone", cachedMetadata{
+		root:     "example.com/gone",
+		vcs:      "git",
+		repoRoot: "file://" + filepath.ToSlash(filepath.Join(h.Path("."), "gone")),
+	})
+	root, err := sm.DeduceProjectRoot("example.com/vanity/pkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root != "example.com/vanity" {
+		t.Fatalf("unexpected root %s", root)
+	}
+	vl, err := sm.ListVersions(mkPI("example.com/vanity"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vl) != 2 {
+		t.Fatalf("expected the tag and the branch of the repository, got %v", vl)
+	}
+
+	// A failure to fetch the repository it points to discards the metadata.
+	if _, err := sm.ListVersions(mkPI("example.com/gone")); err == nil {
+		t.Fatal("expected fetching a missing repository to fail")
+	}
+	if md, ok := sm.deduceCoord.cache.get("", "example.com/gone", true); ok {
+		t.Fatalf("expected the metadata of the missing repository to be discarded, got %v", md)
+	}
+	sm.Release()
+
+	// The metadata persists, and is used offline.
+	cfg.Offline = true
+	sm, err = NewSourceManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Release()
+	pd, err := sm.deduceCoord.deduceRootPath(context.Background(), "example.com/vanity/pkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pd.root != "example.com/vanity" {
+		t.Fatalf("unexpected root %s", pd.root)
+	}
+	if _, err := sm.deduceCoord.deduceRootPath(context.Background(), "example.com/gone"); err == nil {
+		t.Fatal("expected deducing the discarded path offline to fail")
+	} else if _, ok := err.(OfflineError); !ok {
+		t.Fatalf("expected an OfflineError, got %v", err)
+	}
+}
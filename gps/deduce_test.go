@@ -390,6 +390,119 @@ var pathDeductionFixtures = map[string][]pathDeductionFixture{
 			},
 		},
 	},
+	"gitlab": {
+		{
+			in:   "gitlab.com/group/project",
+			root: "gitlab.com/group/project",
+			mb: maybeSources{
+				maybeGitSource{url: mkurl("https://gitlab.com/group/project")},
+				maybeGitSource{url: mkurl("ssh://git@gitlab.com/group/project")},
+				maybeGitSource{url: mkurl("http://gitlab.com/group/project")},
+			},
+		},
+		{
+			in:   "gitlab.com/group/sub/project.git/pkg",
+			root: "gitlab.com/group/sub/project.git",
+			mb: maybeSources{
+				maybeGitSource{url: mkurl("https://gitlab.com/group/sub/project.git")},
+				maybeGitSource{url: mkurl("ssh://git@gitlab.com/group/sub/project.git")},
+				maybeGitSource{url: mkurl("http://gitlab.com/group/sub/project.git")},
+			},
+		},
+		{
+			in:   "gitlab.com/group/sub/sub2/project.git",
+			root: "gitlab.com/group/sub/sub2/project.git",
+			mb: maybeSources{
+				maybeGitSource{url: mkurl("https://gitlab.com/group/sub/sub2/project.git")},
+				maybeGitSource{url: mkurl("ssh://git@gitlab.com/group/sub/sub2/project.git")},
+				maybeGitSource{url: mkurl("http://gitlab.com/group/sub/sub2/project.git")},
+			},
+		},
+		{
+			in:   "git@gitlab.com:group/sub/project.git",
+			root: "gitlab.com/group/sub/project.git",
+			mb: maybeSources{
+				maybeGitSource{url: mkurl("ssh://git@gitlab.com/group/sub/project.git")},
+			},
+		},
+		{
+			in:   "https://gitlab.com/group/project",
+			root: "gitlab.com/group/project",
+			mb: maybeSources{
+				maybeGitSource{url: mkurl("https://gitlab.com/group/project")},
+			},
+		},
+		{
+			in:   "ssh://gitlab.com/group/sub/project.git/pkg",
+			root: "gitlab.com/group/sub/project.git",
+			mb: maybeSources{
+				maybeGitSource{url: mkurl("ssh://git@gitlab.com/group/sub/project.git")},
+			},
+		},
+		// Without a .git suffix, subgroups cannot be told from packages.
+		{
+			in:     "gitlab.com/group/sub/project",
+			rerr:   errNoKnownPathMatch,
+			srcerr: errNoKnownPathMatch,
+		},
+		{
+			in:     "gitlab.com/group.git/project",
+			rerr:   errors.New("gitlab.com/group.git/project is not a valid path for a source on gitlab.com"),
+			srcerr: errors.New("gitlab.com/group.git/project is not a valid path for a source on gitlab.com"),
+		},
+		{
+			in:     "gitlab.com/group",
+			rerr:   errors.New("gitlab.com/group is not a valid path for a source on gitlab.com"),
+			srcerr: errors.New("gitlab.com/group is not a valid path for a source on gitlab.com"),
+		},
+		{
+			in:     "svn://gitlab.com/group/project",
+			root:   "gitlab.com/group/project",
+			srcerr: errors.New("svn is not a valid scheme for accessing a git repository"),
+		},
+		{
+			in:     "ssh://hg@gitlab.com/group/project",
+			root:   "gitlab.com/group/project",
+			srcerr: errors.New("gitlab.com ssh must be accessed via the 'git' user; hg was provided"),
+		},
+	},
+	"gitea": {
+		{
+			in:   "git.example.com/owner/repo/pkg/sub",
+			root: "git.example.com/owner/repo",
+			mb: maybeSources{
+				maybeGitSource{url: mkurl("https://git.example.com/owner/repo")},
+				maybeGitSource{url: mkurl("ssh://git@git.example.com/owner/repo")},
+				maybeGitSource{url: mkurl("http://git.example.com/owner/repo")},
+			},
+		},
+		{
+			in:   "git.example.com/owner/repo.git/pkg",
+			root: "git.example.com/owner/repo.git",
+			mb: maybeSources{
+				maybeGitSource{url: mkurl("https://git.example.com/owner/repo.git")},
+				maybeGitSource{url: mkurl("ssh://git@git.example.com/owner/repo.git")},
+				maybeGitSource{url: mkurl("http://git.example.com/owner/repo.git")},
+			},
+		},
+		{
+			in:   "ssh://git@git.example.com/owner/repo",
+			root: "git.example.com/owner/repo",
+			mb: maybeSources{
+				maybeGitSource{url: mkurl("ssh://git@git.example.com/owner/repo")},
+			},
+		},
+		{
+			in:     "git.example.com/owner.git/repo",
+			rerr:   errors.New("git.example.com/owner.git/repo is not a valid path for a source on git.example.com"),
+			srcerr: errors.New("git.example.com/owner.git/repo is not a valid path for a source on git.example.com"),
+		},
+		{
+			in:     "git.example.com/own+er/repo",
+			rerr:   errors.New("git.example.com/own+er/repo is not a valid path for a source on git.example.com"),
+			srcerr: errors.New("git.example.com/own+er/repo is not a valid path for a source on git.example.com"),
+		},
+	},
 	"vcsext": {
 		// VCS extension-based syntax
 		{
@@ -524,6 +637,10 @@ func TestDeduceFromPath(t *testing.T) {
 				deducer = launchpadGitDeducer{regexp: glpRegex}
 			case "apache":
 				deducer = apacheDeducer{regexp: apacheRegex}
+			case "gitlab":
+				deducer = gitlabDeducer{host: "gitlab.com"}
+			case "gitea":
+				deducer = giteaDeducer{host: "git.example.com"}
 			case "vcsext":
 				deducer = vcsExtensionDeducer{regexp: vcsExtensionRegex}
 			default:
@@ -672,7 +789,7 @@ func TestVanityDeductionSchemeMismatch(t *testing.T) {
 
 	ctx := context.Background()
 	cm := newSupervisor(ctx)
-	dc := newDeductionCoordinator(cm)
+	dc := newDeductionCoordinator(cm, SourceManagerConfig{})
 	_, err := dc.deduceRootPath(ctx, "ssh://golang.org/exp")
 	// TODO(sdboyer) this is not actually the error that it should be
 	if err == nil {
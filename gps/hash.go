@@ -19,6 +19,7 @@ const (
 	hhImportsReqs = "-IMPORTS/REQS-"
 	hhIgnores     = "-IGNORES-"
 	hhOverrides   = "-OVERRIDES-"
+	hhPlatforms   = "-PLATFORMS-"
 	hhAnalyzer    = "-ANALYZER-"
 )
 
@@ -105,6 +106,27 @@ func (s *solver) writeHashingInputs(w io.Writer) {
 		}
 	}
 
+	// Platforms change which imports are followed in dependencies, and not
+	// just in the root. The section is omitted entirely when there are none, so
+	// that existing digests are unaffected.
+	if len(s.rd.plats) > 0 {
+		writeString(hhPlatforms)
+		plats := make([]string, 0, len(s.rd.plats))
+		for _, p := range s.rd.plats {
+			ps := p.String()
+			if len(p.Tags) > 0 {
+				tags := append([]string(nil), p.Tags...)
+				sort.Strings(tags)
+				ps += " " + strings.Join(tags, ",")
+			}
+			plats = append(plats, ps)
+		}
+		sort.Strings(plats)
+		for _, p := range plats {
+			writeString(p)
+		}
+	}
+
 	writeString(hhAnalyzer)
 	ai := s.rd.an.Info()
 	writeString(ai.Name)
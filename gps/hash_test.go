@@ -684,3 +684,79 @@ func TestHashInputsIneffectualWildcardIgs(t *testing.T) {
 		})
 	}
 }
+
+func TestHashInputsPlatforms(t *testing.T) {
+	fix := basicFixtures["shared dependency with overlapping constraints"]
+
+	rm := fix.rootmanifest().(simpleRootManifest).dup()
+	rm.plats = []pkgtree.Platform{
+		{GOOS: "windows"},
+		{GOOS: "linux", GOARCH: "amd64", Tags: []string{"purego", "appengine"}},
+	}
+
+	// b is only imported on darwin, so neither the import nor the constraint
+	// on it is an input.
+	ptree := fix.rootTree()
+	root := ptree.Packages[ptree.ImportRoot]
+	root.P.ImportConstraints = map[string][]pkgtree.BuildConstraint{
+		"b": {{"darwin"}},
+	}
+	ptree.Packages[ptree.ImportRoot] = root
+
+	params := SolveParameters{
+		RootDir:         string(fix.ds[0].n),
+		RootPackageTree: ptree,
+		Manifest:        rm,
+		ProjectAnalyzer: naiveAnalyzer{},
+		stdLibFn:        func(string) bool { return false },
+		mkBridgeFn:      overrideMkBridge,
+	}
+
+	s, err := Prepare(params, newdepspecSM(fix.ds, nil))
+	if err != nil {
+		t.Fatalf("Unexpected error while prepping solver: %s", err)
+	}
+
+	dig := s.HashInputs()
+	h := sha256.New()
+
+	elems := []string{
+		hhConstraints,
+		"a",
+		"sv-1.0.0",
+		hhImportsReqs,
+		"a",
+		hhIgnores,
+		hhOverrides,
+		hhPlatforms,
+		"linux/amd64 appengine,purego",
+		"windows",
+		hhAnalyzer,
+		"naive-analyzer",
+		"1",
+	}
+	for _, v := range elems {
+		h.Write([]byte(v))
+	}
+	correct := h.Sum(nil)
+
+	if !bytes.Equal(dig, correct) {
+		t.Errorf("Hashes are not equal. Inputs:\n%s", diffHashingInputs(s, elems))
+	}
+
+	// A root manifest that does not implement PlatformManifest is built for
+	// all platforms, as one without any platforms is.
+	params.Manifest = struct{ RootManifest }{rm}
+	s, err = Prepare(params, newdepspecSM(fix.ds, nil))
+	if err != nil {
+		t.Fatalf("Unexpected error while prepping solver: %s", err)
+	}
+	params.Manifest = fix.rootmanifest()
+	all, err := Prepare(params, newdepspecSM(fix.ds, nil))
+	if err != nil {
+		t.Fatalf("Unexpected error while prepping solver: %s", err)
+	}
+	if !bytes.Equal(s.HashInputs(), all.HashInputs()) {
+		t.Errorf("Hashes are not equal. Inputs:\n%s", diffHashingInputs(s, strings.Split(HashingInputsAsString(all), "\n")))
+	}
+}
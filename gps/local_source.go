@@ -0,0 +1,225 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang/dep/gps/pkgtree"
+	"github.com/golang/dep/internal/fs"
+	"github.com/pkg/errors"
+)
+
+// LocalVersion is the only version offered by a source that is a directory on
+// the local filesystem. It is paired with a revision derived from the digest
+// of the directory's contents.
+const LocalVersion = "local"
+
+// IsLocalSource reports whether the source of a ProjectIdentifier refers to a
+// directory on the local filesystem, either as an absolute path or as a
+// file:// URL.
+func IsLocalSource(source string) bool {
+	_, ok := LocalSourceDir(source)
+	return ok
+}
+
+// LocalSourceDir returns the directory that a local source refers to, and
+// whether the source is a local one at all.
+func LocalSourceDir(source string) (string, bool) {
+	if filepath.IsAbs(source) {
+		return filepath.Clean(source), true
+	}
+
+	u, err := url.Parse(source)
+	if err != nil || u.Scheme != "file" || u.Host != "" || u.Path == "" {
+		return "", false
+	}
+	dir := filepath.FromSlash(u.Path)
+	if !filepath.IsAbs(dir) {
+		// On Windows, file:///C:/foo has a path of /C:/foo.
+		dir = dir[1:]
+	}
+	return filepath.Clean(dir), filepath.IsAbs(dir)
+}
+
+type maybeLocalSource struct {
+	dir string
+}
+
+func (m maybeLocalSource) try(ctx context.Context, cachedir string) (source, error) {
+	fi, err := os.Stat(m.dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot use %s as a local source", m.dir)
+	}
+	if !fi.IsDir() {
+		return nil, errors.Errorf("cannot use %s as a local source: not a directory", m.dir)
+	}
+
+	rev, err := localRevision(m.dir)
+	if err != nil {
+		return nil, err
+	}
+	return &localSource{dir: m.dir, rev: rev}, nil
+}
+
+// cachePath returns an empty string, as a local source is used in place and
+// never kept in the cache.
+func (m maybeLocalSource) cachePath(cachedir string) string {
+	return ""
+}
+
+func (m maybeLocalSource) URL() *url.URL {
+	return &url.URL{Scheme: "file", Path: filepath.ToSlash(m.dir)}
+}
+
+func (m maybeLocalSource) String() string {
+	return fmt.Sprintf("%T: %s", m, m.dir)
+}
+
+// localRevision returns the synthetic revision of the contents of dir.
+func localRevision(dir string) (Revision, error) {
+	digest, err := pkgtree.DigestFromDirectory(dir)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to compute digest of %s", dir)
+	}
+	return Revision(hex.EncodeToString(digest)), nil
+}
+
+// localSource is a source that reads a project straight from a directory on
+// the local filesystem, typically a working copy that has not been pushed
+// anywhere yet. It has no history: it offers a single version, LocalVersion,
+// at a revision derived from the digest of the directory when the source was
+// set up.
+type localSource struct {
+	dir string
+	rev Revision
+}
+
+func (s *localSource) existsLocally(ctx context.Context) bool {
+	fi, err := os.Stat(s.dir)
+	return err == nil && fi.IsDir()
+}
+
+func (s *localSource) existsUpstream(ctx context.Context) bool {
+	return s.existsLocally(ctx)
+}
+
+func (s *localSource) upstreamURL() string {
+	return s.dir
+}
+
+func (*localSource) initLocal(ctx context.Context) error {
+	return nil
+}
+
+func (*localSource) updateLocal(ctx context.Context) error {
+	return nil
+}
+
+func (*localSource) maybeClean(ctx context.Context) error {
+	return nil
+}
+
+func (s *localSource) listVersions(ctx context.Context) ([]PairedVersion, error) {
+	return []PairedVersion{NewVersion(LocalVersion).Pair(s.rev)}, nil
+}
+
+func (s *localSource) listCachedVersions(ctx context.Context) ([]PairedVersion, error) {
+	return s.listVersions(ctx)
+}
+
+// checkRevision returns an error if r is not the revision of the directory's
+// contents, as it is no longer available.
+func (s *localSource) checkRevision(r Revision) error {
+	if r != s.rev {
+		return errors.Errorf("revision %s of %s is not available: the directory's contents have changed since", r, s.dir)
+	}
+	return nil
+}
+
+func (s *localSource) getManifestAndLock(ctx context.Context, pr ProjectRoot, r Revision, an ProjectAnalyzer) (Manifest, Lock, error) {
+	if err := s.checkRevision(r); err != nil {
+		return nil, nil, err
+	}
+
+	m, l, err := an.DeriveManifestAndLock(s.dir, pr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if l != nil && l != Lock(nil) {
+		l = prepLock(l)
+	}
+
+	return prepManifest(m), l, nil
+}
+
+func (s *localSource) listPackages(ctx context.Context, pr ProjectRoot, r Revision) (pkgtree.PackageTree, error) {
+	if err := s.checkRevision(r); err != nil {
+		return pkgtree.PackageTree{}, err
+	}
+	return pkgtree.ListPackages(s.dir, string(pr))
+}
+
+func (s *localSource) revisionPresentIn(r Revision) (bool, error) {
+	return r == s.rev, nil
+}
+
+func (s *localSource) disambiguateRevision(ctx context.Context, r Revision) (Revision, error) {
+	if err := s.checkRevision(r); err != nil {
+		return "", err
+	}
+	return r, nil
+}
+
+func (s *localSource) revisionTime(ctx context.Context, r Revision) (time.Time, error) {
+	return time.Time{}, errors.Errorf("%s is a local directory, so its revisions have no time", s.dir)
+}
+
+func (s *localSource) commitsBetween(ctx context.Context, from, to Revision) (int, error) {
+	return 0, errors.Errorf("%s is a local directory, so it has no commits", s.dir)
+}
+
+func (s *localSource) exportRevisionTo(ctx context.Context, r Revision, to string) error {
+	if err := s.checkRevision(r); err != nil {
+		return err
+	}
+
+	// Only make the parent dir, as CopyDir will balk on trying to write to an
+	// empty but existing dir.
+	if err := os.MkdirAll(filepath.Dir(to), 0777); err != nil {
+		return err
+	}
+	if err := fs.CopyDir(s.dir, to); err != nil {
+		return err
+	}
+
+	// The working copy may well be a repository; its metadata is of no use in
+	// the export.
+	for _, vcsDir := range []string{".git", ".hg", ".bzr", ".svn"} {
+		if err := os.RemoveAll(filepath.Join(to, vcsDir)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (*localSource) sourceType() string {
+	return "local"
+}
+
+func (*localSource) existsCallsListVersions() bool {
+	return false
+}
+
+func (*localSource) listVersionsRequiresLocal() bool {
+	return false
+}
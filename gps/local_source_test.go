@@ -0,0 +1,128 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestIsLocalSource(t *testing.T) {
+	abs, err := filepath.Abs("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]bool{
+		abs:                          true,
+		fileURL(abs):                 true,
+		"foo":                        false,
+		"./foo":                      false,
+		"github.com/foo/bar":         false,
+		"https://github.com/foo/bar": false,
+		"file://host/foo":            false,
+	}
+	for source, want := range cases {
+		if got := IsLocalSource(source); got != want {
+			t.Errorf("IsLocalSource(%q) = %v, want %v", source, got, want)
+		}
+	}
+}
+
+func fileURL(path string) string {
+	if runtime.GOOS == "windows" {
+		return "file:///" + filepath.ToSlash(path)
+	}
+	return "file://" + path
+}
+
+func TestLocalSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "localsource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	writeFile := func(name, content string) {
+		path := filepath.Join(src, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("foo.go", "package foo\n\nimport _ \"github.com/sdboyer/deptest\"\n")
+	writeFile("bar/bar.go", "package bar\n")
+	writeFile(".git/HEAD", "ref: refs/heads/master\n")
+
+	sm, clean := mkNaiveSM(t)
+	defer clean()
+
+	id := ProjectIdentifier{ProjectRoot: "github.com/foo/local", Source: src}
+	vl, err := sm.ListVersions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vl) != 1 || vl[0].String() != LocalVersion {
+		t.Fatalf("expected a single %q version, got %v", LocalVersion, vl)
+	}
+	rev, err := localRevision(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vl[0].Revision() != rev {
+		t.Fatalf("expected revision %s, got %s", rev, vl[0].Revision())
+	}
+
+	ptree, err := sm.ListPackages(id, vl[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ptree.Packages) != 2 {
+		t.Fatalf("expected two packages, got %v", ptree.Packages)
+	}
+	if imps := ptree.Packages["github.com/foo/local"].P.Imports; !reflect.DeepEqual(imps, []string{"github.com/sdboyer/deptest"}) {
+		t.Fatalf("unexpected imports %v", imps)
+	}
+
+	if _, _, err := sm.GetManifestAndLock(id, vl[0], naiveAnalyzer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	to := filepath.Join(dir, "export", "local")
+	if err := sm.ExportProject(context.Background(), id, vl[0], to); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(to, "bar", "bar.go")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(to, ".git")); !os.IsNotExist(err) {
+		t.Fatalf("expected .git to be left out of the export, got %v", err)
+	}
+
+	// Local sources are used in place, and never end up in the cache.
+	paths, err := sm.CachePathsFor(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 0 {
+		t.Fatalf("expected no cache paths, got %v", paths)
+	}
+
+	// Once the contents change, the old revision can no longer be used.
+	writeFile("baz.go", "package foo\n")
+	sm2, clean2 := mkNaiveSM(t)
+	defer clean2()
+	if _, err := sm2.ListPackages(id, vl[0]); err == nil {
+		t.Fatal("expected listing packages at a stale revision to fail")
+	}
+}
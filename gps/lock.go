@@ -69,11 +69,18 @@ func sortedLockedProjects(lps []LockedProject) []LockedProject {
 // project's name, one or both of version and underlying revision, the network
 // URI for accessing it, the path at which it should be placed within a vendor
 // directory, and the packages that are used in it.
+//
+// A LockedProject may also record the prune options that were applied to the
+// project's tree when it was last written into a vendor directory, along with
+// a digest of the resulting tree. This information is not considered by the
+// solver.
 type LockedProject struct {
-	pi   ProjectIdentifier
-	v    UnpairedVersion
-	r    Revision
-	pkgs []string
+	pi     ProjectIdentifier
+	v      UnpairedVersion
+	r      Revision
+	pkgs   []string
+	prune  PruneOptions
+	digest []byte
 }
 
 // SimpleLock is a helper for tools to easily describe lock data when they know
@@ -152,7 +159,31 @@ func (lp LockedProject) Version() Version {
 	return lp.v.Pair(lp.r)
 }
 
-// Eq checks if two LockedProject instances are equal.
+// WithVendorDigest returns a copy of the LockedProject that records the prune
+// options applied to the project's tree when it was written into a vendor
+// directory, and the digest of that tree as computed by
+// pkgtree.DigestFromDirectory after pruning.
+func (lp LockedProject) WithVendorDigest(opts PruneOptions, digest []byte) LockedProject {
+	lp.prune = opts
+	lp.digest = digest
+	return lp
+}
+
+// PruneOptions returns the prune options that were applied to the project's
+// tree when it was last written into a vendor directory.
+func (lp LockedProject) PruneOptions() PruneOptions {
+	return lp.prune
+}
+
+// VendorDigest returns the digest of the project's tree as it was last written
+// into a vendor directory, after pruning. It returns nil if no digest is
+// known.
+func (lp LockedProject) VendorDigest() []byte {
+	return lp.digest
+}
+
+// Eq checks if two LockedProject instances are equal. Prune options and vendor
+// digests are not considered.
 func (lp LockedProject) Eq(lp2 LockedProject) bool {
 	if lp.pi != lp2.pi {
 		return false
@@ -61,6 +61,19 @@ type RootManifest interface {
 	RequiredPackages() map[string]bool
 }
 
+// PlatformManifest is a RootManifest that limits the platforms the project is
+// built for. A RootManifest that does not implement it is built for all
+// platforms.
+type PlatformManifest interface {
+	RootManifest
+
+	// Platforms returns the platforms that the project is built for. If any
+	// are returned, imports made only by files that are not built on any of
+	// them, per their build constraints, are disregarded during solving, in
+	// the root project and in all of its dependencies.
+	Platforms() []pkgtree.Platform
+}
+
 // SimpleManifest is a helper for tools to enumerate manifest data. It's
 // generally intended for ephemeral manifests, such as those Analyzers create on
 // the fly for projects with no manifest metadata, or metadata through a foreign
@@ -82,8 +95,11 @@ type simpleRootManifest struct {
 	c, ovr ProjectConstraints
 	ig     *pkgtree.IgnoredRuleset
 	req    map[string]bool
+	plats  []pkgtree.Platform
 }
 
+var _ PlatformManifest = simpleRootManifest{}
+
 func (m simpleRootManifest) DependencyConstraints() ProjectConstraints {
 	return m.c
 }
@@ -96,6 +112,9 @@ func (m simpleRootManifest) IgnoredPackages() *pkgtree.IgnoredRuleset {
 func (m simpleRootManifest) RequiredPackages() map[string]bool {
 	return m.req
 }
+func (m simpleRootManifest) Platforms() []pkgtree.Platform {
+	return m.plats
+}
 func (m simpleRootManifest) dup() simpleRootManifest {
 	m2 := simpleRootManifest{
 		c:   make(ProjectConstraints, len(m.c)),
@@ -115,6 +134,7 @@ func (m simpleRootManifest) dup() simpleRootManifest {
 
 	// IgnoredRulesets are immutable, and safe to reuse.
 	m2.ig = m.ig
+	m2.plats = append([]pkgtree.Platform(nil), m.plats...)
 
 	return m2
 }
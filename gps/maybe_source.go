@@ -24,6 +24,9 @@ import (
 type maybeSource interface {
 	// try tries to set up a source.
 	try(ctx context.Context, cachedir string) (source, error)
+	// cachePath returns the location under cachedir at which the source is
+	// kept locally, or an empty string if it is not kept in the cache.
+	cachePath(cachedir string) string
 	URL() *url.URL
 	fmt.Stringer
 }
@@ -38,6 +41,44 @@ func (mbs maybeSources) possibleURLs() []*url.URL {
 	return urlslice
 }
 
+// mirrored returns the maybeSources with the URLs of their repositories
+// rewritten by the mirrors. Those that end up fetching from the same URL as an
+// earlier one are dropped.
+func (mbs maybeSources) mirrored(mt mirrorTable) maybeSources {
+	if len(mt) == 0 {
+		return mbs
+	}
+
+	out := make(maybeSources, 0, len(mbs))
+	seen := make(map[string]bool, len(mbs))
+	for _, mb := range mbs {
+		var u *url.URL
+		switch m := mb.(type) {
+		case maybeGitSource:
+			m.url = mt.rewrite(m.url)
+			mb, u = m, m.url
+		case maybeGopkginSource:
+			m.url = mt.rewrite(m.url)
+			mb, u = m, m.url
+		case maybeBzrSource:
+			m.url = mt.rewrite(m.url)
+			mb, u = m, m.url
+		case maybeHgSource:
+			m.url = mt.rewrite(m.url)
+			mb, u = m, m.url
+		default:
+			u = mb.URL()
+		}
+
+		if seen[u.String()] {
+			continue
+		}
+		seen[u.String()] = true
+		out = append(out, mb)
+	}
+	return out
+}
+
 // sourceCachePath returns a url-sanitized source cache dir path.
 func sourceCachePath(cacheDir, sourceURL string) string {
 	return filepath.Join(cacheDir, "sources", sanitizer.Replace(sourceURL))
@@ -49,7 +90,7 @@ type maybeGitSource struct {
 
 func (m maybeGitSource) try(ctx context.Context, cachedir string) (source, error) {
 	ustr := m.url.String()
-	path := sourceCachePath(cachedir, ustr)
+	path := m.cachePath(cachedir)
 
 	r, err := vcs.NewGitRepo(ustr, path)
 	if err != nil {
@@ -67,6 +108,10 @@ func (m maybeGitSource) try(ctx context.Context, cachedir string) (source, error
 	}, nil
 }
 
+func (m maybeGitSource) cachePath(cachedir string) string {
+	return sourceCachePath(cachedir, m.url.String())
+}
+
 func (m maybeGitSource) URL() *url.URL {
 	return m.url
 }
@@ -90,11 +135,7 @@ type maybeGopkginSource struct {
 }
 
 func (m maybeGopkginSource) try(ctx context.Context, cachedir string) (source, error) {
-	// We don't actually need a fully consistent transform into the on-disk path
-	// - just something that's unique to the particular gopkg.in domain context.
-	// So, it's OK to just dumb-join the scheme with the path.
-	aliasURL := m.url.Scheme + "://" + m.opath
-	path := sourceCachePath(cachedir, aliasURL)
+	path := m.cachePath(cachedir)
 	ustr := m.url.String()
 
 	r, err := vcs.NewGitRepo(ustr, path)
@@ -114,10 +155,22 @@ func (m maybeGopkginSource) try(ctx context.Context, cachedir string) (source, e
 		},
 		major:    m.major,
 		unstable: m.unstable,
-		aliasURL: aliasURL,
+		aliasURL: m.aliasURL(),
 	}, nil
 }
 
+// aliasURL returns the gopkg.in URL that the source is known by.
+func (m maybeGopkginSource) aliasURL() string {
+	// We don't actually need a fully consistent transform into the on-disk path
+	// - just something that's unique to the particular gopkg.in domain context.
+	// So, it's OK to just dumb-join the scheme with the path.
+	return m.url.Scheme + "://" + m.opath
+}
+
+func (m maybeGopkginSource) cachePath(cachedir string) string {
+	return sourceCachePath(cachedir, m.aliasURL())
+}
+
 func (m maybeGopkginSource) URL() *url.URL {
 	return &url.URL{
 		Scheme: m.url.Scheme,
@@ -135,7 +188,7 @@ type maybeBzrSource struct {
 
 func (m maybeBzrSource) try(ctx context.Context, cachedir string) (source, error) {
 	ustr := m.url.String()
-	path := sourceCachePath(cachedir, ustr)
+	path := m.cachePath(cachedir)
 
 	r, err := vcs.NewBzrRepo(ustr, path)
 	if err != nil {
@@ -153,6 +206,10 @@ func (m maybeBzrSource) try(ctx context.Context, cachedir string) (source, error
 	}, nil
 }
 
+func (m maybeBzrSource) cachePath(cachedir string) string {
+	return sourceCachePath(cachedir, m.url.String())
+}
+
 func (m maybeBzrSource) URL() *url.URL {
 	return m.url
 }
@@ -167,7 +224,7 @@ type maybeHgSource struct {
 
 func (m maybeHgSource) try(ctx context.Context, cachedir string) (source, error) {
 	ustr := m.url.String()
-	path := sourceCachePath(cachedir, ustr)
+	path := m.cachePath(cachedir)
 
 	r, err := vcs.NewHgRepo(ustr, path)
 	if err != nil {
@@ -185,6 +242,10 @@ func (m maybeHgSource) try(ctx context.Context, cachedir string) (source, error)
 	}, nil
 }
 
+func (m maybeHgSource) cachePath(cachedir string) string {
+	return sourceCachePath(cachedir, m.url.String())
+}
+
 func (m maybeHgSource) URL() *url.URL {
 	return m.url
 }
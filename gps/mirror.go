@@ -0,0 +1,64 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Mirror redirects the fetches from every repository whose URL starts with
+// Prefix to the same path under URL, much as git's url.<URL>.insteadOf
+// setting does. Only where sources are fetched from changes: the projects in
+// them, and what Gopkg.lock records about them, stay the same, so that locks
+// remain usable without the mirror.
+type Mirror struct {
+	Prefix string
+	URL    string
+}
+
+// ValidateMirror checks that a Mirror rewrites URLs into URLs.
+func ValidateMirror(m Mirror) error {
+	if m.Prefix == "" {
+		return errors.Errorf("mirror %s has no prefix to rewrite", m.URL)
+	}
+	if u, err := url.Parse(m.URL); err != nil || u.Scheme == "" {
+		return errors.Errorf("invalid URL %q for mirror of %s", m.URL, m.Prefix)
+	}
+	return nil
+}
+
+// mirrorTable holds Mirrors, the ones with the longest prefixes first.
+type mirrorTable []Mirror
+
+func newMirrorTable(ms []Mirror) mirrorTable {
+	mt := make(mirrorTable, len(ms))
+	copy(mt, ms)
+	// Mirrors with the same prefix keep their order, so that the first one
+	// given wins.
+	sort.SliceStable(mt, func(i, j int) bool {
+		return len(mt[i].Prefix) > len(mt[j].Prefix)
+	})
+	return mt
+}
+
+// rewrite returns the URL to fetch from instead of u, which is u itself if no
+// mirror applies.
+func (mt mirrorTable) rewrite(u *url.URL) *url.URL {
+	us := u.String()
+	for _, m := range mt {
+		if !strings.HasPrefix(us, m.Prefix) {
+			continue
+		}
+		if mu, err := url.Parse(m.URL + strings.TrimPrefix(us, m.Prefix)); err == nil {
+			return mu
+		}
+		return u
+	}
+	return u
+}
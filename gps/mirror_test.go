@@ -0,0 +1,134 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/dep/internal/test"
+)
+
+func TestMirrorTableRewrite(t *testing.T) {
+	mt := newMirrorTable([]Mirror{
+		{Prefix: "https://github.com/", URL: "https://git.example.com/github/"},
+		{Prefix: "https://github.com/sdboyer/", URL: "https://git.example.com/sdboyer/"},
+		{Prefix: "https://github.com/", URL: "https://other.example.com/"},
+	})
+
+	cases := map[string]string{
+		"https://github.com/foo/bar":           "https://git.example.com/github/foo/bar",
+		"https://github.com/sdboyer/deptest":   "https://git.example.com/sdboyer/deptest",
+		"ssh://git@github.com/foo/bar":         "ssh://git@github.com/foo/bar",
+		"https://bitbucket.org/sdboyer/withbm": "https://bitbucket.org/sdboyer/withbm",
+	}
+	for in, want := range cases {
+		u, err := url.Parse(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := mt.rewrite(u).String(); got != want {
+			t.Errorf("rewrite(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestMirroredDeduction(t *testing.T) {
+	dc := newDeductionCoordinator(newSupervisor(context.Background()), SourceManagerConfig{
+		Mirrors: []Mirror{{Prefix: "https://github.com/", URL: "https://git.example.com/github/"}},
+	})
+
+	pd, err := dc.deduceRootPath(context.Background(), "github.com/foo/bar/baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pd.root != "github.com/foo/bar" {
+		t.Fatalf("expected the root to be unchanged, got %s", pd.root)
+	}
+	want := []string{
+		"https://git.example.com/github/foo/bar",
+		"ssh://git@github.com/foo/bar",
+		"git://github.com/foo/bar",
+		"http://github.com/foo/bar",
+	}
+	urls := pd.mb.possibleURLs()
+	if len(urls) != len(want) {
+		t.Fatalf("unexpected URLs %v, want %v", urls, want)
+	}
+	for i, u := range urls {
+		if u.String() != want[i] {
+			t.Errorf("unexpected URL %s, want %s", u, want[i])
+		}
+	}
+
+	// gopkg.in keeps its identity, but its repository comes from the mirror.
+	pd, err = dc.deduceRootPath(context.Background(), "gopkg.in/yaml.v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gm := pd.mb[0].(maybeGopkginSource)
+	if gm.url.String() != "https://git.example.com/github/go-yaml/yaml" || gm.URL().String() != "https://gopkg.in/yaml.v2" {
+		t.Fatalf("unexpected gopkg.in source %s", gm)
+	}
+}
+
+func TestMirroredSource(t *testing.T) {
+	requiresBins(t, "git")
+
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("mirror/foo/bar")
+	repoPath := h.Path("mirror/foo/bar")
+	h.RunGit(repoPath, "init")
+	h.RunGit(repoPath, "config", "--local", "user.email", "test@example.com")
+	h.RunGit(repoPath, "config", "--local", "user.name", "Test author")
+	h.TempFile("mirror/foo/bar/bar.go", "package bar\n")
+	h.RunGit(repoPath, "add", "bar.go")
+	h.RunGit(repoPath, "commit", "--message=Initial commit")
+	h.RunGit(repoPath, "tag", "v1.0.0")
+
+	h.TempDir("smcache")
+	sm, err := NewSourceManager(SourceManagerConfig{
+		Cachedir: h.Path("smcache"),
+		Logger:   log.New(test.Writer{TB: t}, "", 0),
+		Mirrors: []Mirror{{
+			Prefix: "https://github.com/",
+			URL:    "file://" + filepath.ToSlash(h.Path("mirror")) + "/",
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Release()
+
+	// The project is fetched from the mirror, without github.com ever being
+	// contacted.
+	id := mkPI("github.com/foo/bar")
+	vl, err := sm.ListVersions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vl) != 2 {
+		t.Fatalf("expected the tag and the branch of the mirror, got %v", vl)
+	}
+	ptree, err := sm.ListPackages(id, NewVersion("v1.0.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, has := ptree.Packages["github.com/foo/bar"]; !has {
+		t.Fatalf("unexpected packages: %v", ptree.Packages)
+	}
+
+	if _, err := NewSourceManager(SourceManagerConfig{
+		Cachedir:       h.Path("smcache"),
+		DisableLocking: true,
+		Mirrors:        []Mirror{{Prefix: "https://github.com/", URL: "mirror"}},
+	}); err == nil {
+		t.Fatal("expected a mirror that is not a URL to be rejected")
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// PathDeducer statically deduces the project roots of the import paths under
+// some prefix, and the repositories holding them, sparing the HTTP requests
+// for go-get metadata that such paths would otherwise need. It is registered
+// for its prefix with SourceMgr.RegisterPathDeducer.
+type PathDeducer interface {
+	// DeduceRoot returns the project root of an import path; for instance,
+	// "git.example.com/team/repo" for "git.example.com/team/repo/pkg".
+	DeduceRoot(path string) (string, error)
+	// DeduceRepositories returns the type of VCS of the repository holding
+	// the project at root - "git", "hg" or "bzr" - and the URLs it may be
+	// fetched from, in order of preference.
+	DeduceRepositories(root string) (vcs string, urls []*url.URL, err error)
+}
+
+// RegisterPathDeducer makes the SourceMgr deduce the import paths starting
+// with prefix, which should be a host or a path ending at a path element
+// boundary, with the PathDeducer. It takes precedence over any built-in or
+// previously registered deduction for a shorter prefix, and replaces one for
+// the same prefix.
+//
+// Import paths are deduced only once, so PathDeducers should be registered
+// before the SourceMgr is put to use.
+func (sm *SourceMgr) RegisterPathDeducer(prefix string, d PathDeducer) error {
+	if atomic.LoadInt32(&sm.releasing) == 1 {
+		return ErrSourceManagerIsReleased
+	}
+
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return errors.New("cannot register a path deducer for an empty prefix")
+	}
+	sm.deduceCoord.deducext.Insert(prefix+"/", exportedPathDeducer{d})
+	return nil
+}
+
+// exportedPathDeducer adapts a PathDeducer to the pathDeducer interface used
+// by the deductionCoordinator.
+type exportedPathDeducer struct {
+	PathDeducer
+}
+
+func (d exportedPathDeducer) deduceRoot(path string) (string, error) {
+	return d.DeduceRoot(path)
+}
+
+func (d exportedPathDeducer) deduceSource(path string, u *url.URL) (maybeSources, error) {
+	root, err := d.DeduceRoot(path)
+	if err != nil {
+		return nil, err
+	}
+	vcs, urls, err := d.DeduceRepositories(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var mb maybeSources
+	for _, ru := range urls {
+		// If the input specified a scheme, only the URLs with that scheme
+		// are candidates.
+		if u.Scheme != "" && ru.Scheme != u.Scheme {
+			continue
+		}
+		switch vcs {
+		case "git":
+			mb = append(mb, maybeGitSource{url: ru})
+		case "hg":
+			mb = append(mb, maybeHgSource{url: ru})
+		case "bzr":
+			mb = append(mb, maybeBzrSource{url: ru})
+		default:
+			return nil, errors.Errorf("unsupported vcs type %s for %s", vcs, root)
+		}
+	}
+
+	if len(mb) == 0 {
+		if u.Scheme != "" {
+			return nil, errors.Errorf("no repository of %s is reachable over %s", root, u.Scheme)
+		}
+		return nil, errors.Errorf("no repository found for %s", root)
+	}
+	return mb, nil
+}
+
+// DeductionRule is a PathDeducer for hosts on which every project root has
+// the same number of path elements, such as git.example.com/team/sub/repo.
+type DeductionRule struct {
+	// Prefix is the prefix of the import paths the rule applies to, such as
+	// "git.example.com".
+	Prefix string
+	// Depth is the number of path elements in project roots, counting those
+	// of the prefix: 4 for git.example.com/team/sub/repo.
+	Depth int
+	// VCS is the type of the repositories: "git", "hg" or "bzr".
+	VCS string
+	// URL is the template of the URL of the repository holding a project, in
+	// which {root} stands for the project root, and {path} for the part of it
+	// after the prefix: "ssh://git@git.example.com/{path}.git".
+	URL string
+}
+
+// ValidateDeductionRule checks that a DeductionRule is complete, and that
+// its URL template makes URLs.
+func ValidateDeductionRule(r DeductionRule) error {
+	prefix := strings.TrimSuffix(r.Prefix, "/")
+	if prefix == "" {
+		return errors.New("deduction rule has no prefix")
+	}
+	if r.Depth <= strings.Count(prefix, "/")+1 {
+		return errors.Errorf("deduction rule for %s has depth %d, which leaves no room for projects under it", prefix, r.Depth)
+	}
+	switch r.VCS {
+	case "git", "hg", "bzr":
+	default:
+		return errors.Errorf("deduction rule for %s has unsupported vcs type %q", prefix, r.VCS)
+	}
+	if _, urls, err := r.DeduceRepositories(prefix + "/project"); err != nil || urls[0].Scheme == "" {
+		return errors.Errorf("deduction rule for %s has invalid URL template %q", prefix, r.URL)
+	}
+	return nil
+}
+
+// DeduceRoot returns the first Depth path elements of path.
+func (r DeductionRule) DeduceRoot(path string) (string, error) {
+	prefix := strings.TrimSuffix(r.Prefix, "/")
+	if !strings.HasPrefix(path, prefix) || !isPathPrefixOrEqual(prefix, path) {
+		return "", errors.Errorf("%s is not under %s", path, prefix)
+	}
+
+	elems := strings.Split(path, "/")
+	if len(elems) < r.Depth {
+		return "", errors.Errorf("%s is not a valid path for a source under %s, whose project roots have %d path elements", path, prefix, r.Depth)
+	}
+	return strings.Join(elems[:r.Depth], "/"), nil
+}
+
+// DeduceRepositories fills the URL template in with root.
+func (r DeductionRule) DeduceRepositories(root string) (string, []*url.URL, error) {
+	prefix := strings.TrimSuffix(r.Prefix, "/")
+	rs := strings.NewReplacer(
+		"{root}", root,
+		"{path}", strings.TrimPrefix(strings.TrimPrefix(root, prefix), "/"),
+	)
+
+	u, err := url.Parse(rs.Replace(r.URL))
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "invalid repository URL for %s", root)
+	}
+	return r.VCS, []*url.URL{u}, nil
+}
+
+// Forge is a self-hosted GitLab or Gitea, whose import paths are deduced as
+// those on gitlab.com are. Such hosts are registered with
+// SourceMgr.RegisterForge.
+type Forge struct {
+	// Host is the host name of the forge.
+	Host string
+	// Type is the kind of forge: "gitlab" or "gitea".
+	Type string
+}
+
+// ValidateForge checks that a Forge has a host, and is of a known type.
+func ValidateForge(f Forge) error {
+	if f.Host == "" || strings.Contains(f.Host, "/") {
+		return errors.Errorf("invalid host %q for %s forge", f.Host, f.Type)
+	}
+	switch f.Type {
+	case "gitlab", "gitea":
+		return nil
+	}
+	return errors.Errorf("forge %s has unsupported type %q; expected gitlab or gitea", f.Host, f.Type)
+}
+
+// RegisterForge makes the SourceMgr deduce the import paths on a
+// self-hosted GitLab or Gitea. As with RegisterPathDeducer, forges should be
+// registered before the SourceMgr is put to use.
+func (sm *SourceMgr) RegisterForge(f Forge) error {
+	if atomic.LoadInt32(&sm.releasing) == 1 {
+		return ErrSourceManagerIsReleased
+	}
+	if err := ValidateForge(f); err != nil {
+		return err
+	}
+
+	var d pathDeducer = gitlabDeducer{host: f.Host}
+	if f.Type == "gitea" {
+		d = giteaDeducer{host: f.Host}
+	}
+	sm.deduceCoord.deducext.Insert(f.Host+"/", d)
+	return nil
+}
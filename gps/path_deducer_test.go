@@ -0,0 +1,201 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestDeductionRule(t *testing.T) {
+	r := DeductionRule{
+		Prefix: "git.example.com",
+		Depth:  4,
+		VCS:    "git",
+		URL:    "ssh://git@git.example.com:7999/{path}.git",
+	}
+	if err := ValidateDeductionRule(r); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"git.example.com/team/sub/repo":         "git.example.com/team/sub/repo",
+		"git.example.com/team/sub/repo/pkg/foo": "git.example.com/team/sub/repo",
+	}
+	for path, want := range cases {
+		root, err := r.DeduceRoot(path)
+		if err != nil {
+			t.Errorf("DeduceRoot(%q) failed: %s", path, err)
+			continue
+		}
+		if root != want {
+			t.Errorf("DeduceRoot(%q) = %q, want %q", path, root, want)
+		}
+	}
+	for _, path := range []string{"git.example.com/team/sub", "git.example.company/team/sub/repo"} {
+		if root, err := r.DeduceRoot(path); err == nil {
+			t.Errorf("expected DeduceRoot(%q) to fail, got %q", path, root)
+		}
+	}
+
+	vcs, urls, err := r.DeduceRepositories("git.example.com/team/sub/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vcs != "git" || len(urls) != 1 || urls[0].String() != "ssh://git@git.example.com:7999/team/sub/repo.git" {
+		t.Fatalf("unexpected repositories %s %v", vcs, urls)
+	}
+
+	r.URL = "https://{root}.git"
+	if _, urls, _ = r.DeduceRepositories("git.example.com/team/sub/repo"); urls[0].String() != "https://git.example.com/team/sub/repo.git" {
+		t.Fatalf("unexpected repository %v", urls)
+	}
+}
+
+func TestValidateDeductionRule(t *testing.T) {
+	cases := map[string]DeductionRule{
+		"no prefix":     {Depth: 3, VCS: "git", URL: "https://{root}"},
+		"shallow depth": {Prefix: "git.example.com/team", Depth: 2, VCS: "git", URL: "https://{root}"},
+		"bad vcs":       {Prefix: "git.example.com", Depth: 3, VCS: "svn", URL: "https://{root}"},
+		"no scheme":     {Prefix: "git.example.com", Depth: 3, VCS: "git", URL: "{root}"},
+	}
+	for name, r := range cases {
+		if err := ValidateDeductionRule(r); err == nil {
+			t.Errorf("%s: expected %+v to be invalid", name, r)
+		}
+	}
+}
+
+// hgDeducer puts every project of example.com/hg at its third path element.
+type hgDeducer struct{}
+
+func (hgDeducer) DeduceRoot(path string) (string, error) {
+	return DeductionRule{Prefix: "example.com/hg", Depth: 3}.DeduceRoot(path)
+}
+
+func (hgDeducer) DeduceRepositories(root string) (string, []*url.URL, error) {
+	return "hg", []*url.URL{
+		{Scheme: "https", Host: "hg.example.com", Path: root},
+		{Scheme: "ssh", Host: "hg.example.com", Path: root},
+	}, nil
+}
+
+func TestRegisterPathDeducer(t *testing.T) {
+	sm, clean := mkNaiveSM(t)
+	defer clean()
+
+	rule := DeductionRule{Prefix: "git.example.com", Depth: 4, VCS: "git", URL: "https://{root}.git"}
+	if err := sm.RegisterPathDeducer(rule.Prefix, rule); err != nil {
+		t.Fatal(err)
+	}
+	if err := sm.RegisterPathDeducer("example.com/hg/", hgDeducer{}); err != nil {
+		t.Fatal(err)
+	}
+	// A more specific prefix takes precedence over the built-in deduction.
+	if err := sm.RegisterPathDeducer("github.com/corp", DeductionRule{Prefix: "github.com/corp", Depth: 4, VCS: "git", URL: "https://github.com/{path}"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		root string
+		urls []string
+	}{
+		"git.example.com/team/sub/repo/pkg": {
+			root: "git.example.com/team/sub/repo",
+			urls: []string{"https://git.example.com/team/sub/repo.git"},
+		},
+		"example.com/hg/foo/bar": {
+			root: "example.com/hg/foo",
+			urls: []string{"https://hg.example.com/example.com/hg/foo", "ssh://hg.example.com/example.com/hg/foo"},
+		},
+		"github.com/corp/team/repo/pkg": {
+			root: "github.com/corp/team/repo",
+			urls: []string{"https://github.com/team/repo"},
+		},
+	}
+	for path, want := range cases {
+		root, err := sm.DeduceProjectRoot(path)
+		if err != nil {
+			t.Errorf("DeduceProjectRoot(%q) failed: %s", path, err)
+			continue
+		}
+		if string(root) != want.root {
+			t.Errorf("DeduceProjectRoot(%q) = %q, want %q", path, root, want.root)
+		}
+
+		urls, err := sm.SourceURLsForPath(path)
+		if err != nil {
+			t.Errorf("SourceURLsForPath(%q) failed: %s", path, err)
+			continue
+		}
+		if len(urls) != len(want.urls) {
+			t.Errorf("SourceURLsForPath(%q) = %v, want %v", path, urls, want.urls)
+			continue
+		}
+		for i, u := range urls {
+			if u.String() != want.urls[i] {
+				t.Errorf("SourceURLsForPath(%q) = %v, want %v", path, urls, want.urls)
+				break
+			}
+		}
+	}
+
+	// The scheme of an explicit URL must be offered by the deducer.
+	if urls, err := sm.SourceURLsForPath("https://git.example.com/team/sub/other"); err != nil || len(urls) != 1 {
+		t.Errorf("expected the https URL of the deducer, got %v, %v", urls, err)
+	}
+	if _, err := sm.SourceURLsForPath("ssh://git.example.com/team/sub/other"); err == nil {
+		t.Error("expected a scheme the deducer does not offer to fail")
+	}
+	if err := sm.RegisterPathDeducer("", rule); err == nil {
+		t.Error("expected registering for an empty prefix to fail")
+	}
+}
+
+func TestRegisterForge(t *testing.T) {
+	sm, clean := mkNaiveSM(t)
+	defer clean()
+
+	if err := sm.RegisterForge(Forge{Host: "gitlab.example.com", Type: "gitlab"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sm.RegisterForge(Forge{Host: "gitea.example.com", Type: "gitea"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"gitlab.example.com/group/sub/project.git/pkg": "gitlab.example.com/group/sub/project.git",
+		"gitlab.example.com/group/project":             "gitlab.example.com/group/project",
+		"gitea.example.com/owner/repo/pkg":             "gitea.example.com/owner/repo",
+	}
+	for path, want := range cases {
+		root, err := sm.DeduceProjectRoot(path)
+		if err != nil {
+			t.Errorf("DeduceProjectRoot(%q) failed: %s", path, err)
+			continue
+		}
+		if string(root) != want {
+			t.Errorf("DeduceProjectRoot(%q) = %q, want %q", path, root, want)
+		}
+	}
+
+	urls, err := sm.SourceURLsForPath("gitea.example.com/owner/repo/pkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 3 || urls[1].String() != "ssh://git@gitea.example.com/owner/repo" {
+		t.Fatalf("unexpected URLs %v", urls)
+	}
+
+	for _, f := range []Forge{
+		{Host: "", Type: "gitlab"},
+		{Host: "git.example.com/sub", Type: "gitlab"},
+		{Host: "git.example.com", Type: "github"},
+	} {
+		if err := sm.RegisterForge(f); err == nil {
+			t.Errorf("expected registering %v to fail", f)
+		}
+	}
+}
@@ -28,6 +28,13 @@ type Package struct {
 	CommentPath string   // Import path given in the comment on the package statement
 	Imports     []string // Imports from all go and cgo files
 	TestImports []string // Imports from all go test files (in go/build parlance: both TestImports and XTestImports)
+
+	// ImportConstraints holds, for each import in Imports that is only made by
+	// files with build constraints, the constraints of those files. Imports
+	// made by a file that is built everywhere are absent.
+	// TestImportConstraints does the same for TestImports.
+	ImportConstraints     map[string][]BuildConstraint
+	TestImportConstraints map[string][]BuildConstraint
 }
 
 // vcsRoots is a set of directories we should not descend into in ListPackages when
@@ -61,7 +68,12 @@ var vcsRoots = map[string]struct{}{
 // A PackageTree is returned, which contains the ImportRoot and map of import path
 // to PackageOrErr - each path under the root that exists will have either a
 // Package, or an error describing why the directory is not a valid package.
-func ListPackages(fileRoot, importRoot string) (PackageTree, error) {
+//
+// Imports are gathered from files for all os/arch combinations, and recorded
+// with the build constraints of the files making them. If any platforms are
+// given, the returned PackageTree is limited to the imports made on them, as
+// with PackageTree.ForPlatforms.
+func ListPackages(fileRoot, importRoot string, platforms ...Platform) (PackageTree, error) {
 	ptree := PackageTree{
 		ImportRoot: importRoot,
 		Packages:   make(map[string]PackageOrErr),
@@ -136,7 +148,7 @@ func ListPackages(fileRoot, importRoot string) (PackageTree, error) {
 			Dir:        wp,
 			ImportPath: ip,
 		}
-		err = fillPackage(p)
+		ic, tic, err := fillPackage(p)
 
 		if err != nil {
 			switch err.(type) {
@@ -157,7 +169,9 @@ func ListPackages(fileRoot, importRoot string) (PackageTree, error) {
 			CommentPath: p.ImportComment,
 			Name:        p.Name,
 			Imports:     p.Imports,
-			TestImports: dedupeStrings(p.TestImports, p.XTestImports),
+			TestImports:           dedupeStrings(p.TestImports, p.XTestImports),
+			ImportConstraints:     ic,
+			TestImportConstraints: tic,
 		}
 
 		if pkg.CommentPath != "" && !strings.HasPrefix(pkg.CommentPath, importRoot) {
@@ -205,11 +219,13 @@ func ListPackages(fileRoot, importRoot string) (PackageTree, error) {
 		return PackageTree{}, err
 	}
 
-	return ptree, nil
+	return ptree.ForPlatforms(platforms), nil
 }
 
-// fillPackage full of info. Assumes p.Dir is set at a minimum
-func fillPackage(p *build.Package) error {
+// fillPackage full of info. Assumes p.Dir is set at a minimum. The build
+// constraints of the imports and test imports that are not made everywhere are
+// returned.
+func fillPackage(p *build.Package) (ic, tic map[string][]BuildConstraint, err error) {
 	var buildPrefix = "// +build "
 	var buildFieldSplit = func(r rune) bool {
 		return unicode.IsSpace(r) || r == ','
@@ -217,13 +233,14 @@ func fillPackage(p *build.Package) error {
 
 	gofiles, err := filepath.Glob(filepath.Join(p.Dir, "*.go"))
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	if len(gofiles) == 0 {
-		return &build.NoGoError{Dir: p.Dir}
+		return nil, nil, &build.NoGoError{Dir: p.Dir}
 	}
 
+	var constraints, testConstraints importConstraints
 	var testImports []string
 	var imports []string
 	var importComments []string
@@ -244,12 +261,13 @@ func fillPackage(p *build.Package) error {
 			if os.IsPermission(err) {
 				continue
 			}
-			return err
+			return nil, nil, err
 		}
 		testFile := strings.HasSuffix(file, "_test.go")
 		fname := filepath.Base(file)
 
 		var ignored bool
+		var buildLines []string
 		for _, c := range pf.Comments {
 			ic := findImportComment(pf.Name, c)
 			if ic != "" {
@@ -259,25 +277,23 @@ func fillPackage(p *build.Package) error {
 				continue
 			}
 
-			var ct string
 			for _, cl := range c.List {
-				if strings.HasPrefix(cl.Text, buildPrefix) {
-					ct = cl.Text
-					break
+				if !strings.HasPrefix(cl.Text, buildPrefix) {
+					continue
 				}
-			}
-			if ct == "" {
-				continue
-			}
-
-			for _, t := range strings.FieldsFunc(ct[len(buildPrefix):], buildFieldSplit) {
-				// hardcoded (for now) handling for the "ignore" build tag
-				// We "soft" ignore the files tagged with ignore so that we pull in their imports.
-				if t == "ignore" {
-					ignored = true
+				ct := cl.Text[len(buildPrefix):]
+				buildLines = append(buildLines, ct)
+
+				for _, t := range strings.FieldsFunc(ct, buildFieldSplit) {
+					// hardcoded (for now) handling for the "ignore" build tag
+					// We "soft" ignore the files tagged with ignore so that we pull in their imports.
+					if t == "ignore" {
+						ignored = true
+					}
 				}
 			}
 		}
+		bc := buildConstraintOf(fname, buildLines)
 
 		if testFile {
 			p.TestGoFiles = append(p.TestGoFiles, fname)
@@ -294,18 +310,20 @@ func fillPackage(p *build.Package) error {
 		for _, is := range pf.Imports {
 			name, err := strconv.Unquote(is.Path.Value)
 			if err != nil {
-				return err // can't happen?
+				return nil, nil, err // can't happen?
 			}
 			if testFile {
 				testImports = append(testImports, name)
+				testConstraints.add(name, bc)
 			} else {
 				imports = append(imports, name)
+				constraints.add(name, bc)
 			}
 		}
 	}
 	importComments = uniq(importComments)
 	if len(importComments) > 1 {
-		return &ConflictingImportComments{
+		return nil, nil, &ConflictingImportComments{
 			ImportPath:                p.ImportPath,
 			ConflictingImportComments: importComments,
 		}
@@ -317,7 +335,7 @@ func fillPackage(p *build.Package) error {
 	testImports = uniq(testImports)
 	p.Imports = imports
 	p.TestImports = testImports
-	return nil
+	return constraints.result(), testConstraints.result(), nil
 }
 
 var (
@@ -639,6 +657,8 @@ func CopyPackages(p map[string]PackageOrErr, fn func(string, PackageOrErr) (stri
 				poe2.P.TestImports, pool = pool[:til], pool[til:]
 				copy(poe2.P.TestImports, poe.P.TestImports)
 			}
+			poe2.P.ImportConstraints = copyImportConstraints(poe.P.ImportConstraints)
+			poe2.P.TestImportConstraints = copyImportConstraints(poe.P.TestImportConstraints)
 		}
 		if fn != nil {
 			path, poe2 = fn(path, poe2)
@@ -649,6 +669,19 @@ func CopyPackages(p map[string]PackageOrErr, fn func(string, PackageOrErr) (stri
 	return p2
 }
 
+// copyImportConstraints returns a copy of the map. The constraints themselves
+// are never modified, so they are shared.
+func copyImportConstraints(ic map[string][]BuildConstraint) map[string][]BuildConstraint {
+	if len(ic) == 0 {
+		return nil
+	}
+	ic2 := make(map[string][]BuildConstraint, len(ic))
+	for imp, bcs := range ic {
+		ic2[imp] = bcs
+	}
+	return ic2
+}
+
 // TrimHiddenPackages returns a new PackageTree where packages that are ignored,
 // or both hidden and unreachable, have been removed.
 //
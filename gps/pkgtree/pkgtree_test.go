@@ -1337,6 +1337,42 @@ func TestListPackages(t *testing.T) {
 				},
 			},
 		},
+		"imports with build constraints": {
+			fileRoot:   j("platforms"),
+			importRoot: "platforms",
+			out: PackageTree{
+				ImportRoot: "platforms",
+				Packages: map[string]PackageOrErr{
+					"platforms": {
+						P: Package{
+							ImportPath: "platforms",
+							Name:       "platforms",
+							Imports: []string{
+								"golang.org/x/sys/unix",
+								"golang.org/x/sys/windows",
+								"google.golang.org/appengine",
+								"os/user",
+								"sort",
+							},
+							TestImports: []string{
+								"golang.org/x/sys/unix",
+								"testing",
+							},
+							ImportConstraints: map[string][]BuildConstraint{
+								"golang.org/x/sys/unix":       {{"darwin linux,!android", "!appengine"}},
+								"golang.org/x/sys/windows":    {{"windows"}},
+								"google.golang.org/appengine": {{"appengine"}},
+								"os/user":                     {{"!cgo"}},
+							},
+							TestImportConstraints: map[string][]BuildConstraint{
+								"golang.org/x/sys/unix": {{"linux"}},
+								"testing":               {{"linux"}},
+							},
+						},
+					},
+				},
+			},
+		},
 		"does not skip directories starting with '.'": {
 			fileRoot:   j("dotgodir"),
 			importRoot: "dotgodir",
@@ -2250,6 +2286,8 @@ func TestCanaryPackageTreeCopy(t *testing.T) {
 		"CommentPath",
 		"Imports",
 		"TestImports",
+		"ImportConstraints",
+		"TestImportConstraints",
 	}
 
 	fieldNames := func(typ reflect.Type) []string {
@@ -2290,6 +2328,9 @@ func TestPackageTreeCopy(t *testing.T) {
 						"github.com/sdboyer/gps",
 						"sort",
 					},
+					ImportConstraints: map[string][]BuildConstraint{
+						"github.com/sdboyer/gps": {{"linux"}},
+					},
 				},
 			},
 		},
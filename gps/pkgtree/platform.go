@@ -0,0 +1,329 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pkgtree
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// knownOS and knownArch hold the values of GOOS and GOARCH that the go tool
+// recognizes in file names, such as foo_windows.go or foo_linux_arm64.go.
+var knownOS = map[string]bool{
+	"android":   true,
+	"darwin":    true,
+	"dragonfly": true,
+	"freebsd":   true,
+	"js":        true,
+	"linux":     true,
+	"nacl":      true,
+	"netbsd":    true,
+	"openbsd":   true,
+	"plan9":     true,
+	"solaris":   true,
+	"windows":   true,
+	"zos":       true,
+}
+
+var knownArch = map[string]bool{
+	"386":         true,
+	"amd64":       true,
+	"amd64p32":    true,
+	"arm":         true,
+	"armbe":       true,
+	"arm64":       true,
+	"arm64be":     true,
+	"mips":        true,
+	"mipsle":      true,
+	"mips64":      true,
+	"mips64le":    true,
+	"mips64p32":   true,
+	"mips64p32le": true,
+	"ppc":         true,
+	"ppc64":       true,
+	"ppc64le":     true,
+	"s390":        true,
+	"s390x":       true,
+	"sparc":       true,
+	"sparc64":     true,
+	"wasm":        true,
+}
+
+// Platform is a target that packages are built for: an operating system and
+// architecture, along with the build tags that are set. An empty GOARCH
+// stands for every architecture.
+type Platform struct {
+	GOOS   string
+	GOARCH string
+	Tags   []string
+}
+
+// ParsePlatform parses a platform written as GOOS/GOARCH, such as
+// "linux/amd64", or as a GOOS alone, such as "windows".
+func ParsePlatform(s string) (Platform, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) > 2 {
+		return Platform{}, fmt.Errorf("invalid platform %q: must be GOOS or GOOS/GOARCH", s)
+	}
+	if !knownOS[parts[0]] {
+		return Platform{}, fmt.Errorf("invalid platform %q: unknown GOOS %q", s, parts[0])
+	}
+
+	p := Platform{GOOS: parts[0]}
+	if len(parts) == 2 {
+		if !knownArch[parts[1]] {
+			return Platform{}, fmt.Errorf("invalid platform %q: unknown GOARCH %q", s, parts[1])
+		}
+		p.GOARCH = parts[1]
+	}
+	return p, nil
+}
+
+// String returns the platform in the form accepted by ParsePlatform. Tags are
+// not included.
+func (p Platform) String() string {
+	if p.GOARCH == "" {
+		return p.GOOS
+	}
+	return p.GOOS + "/" + p.GOARCH
+}
+
+// toolchainTags are the build tags that depend on the toolchain a dependency
+// is eventually built with, which is unknown. A term on any of them, negated
+// or not, is assumed to be satisfied.
+var toolchainTags = map[string]bool{
+	"cgo":   true,
+	"gc":    true,
+	"gccgo": true,
+}
+
+// hasTag reports whether the build tag is satisfied on the platform.
+//
+// All release tags are assumed to be satisfied, for the same reason as the
+// toolchain tags. So is the ignore tag, so that the imports of programs kept out of builds with it (go
+// generate tools, typically) are still followed, just as they are when no
+// platforms are given.
+func (p Platform) hasTag(tag string) bool {
+	switch {
+	case tag == p.GOOS, tag == p.GOARCH:
+		return true
+	case tag == "linux" && p.GOOS == "android":
+		return true
+	case tag == "ignore", strings.HasPrefix(tag, "go1."):
+		return true
+	}
+
+	for _, t := range p.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// matchTerm reports whether a single term of a build constraint, such as
+// "linux" or "!appengine", is satisfied on the platform.
+func (p Platform) matchTerm(term string) bool {
+	if strings.HasPrefix(term, "!!") {
+		return false
+	}
+	if toolchainTags[strings.TrimPrefix(term, "!")] {
+		return true
+	}
+	if strings.HasPrefix(term, "!") {
+		return len(term) > 1 && !p.hasTag(term[1:])
+	}
+	return p.hasTag(term)
+}
+
+// A BuildConstraint holds the build constraints of a Go source file, as the
+// arguments of its +build lines. A constraint implied by the name of the
+// file, such as foo_windows.go, is included as a line of its own. The file is
+// built only where every line is satisfied.
+type BuildConstraint []string
+
+// buildConstraintOf returns the build constraint of a file, given its name
+// and the arguments of its +build lines. It is nil for files that are built on
+// every platform.
+func buildConstraintOf(name string, lines []string) BuildConstraint {
+	var bc BuildConstraint
+	if nc := nameConstraint(name); nc != "" {
+		bc = append(bc, nc)
+	}
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		// ignore is satisfied everywhere, so a line consisting of nothing else
+		// never excludes the file.
+		if line != "" && line != "ignore" {
+			bc = append(bc, line)
+		}
+	}
+	return bc
+}
+
+// nameConstraint returns the build constraint implied by the name of a file,
+// following the same rules as the go tool, or an empty string if there is
+// none.
+func nameConstraint(name string) string {
+	name = strings.TrimSuffix(name, ".go")
+	i := strings.Index(name, "_")
+	if i < 0 {
+		return ""
+	}
+
+	l := strings.Split(name[i:], "_")
+	if n := len(l); n > 0 && l[n-1] == "test" {
+		l = l[:n-1]
+	}
+	n := len(l)
+	switch {
+	case n >= 2 && knownOS[l[n-2]] && knownArch[l[n-1]]:
+		return l[n-2] + "," + l[n-1]
+	case n >= 1 && (knownOS[l[n-1]] || knownArch[l[n-1]]):
+		return l[n-1]
+	}
+	return ""
+}
+
+// Matches reports whether a file with the build constraint is built on the
+// platform. If the platform has no GOARCH, it reports whether the file is
+// built on any architecture.
+func (bc BuildConstraint) Matches(p Platform) bool {
+	if p.GOARCH == "" {
+		for arch := range knownArch {
+			pa := p
+			pa.GOARCH = arch
+			if bc.Matches(pa) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, line := range bc {
+		if !p.matchLine(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchLine reports whether the arguments of a +build line are satisfied on
+// the platform: at least one space-separated option must be, and an option
+// is satisfied if all of its comma-separated terms are.
+func (p Platform) matchLine(line string) bool {
+	for _, opt := range strings.Fields(line) {
+		ok := true
+		for _, term := range strings.Split(opt, ",") {
+			if !p.matchTerm(term) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ForPlatforms returns a new PackageTree in which the imports of each package
+// are limited to those made by files that are built on at least one of the
+// platforms. If no platforms are given, the PackageTree is returned as it is.
+func (t PackageTree) ForPlatforms(platforms []Platform) PackageTree {
+	if len(platforms) == 0 {
+		return t
+	}
+
+	return PackageTree{
+		ImportRoot: t.ImportRoot,
+		Packages: CopyPackages(t.Packages, func(ip string, poe PackageOrErr) (string, PackageOrErr) {
+			if poe.Err == nil {
+				poe.P.Imports = filterImports(poe.P.Imports, poe.P.ImportConstraints, platforms)
+				poe.P.TestImports = filterImports(poe.P.TestImports, poe.P.TestImportConstraints, platforms)
+			}
+			return ip, poe
+		}),
+	}
+}
+
+// filterImports returns the imports among imps that are made by a file built
+// on at least one of the platforms, given the constraints of the imports.
+func filterImports(imps []string, ic map[string][]BuildConstraint, platforms []Platform) []string {
+	if len(ic) == 0 {
+		return imps
+	}
+
+	ret := imps[:0]
+	for _, imp := range imps {
+		bcs, constrained := ic[imp]
+		if !constrained || anyMatches(bcs, platforms) {
+			ret = append(ret, imp)
+		}
+	}
+	return ret
+}
+
+func anyMatches(bcs []BuildConstraint, platforms []Platform) bool {
+	for _, bc := range bcs {
+		for _, p := range platforms {
+			if bc.Matches(p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// importConstraints collects, for each import, the build constraints of the
+// files that make it. Imports made by any file that is built everywhere are
+// left out, as they are needed on every platform.
+type importConstraints struct {
+	everywhere map[string]bool
+	bcs        map[string][]BuildConstraint
+}
+
+func (ic *importConstraints) add(imp string, bc BuildConstraint) {
+	if bc == nil {
+		if ic.everywhere == nil {
+			ic.everywhere = make(map[string]bool)
+		}
+		ic.everywhere[imp] = true
+		return
+	}
+
+	if ic.bcs == nil {
+		ic.bcs = make(map[string][]BuildConstraint)
+	}
+	ic.bcs[imp] = append(ic.bcs[imp], bc)
+}
+
+// result returns the constraints of the imports that are not made
+// everywhere, or nil if there are none.
+func (ic *importConstraints) result() map[string][]BuildConstraint {
+	var ret map[string][]BuildConstraint
+	for imp, bcs := range ic.bcs {
+		if ic.everywhere[imp] {
+			continue
+		}
+		if ret == nil {
+			ret = make(map[string][]BuildConstraint)
+		}
+		keys := make(map[string]BuildConstraint, len(bcs))
+		for _, bc := range bcs {
+			keys[strings.Join(bc, "\n")] = bc
+		}
+		uniq := make([]BuildConstraint, 0, len(keys))
+		for _, bc := range keys {
+			uniq = append(uniq, bc)
+		}
+		sort.Slice(uniq, func(i, j int) bool {
+			return strings.Join(uniq[i], "\n") < strings.Join(uniq[j], "\n")
+		})
+		ret[imp] = uniq
+	}
+	return ret
+}
@@ -0,0 +1,159 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pkgtree
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParsePlatform(t *testing.T) {
+	cases := map[string]Platform{
+		"linux/amd64": {GOOS: "linux", GOARCH: "amd64"},
+		"windows":     {GOOS: "windows"},
+		"js/wasm":     {GOOS: "js", GOARCH: "wasm"},
+	}
+	for s, want := range cases {
+		got, err := ParsePlatform(s)
+		if err != nil {
+			t.Errorf("ParsePlatform(%q) failed: %s", s, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ParsePlatform(%q) = %+v, want %+v", s, got, want)
+		}
+		if got.String() != s {
+			t.Errorf("%+v.String() = %q, want %q", got, got.String(), s)
+		}
+	}
+
+	for _, s := range []string{"", "amd64", "linux/", "linux/amd64/v2", "beos/amd64", "linux/vax"} {
+		if _, err := ParsePlatform(s); err == nil {
+			t.Errorf("expected ParsePlatform(%q) to fail", s)
+		}
+	}
+}
+
+func TestNameConstraint(t *testing.T) {
+	cases := map[string]string{
+		"foo.go":                  "",
+		"foo_bar.go":              "",
+		"foo_windows.go":          "windows",
+		"foo_windows_test.go":     "windows",
+		"foo_amd64.go":            "amd64",
+		"foo_linux_arm64.go":      "linux,arm64",
+		"foo_linux_arm64_test.go": "linux,arm64",
+		"linux.go":                "",
+		"_linux.go":               "linux",
+		"foo_test.go":             "",
+	}
+	for name, want := range cases {
+		if got := nameConstraint(name); got != want {
+			t.Errorf("nameConstraint(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestBuildConstraintMatches(t *testing.T) {
+	linux := Platform{GOOS: "linux", GOARCH: "amd64"}
+	android := Platform{GOOS: "android", GOARCH: "arm"}
+	windows := Platform{GOOS: "windows"}
+	appengine := Platform{GOOS: "linux", GOARCH: "amd64", Tags: []string{"appengine"}}
+
+	cases := []struct {
+		bc   BuildConstraint
+		p    Platform
+		want bool
+	}{
+		{BuildConstraint{"linux"}, linux, true},
+		{BuildConstraint{"linux"}, android, true},
+		{BuildConstraint{"linux"}, windows, false},
+		{BuildConstraint{"linux,!android"}, android, false},
+		{BuildConstraint{"darwin linux"}, linux, true},
+		{BuildConstraint{"darwin linux", "!appengine"}, linux, true},
+		{BuildConstraint{"darwin linux", "!appengine"}, appengine, false},
+		{BuildConstraint{"appengine"}, appengine, true},
+		{BuildConstraint{"appengine"}, linux, false},
+		{BuildConstraint{"windows,386"}, windows, true},
+		{BuildConstraint{"windows,386", "!386"}, windows, false},
+		{BuildConstraint{"!windows"}, windows, false},
+		{BuildConstraint{"cgo,go1.9"}, linux, true},
+		{BuildConstraint{"!cgo"}, linux, true},
+		{BuildConstraint{"!gc"}, linux, true},
+		{BuildConstraint{"gccgo"}, linux, true},
+		{BuildConstraint{"linux,!cgo"}, windows, false},
+		{BuildConstraint{"ignore,linux"}, linux, true},
+		{BuildConstraint{"!!linux"}, linux, false},
+	}
+	for _, c := range cases {
+		if got := c.bc.Matches(c.p); got != c.want {
+			t.Errorf("%q.Matches(%+v) = %v, want %v", c.bc, c.p, got, c.want)
+		}
+	}
+}
+
+func TestForPlatforms(t *testing.T) {
+	ptree, err := ListPackages(filepath.Join(getTestdataRootDir(t), "src", "platforms"), "platforms")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		platforms   []Platform
+		imports     []string
+		testImports []string
+	}{
+		"no platforms": {
+			imports:     []string{"golang.org/x/sys/unix", "golang.org/x/sys/windows", "google.golang.org/appengine", "os/user", "sort"},
+			testImports: []string{"golang.org/x/sys/unix", "testing"},
+		},
+		"linux": {
+			platforms:   []Platform{{GOOS: "linux", GOARCH: "amd64"}},
+			imports:     []string{"golang.org/x/sys/unix", "os/user", "sort"},
+			testImports: []string{"golang.org/x/sys/unix", "testing"},
+		},
+		"windows": {
+			platforms: []Platform{{GOOS: "windows"}},
+			imports:   []string{"golang.org/x/sys/windows", "os/user", "sort"},
+		},
+		"appengine": {
+			platforms:   []Platform{{GOOS: "linux", GOARCH: "amd64", Tags: []string{"appengine"}}},
+			imports:     []string{"google.golang.org/appengine", "os/user", "sort"},
+			testImports: []string{"golang.org/x/sys/unix", "testing"},
+		},
+		"darwin and windows": {
+			platforms: []Platform{{GOOS: "darwin", GOARCH: "amd64"}, {GOOS: "windows", GOARCH: "386"}},
+			imports:   []string{"golang.org/x/sys/unix", "golang.org/x/sys/windows", "os/user", "sort"},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ptree.ForPlatforms(c.platforms).Packages["platforms"].P
+			if !reflect.DeepEqual(got.Imports, c.imports) {
+				t.Errorf("unexpected imports:\n\t(GOT): %v\n\t(WNT): %v", got.Imports, c.imports)
+			}
+			if len(got.TestImports) != 0 || len(c.testImports) != 0 {
+				if !reflect.DeepEqual(got.TestImports, c.testImports) {
+					t.Errorf("unexpected test imports:\n\t(GOT): %v\n\t(WNT): %v", got.TestImports, c.testImports)
+				}
+			}
+
+			listed, err := ListPackages(filepath.Join(getTestdataRootDir(t), "src", "platforms"), "platforms", c.platforms...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(listed.Packages["platforms"].P, got) {
+				t.Errorf("ListPackages with platforms differs from ForPlatforms:\n\t(GOT): %#v\n\t(WNT): %#v", listed.Packages["platforms"].P, got)
+			}
+		})
+	}
+
+	// The original tree must be left as it was.
+	if imps := ptree.Packages["platforms"].P.Imports; len(imps) != 5 {
+		t.Errorf("ForPlatforms modified the original tree: %v", imps)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultPrefetchWorkers is the number of projects SourceMgr.Prefetch works
+// on at once when not told otherwise.
+const DefaultPrefetchWorkers = 8
+
+// prefetchRequest is a project to prefetch, and the version of it to warm the
+// package tree and manifest of, if one is known.
+type prefetchRequest struct {
+	id ProjectIdentifier
+	v  Version
+}
+
+// Prefetch warms the caches of the SourceMgr for the projects in the manifest
+// and lock of a root project, so that a solver does not have to wait on the
+// network for each of them in turn: the versions of every project are listed,
+// and for those in the lock, the packages and the manifest and lock at the
+// locked version are read, using the ProjectAnalyzer. Either of m and l may be
+// nil.
+//
+// At most workers projects are fetched at once, or DefaultPrefetchWorkers if
+// workers is not positive. Failures to fetch a project are not reported, as
+// the solver will run into them again, should it need the project at all;
+// the error returned is only that of ctx, if it is done before Prefetch is.
+func (sm *SourceMgr) Prefetch(ctx context.Context, m Manifest, l Lock, an ProjectAnalyzer, workers int) error {
+	if atomic.LoadInt32(&sm.releasing) == 1 {
+		return ErrSourceManagerIsReleased
+	}
+	if workers <= 0 {
+		workers = DefaultPrefetchWorkers
+	}
+
+	reqs := prefetchRequests(m, l)
+	if workers > len(reqs) {
+		workers = len(reqs)
+	}
+
+	reqch := make(chan prefetchRequest)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for req := range reqch {
+				sm.prefetch(ctx, req, an)
+			}
+		}()
+	}
+
+feed:
+	for _, req := range reqs {
+		select {
+		case reqch <- req:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(reqch)
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+// prefetchRequests returns the projects of m and l to prefetch, sorted by
+// project root. Projects in l are prefetched at their locked versions, and
+// from the sources given for them in l.
+func prefetchRequests(m Manifest, l Lock) []prefetchRequest {
+	byRoot := make(map[ProjectRoot]prefetchRequest)
+	if m != nil {
+		constraints := m.DependencyConstraints()
+		var overrides ProjectConstraints
+		if rm, ok := m.(RootManifest); ok {
+			overrides = rm.Overrides()
+		}
+		for pr, pp := range constraints {
+			id := ProjectIdentifier{ProjectRoot: pr, Source: pp.Source}
+			if opp, has := overrides[pr]; has && opp.Source != "" {
+				id.Source = opp.Source
+			}
+			byRoot[pr] = prefetchRequest{id: id}
+		}
+	}
+	if l != nil {
+		for _, lp := range l.Projects() {
+			id := lp.Ident()
+			byRoot[id.ProjectRoot] = prefetchRequest{id: id, v: lp.Version()}
+		}
+	}
+
+	reqs := make([]prefetchRequest, 0, len(byRoot))
+	for _, req := range byRoot {
+		reqs = append(reqs, req)
+	}
+	sort.Slice(reqs, func(i, j int) bool {
+		return reqs[i].id.ProjectRoot < reqs[j].id.ProjectRoot
+	})
+	return reqs
+}
+
+// prefetch warms the caches for a single project, giving up at its first
+// failure.
+func (sm *SourceMgr) prefetch(ctx context.Context, req prefetchRequest, an ProjectAnalyzer) {
+	srcg, err := sm.srcCoord.getSourceGatewayFor(ctx, req.id)
+	if err != nil {
+		return
+	}
+	if _, err := srcg.listVersions(ctx); err != nil || req.v == nil {
+		return
+	}
+	if _, err := srcg.listPackages(ctx, req.id.ProjectRoot, req.v); err != nil || an == nil {
+		return
+	}
+	srcg.getManifestAndLock(ctx, req.id.ProjectRoot, req.v, an)
+}
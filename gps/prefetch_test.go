@@ -0,0 +1,94 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/golang/dep/internal/test"
+)
+
+func TestPrefetchRequests(t *testing.T) {
+	m := simpleRootManifest{
+		c: ProjectConstraints{
+			"github.com/foo/bar": ProjectProperties{Constraint: Any()},
+			"github.com/foo/baz": ProjectProperties{Source: "github.com/fork/baz", Constraint: Any()},
+		},
+		ovr: ProjectConstraints{
+			"github.com/foo/bar": ProjectProperties{Source: "github.com/fork/bar"},
+		},
+	}
+	v := NewVersion("v1.0.0").Pair("abc123")
+	l := safeLock{
+		p: []LockedProject{
+			NewLockedProject(ProjectIdentifier{ProjectRoot: "github.com/foo/baz"}, v, []string{"."}),
+			NewLockedProject(mkPI("github.com/foo/qux"), v, []string{"."}),
+		},
+	}
+
+	got := prefetchRequests(m, l)
+	want := []prefetchRequest{
+		{id: ProjectIdentifier{ProjectRoot: "github.com/foo/bar", Source: "github.com/fork/bar"}},
+		{id: ProjectIdentifier{ProjectRoot: "github.com/foo/baz"}, v: v},
+		{id: mkPI("github.com/foo/qux"), v: v},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected requests:\n\t(GOT): %v\n\t(WNT): %v", got, want)
+	}
+
+	if got := prefetchRequests(nil, nil); len(got) != 0 {
+		t.Fatalf("expected no requests, got %v", got)
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempFile("locked/locked.go", "package locked\n")
+	h.TempFile("constrained/constrained.go", "package constrained\n")
+
+	sm, clean := mkNaiveSM(t)
+	defer clean()
+
+	locked := ProjectIdentifier{ProjectRoot: "github.com/foo/locked", Source: h.Path("locked")}
+	rev, err := localRevision(h.Path("locked"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := simpleRootManifest{
+		c: ProjectConstraints{
+			"github.com/foo/constrained": ProjectProperties{Source: h.Path("constrained"), Constraint: Any()},
+		},
+	}
+	l := safeLock{
+		p: []LockedProject{NewLockedProject(locked, NewVersion(LocalVersion).Pair(rev), []string{"."})},
+	}
+
+	if err := sm.Prefetch(context.Background(), m, l, naiveAnalyzer{}, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sm.srcCoord.srcs) != 2 {
+		t.Fatalf("expected gateways for both projects, got %v", sm.srcCoord.srcs)
+	}
+	srcg, err := sm.srcCoord.getSourceGatewayFor(context.Background(), locked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := srcg.cache.getPackageTree(rev, locked.ProjectRoot); !ok {
+		t.Error("expected the package tree of the locked version to be cached")
+	}
+	if _, _, ok := srcg.cache.getManifestAndLock(rev, naiveAnalyzer{}.Info()); !ok {
+		t.Error("expected the manifest of the locked version to be cached")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sm.Prefetch(ctx, m, l, naiveAnalyzer{}, 0); err != context.Canceled {
+		t.Fatalf("expected a canceled prefetch to fail with %v, got %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,476 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// proxyMarkerFile records, in the cache directory of a proxy source, the
+	// URL of the module it holds.
+	proxyMarkerFile = "proxy-source"
+	// proxyVersionsFile records the module versions seen so far, with their
+	// revisions, one per line.
+	proxyVersionsFile = "versions"
+	// incompatibleSuffix marks the module versions of v2 and later releases
+	// of a project that has no go.mod.
+	incompatibleSuffix = "+incompatible"
+)
+
+// ValidateProxy checks that proxy is usable as the base URL of a Go module
+// proxy.
+func ValidateProxy(proxy string) error {
+	u, err := url.Parse(proxy)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.Errorf("invalid module proxy %q: it must be an http or https URL", proxy)
+	}
+	return nil
+}
+
+// escapeModulePath escapes a module path or version for use in the URLs of a
+// module proxy, which are case-insensitive: each upper-case letter is
+// replaced by an exclamation mark followed by its lower-case version.
+func escapeModulePath(s string) string {
+	var buf []rune
+	for _, r := range s {
+		if unicode.IsUpper(r) {
+			buf = append(buf, '!', unicode.ToLower(r))
+		} else {
+			buf = append(buf, r)
+		}
+	}
+	return string(buf)
+}
+
+// proxyVersion returns the version offered for a module version. The
+// +incompatible suffix is dropped, as it only matters to the go command.
+func proxyVersion(modv string, r Revision) PairedVersion {
+	return NewVersion(strings.TrimSuffix(modv, incompatibleSuffix)).Pair(r)
+}
+
+// proxyInfo is the metadata a module proxy serves about a module version.
+type proxyInfo struct {
+	Version string
+	Time    time.Time
+	Origin  *struct {
+		Hash string
+	}
+}
+
+type maybeProxySource struct {
+	proxy   string // the base URL of the proxy
+	module  string // the module path, which is the project root
+	offline bool
+}
+
+// try checks that the proxy has versions of the module, and reports the
+// commits they were made from, so that other modules fall back to the
+// repository they would otherwise come from. Offline, the module must already
+// be in the cache.
+func (m maybeProxySource) try(ctx context.Context, cachedir string) (source, error) {
+	s := &proxySource{
+		base:       m.URL().String(),
+		module:     m.module,
+		offline:    m.offline,
+		revs:       make(map[Revision]string),
+		branchRevs: make(map[Revision]bool),
+	}
+	s.unpackedTrees = unpackedTrees{dir: m.cachePath(cachedir), fetchTree: s.fetchRevision}
+
+	if m.offline {
+		if !s.existsLocally(ctx) {
+			return nil, OfflineError{What: s.base}
+		}
+		return s, nil
+	}
+
+	versions, err := s.upstreamVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, errors.Errorf("module proxy %s has no versions of %s", m.proxy, m.module)
+	}
+
+	for _, modv := range versions {
+		info, err := s.info(ctx, modv)
+		if err != nil {
+			return nil, err
+		}
+		if r, err := s.infoRevision(info); err == nil {
+			s.revs[r] = modv
+			return s, nil
+		}
+	}
+	return nil, errors.Errorf("module proxy %s does not report the commits versions of %s were made from", m.proxy, m.module)
+}
+
+func (m maybeProxySource) cachePath(cachedir string) string {
+	return sourceCachePath(cachedir, m.URL().String())
+}
+
+func (m maybeProxySource) URL() *url.URL {
+	u, _ := url.Parse(strings.TrimSuffix(m.proxy, "/") + "/" + escapeModulePath(m.module))
+	return u
+}
+
+func (m maybeProxySource) String() string {
+	return fmt.Sprintf("%T: %s", m, m.URL())
+}
+
+// proxySource is a source served by a Go module proxy, through the
+// GOPROXY protocol:
+//
+//	<base>/@v/list             the module versions, one per line
+//	<base>/@v/<version>.info   the metadata of a version, in JSON
+//	<base>/@v/<version>.zip    the tree of a version
+//
+// The revision of a version is the commit the proxy reports it was made
+// from, so that Gopkg.lock matches what the repository itself would yield.
+// Versions whose commits the proxy does not report are left out.
+//
+// Module proxies do not serve branches. Those are listed from the repository
+// the proxy was tried ahead of, along with the versions on the proxy. The
+// trees at revisions that the proxy is unable to resolve, such as those of
+// branches, come from that repository too.
+//
+// Each tree is unpacked into the cache directory of the source once, in a
+// directory named after its revision.
+type proxySource struct {
+	unpackedTrees
+	base    string // the URL of the module on the proxy
+	module  string
+	offline bool
+	// revs maps the revisions seen so far to their module versions.
+	revs map[Revision]string
+	// branchRevs holds the revisions of the branches listed so far.
+	branchRevs map[Revision]bool
+	// fallback returns the gateway of the repository the proxy was tried
+	// ahead of, if there is one.
+	fallback func(context.Context) (*sourceGateway, error)
+}
+
+func (s *proxySource) existsLocally(ctx context.Context) bool {
+	_, err := os.Stat(filepath.Join(s.dir, proxyMarkerFile))
+	return err == nil
+}
+
+func (s *proxySource) existsUpstream(ctx context.Context) bool {
+	_, err := s.upstreamVersions(ctx)
+	return err == nil
+}
+
+func (s *proxySource) upstreamURL() string {
+	return s.base
+}
+
+func (s *proxySource) initLocal(ctx context.Context) error {
+	if err := os.MkdirAll(s.dir, 0777); err != nil {
+		return errors.Wrapf(err, "failed to create cache directory for %s", s.base)
+	}
+	return ioutil.WriteFile(filepath.Join(s.dir, proxyMarkerFile), []byte(s.base), 0666)
+}
+
+// updateLocal is a no-op, as trees are fetched as they are needed.
+func (*proxySource) updateLocal(ctx context.Context) error {
+	return nil
+}
+
+func (*proxySource) maybeClean(ctx context.Context) error {
+	return nil
+}
+
+func (s *proxySource) listVersions(ctx context.Context) ([]PairedVersion, error) {
+	versions, err := s.upstreamVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.listCachedVersions(ctx); err != nil {
+		return nil, err
+	}
+	known := make(map[string]Revision, len(s.revs))
+	for r, modv := range s.revs {
+		known[modv] = r
+	}
+
+	// The records use module versions, so they can be turned back into URLs.
+	records := make([]PairedVersion, 0, len(versions))
+	pvs := make([]PairedVersion, 0, len(versions))
+	for _, modv := range versions {
+		r, has := known[modv]
+		if !has {
+			info, err := s.info(ctx, modv)
+			if err != nil {
+				return nil, err
+			}
+			if r, err = s.infoRevision(info); err != nil {
+				continue
+			}
+		}
+		s.revs[r] = modv
+		records = append(records, NewVersion(modv).Pair(r))
+		pvs = append(pvs, proxyVersion(modv, r))
+	}
+	if err := writeVersionsFile(filepath.Join(s.dir, proxyVersionsFile), records); err != nil {
+		return nil, err
+	}
+
+	branches, err := s.listBranches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append(pvs, branches...), nil
+}
+
+func (s *proxySource) listCachedVersions(ctx context.Context) ([]PairedVersion, error) {
+	records, err := readVersionsFile(filepath.Join(s.dir, proxyVersionsFile))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read cached versions of %s", s.base)
+	}
+
+	pvs := make([]PairedVersion, 0, len(records))
+	for _, rec := range records {
+		s.revs[rec.Revision()] = rec.String()
+		pvs = append(pvs, proxyVersion(rec.String(), rec.Revision()))
+	}
+	return pvs, nil
+}
+
+// upstreamVersions returns the module versions the proxy lists.
+func (s *proxySource) upstreamVersions(ctx context.Context) ([]string, error) {
+	if s.offline {
+		return nil, OfflineError{What: s.base}
+	}
+
+	u := s.base + "/@v/list"
+	resp, err := httpGet(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var versions []string
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		if v := strings.TrimSpace(sc.Text()); v != "" {
+			versions = append(versions, v)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", u)
+	}
+	return versions, nil
+}
+
+// info returns the metadata of a module version, which may also be a
+// revision the proxy is able to resolve.
+func (s *proxySource) info(ctx context.Context, query string) (proxyInfo, error) {
+	if s.offline {
+		return proxyInfo{}, OfflineError{What: fmt.Sprintf("version %q of %s", query, s.base)}
+	}
+
+	u := s.base + "/@v/" + escapeModulePath(query) + ".info"
+	resp, err := httpGet(ctx, u)
+	if err != nil {
+		return proxyInfo{}, err
+	}
+	defer resp.Body.Close()
+
+	var info proxyInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return proxyInfo{}, errors.Wrapf(err, "failed to decode %s", u)
+	}
+	if info.Version == "" {
+		return proxyInfo{}, errors.Errorf("%s does not name a version", u)
+	}
+	return info, nil
+}
+
+// infoRevision returns the revision a module version was made from, given
+// its metadata. Without it, the version cannot be locked in a way that does
+// not depend on the proxy.
+func (s *proxySource) infoRevision(info proxyInfo) (Revision, error) {
+	if info.Origin == nil || info.Origin.Hash == "" {
+		return "", errors.Errorf("module proxy does not report the commit %s %s was made from", s.module, info.Version)
+	}
+	return Revision(info.Origin.Hash), nil
+}
+
+// listBranches lists the branches of the project from the repository the
+// proxy was tried ahead of, if there is one.
+func (s *proxySource) listBranches(ctx context.Context) ([]PairedVersion, error) {
+	if s.fallback == nil {
+		return nil, nil
+	}
+	sg, err := s.fallback(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vl, err := sg.listVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []PairedVersion
+	for _, v := range vl {
+		if v.Type() == IsBranch {
+			s.branchRevs[v.Revision()] = true
+			branches = append(branches, v)
+		}
+	}
+	return branches, nil
+}
+
+// moduleVersion returns the module version of the revision.
+func (s *proxySource) moduleVersion(ctx context.Context, r Revision) (string, error) {
+	// The revision may come from Gopkg.lock, before the versions have been
+	// listed.
+	if modv, has := s.revs[r]; has {
+		return modv, nil
+	}
+	if _, err := s.listCachedVersions(ctx); err != nil {
+		return "", err
+	}
+	if modv, has := s.revs[r]; has {
+		return modv, nil
+	}
+	if s.offline {
+		return "", OfflineError{What: fmt.Sprintf("revision %s of %s", r, s.base)}
+	}
+
+	// The proxy may be able to resolve revisions that no tagged version is
+	// made from into pseudo-versions.
+	info, err := s.info(ctx, string(r))
+	if err != nil {
+		return "", errors.Wrapf(err, "module proxy has no version of %s at revision %s", s.module, r)
+	}
+	ir, err := s.infoRevision(info)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(string(ir), string(r)) {
+		return "", errors.Errorf("module proxy resolved revision %s of %s to %s, at revision %s", r, s.module, info.Version, ir)
+	}
+	s.revs[r] = info.Version
+	return info.Version, nil
+}
+
+// fetchRevision fetches the tree at the revision, from the zip of the module
+// version made from it.
+func (s *proxySource) fetchRevision(ctx context.Context, r Revision) error {
+	modv, err := s.moduleVersion(ctx, r)
+	if err != nil {
+		if s.fallback != nil {
+			// The proxy may know nothing of commits that no module version
+			// was made from yet, such as those at the tips of branches; the
+			// repository does.
+			return s.exportFromRepository(ctx, r)
+		}
+		return err
+	}
+	if s.offline {
+		return OfflineError{What: fmt.Sprintf("version %q of %s", modv, s.base)}
+	}
+
+	f, err := ioutil.TempFile(s.dir, "download-")
+	if err != nil {
+		return errors.Wrapf(err, "failed to create temporary file in %s", s.dir)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	u := s.base + "/@v/" + escapeModulePath(modv) + ".zip"
+	resp, err := httpGet(ctx, u)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return errors.Wrapf(err, "failed to download %s", u)
+	}
+
+	return s.placeTree(r, u, func(tmp string) (string, error) {
+		// Every file in a module zip is within a module@version/ directory.
+		if err := unpackZip(f, tmp, s.module+"@"+modv+"/"); err != nil {
+			return "", errors.Wrapf(err, "failed to unpack %s", u)
+		}
+		return tmp, nil
+	})
+}
+
+// exportFromRepository exports the tree at the revision from the repository
+// the proxy was tried ahead of.
+func (s *proxySource) exportFromRepository(ctx context.Context, r Revision) error {
+	sg, err := s.fallback(ctx)
+	if err != nil {
+		return err
+	}
+
+	what := fmt.Sprintf("revision %s of %s", r, s.module)
+	return s.placeTree(r, what, func(tmp string) (string, error) {
+		to := filepath.Join(tmp, "tree")
+		return to, sg.exportVersionTo(ctx, r, to)
+	})
+}
+
+func (s *proxySource) revisionPresentIn(r Revision) (bool, error) {
+	if _, has := s.revs[r]; has || s.branchRevs[r] {
+		return true, nil
+	}
+	return s.hasTree(r), nil
+}
+
+func (s *proxySource) disambiguateRevision(ctx context.Context, r Revision) (Revision, error) {
+	if present, _ := s.revisionPresentIn(r); present {
+		return r, nil
+	}
+	if _, err := s.moduleVersion(ctx, r); err != nil {
+		return "", err
+	}
+	return r, nil
+}
+
+func (s *proxySource) revisionTime(ctx context.Context, r Revision) (time.Time, error) {
+	modv, err := s.moduleVersion(ctx, r)
+	if err != nil {
+		return time.Time{}, err
+	}
+	info, err := s.info(ctx, modv)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.Time, nil
+}
+
+func (s *proxySource) commitsBetween(ctx context.Context, from, to Revision) (int, error) {
+	return 0, errors.Errorf("%s serves module versions from a proxy, so it has no commits", s.upstreamURL())
+}
+
+func (*proxySource) sourceType() string {
+	return "proxy"
+}
+
+func (*proxySource) existsCallsListVersions() bool {
+	return false
+}
+
+func (*proxySource) listVersionsRequiresLocal() bool {
+	return true
+}
@@ -0,0 +1,318 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/dep/gps/pkgtree"
+	"github.com/golang/dep/internal/test"
+)
+
+func TestEscapeModulePath(t *testing.T) {
+	cases := map[string]string{
+		"github.com/foo/bar":         "github.com/foo/bar",
+		"github.com/Azure/azure-sdk": "github.com/!azure/azure-sdk",
+		"v1.0.0-RC1":                 "v1.0.0-!r!c1",
+	}
+	for in, want := range cases {
+		if got := escapeModulePath(in); got != want {
+			t.Errorf("escapeModulePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestProxyDeduction(t *testing.T) {
+	dc := newDeductionCoordinator(newSupervisor(context.Background()), SourceManagerConfig{Proxy: "https://proxy.example.com"})
+
+	pd, err := dc.deduceRootPath(context.Background(), "github.com/Foo/bar/baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pd.mb) < 2 {
+		t.Fatalf("expected the proxy ahead of the repositories, got %v", pd.mb)
+	}
+	want := maybeProxySource{proxy: "https://proxy.example.com", module: "github.com/Foo/bar"}
+	if pd.mb[0] != want {
+		t.Fatalf("expected %v first, got %v", want, pd.mb[0])
+	}
+	if got := pd.mb[0].URL().String(); got != "https://proxy.example.com/github.com/!foo/bar" {
+		t.Fatalf("unexpected proxy URL %s", got)
+	}
+
+	// A source given as a URL always goes to the repository.
+	pd, err = dc.deduceRootPath(context.Background(), "https://github.com/foo/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mb := range pd.mb {
+		if _, is := mb.(maybeProxySource); is {
+			t.Fatalf("expected no proxy for an explicit URL, got %v", pd.mb)
+		}
+	}
+}
+
+func TestProxySource(t *testing.T) {
+	const hash = "d3f1e6d4b6d1b5e1a5c1f6e2c2a0c5d7a2b1c3d4"
+	v1 := mkZip(t, map[string]string{
+		"github.com/foo/bar@v1.0.0/bar.go":     "package bar\n\nimport _ \"github.com/sdboyer/deptest\"\n",
+		"github.com/foo/bar@v1.0.0/baz/baz.go": "package baz\n",
+	})
+	ps := newArchiveServer(map[string][]byte{
+		"/github.com/foo/bar/@v/list":                     []byte("v1.0.0\nv2.0.0+incompatible\n"),
+		"/github.com/foo/bar/@v/v1.0.0.info":              []byte(`{"Version":"v1.0.0","Time":"2018-06-01T10:00:00Z","Origin":{"VCS":"git","Hash":"` + hash + `"}}`),
+		"/github.com/foo/bar/@v/v1.0.0.zip":               v1,
+		"/github.com/foo/bar/@v/v2.0.0+incompatible.info": []byte(`{"Version":"v2.0.0+incompatible","Time":"2018-07-01T10:00:00Z"}`),
+	})
+	defer ps.Close()
+
+	// The branches come from the repository the proxy is tried ahead of.
+	requiresBins(t, "git")
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("mirror/foo/bar")
+	repoPath := h.Path("mirror/foo/bar")
+	h.RunGit(repoPath, "init")
+	h.RunGit(repoPath, "config", "--local", "user.email", "test@example.com")
+	h.RunGit(repoPath, "config", "--local", "user.name", "Test author")
+	h.TempFile("mirror/foo/bar/bar.go", "package bar\n")
+	h.RunGit(repoPath, "add", "bar.go")
+	h.RunGit(repoPath, "commit", "--message=Initial commit")
+	h.RunGit(repoPath, "branch", "-M", "master")
+	head := gitHead(t, repoPath)
+
+	cachedir, err := ioutil.TempDir("", "proxysource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cachedir)
+
+	sm, err := NewSourceManager(SourceManagerConfig{
+		Cachedir: cachedir,
+		Proxy:    ps.URL,
+		Mirrors: []Mirror{{
+			Prefix: "https://github.com/",
+			URL:    "file://" + filepath.ToSlash(h.Path("mirror")) + "/",
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Release()
+
+	id := mkPI("github.com/foo/bar")
+	vl, err := sm.ListVersions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// v2.0.0 is left out, as the proxy does not report its commit.
+	want := []PairedVersion{
+		NewVersion("v1.0.0").Pair(Revision(hash)),
+	}
+	if len(vl) != 2 || vl[0] != want[0] {
+		t.Fatalf("unexpected versions:\n\t(GOT): %v\n\t(WNT): %v and the master branch", vl, want)
+	}
+	if vl[1].Type() != IsBranch || vl[1].String() != "master" || vl[1].Revision() != Revision(head) {
+		t.Fatalf("expected the master branch at %s, got %v", head, vl[1])
+	}
+
+	ptree, err := sm.ListPackages(id, want[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, has := ptree.Packages["github.com/foo/bar/baz"]; !has || len(ptree.Packages) != 2 {
+		t.Fatalf("unexpected packages: %v", ptree.Packages)
+	}
+
+	// The tree is only fetched once.
+	to := filepath.Join(cachedir, "export", "bar")
+	if err := sm.ExportProject(context.Background(), id, want[0], to); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(to, "baz", "baz.go")); err != nil {
+		t.Fatal(err)
+	}
+	if n := ps.hitsFor("/github.com/foo/bar/@v/v1.0.0.zip"); n != 1 {
+		t.Errorf("expected the zip of v1.0.0 to be fetched once, but it was fetched %d times", n)
+	}
+
+	css, err := sm.CachedSources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(css) != 1 || css[0].Type != "proxy" || css[0].URL != ps.URL+"/github.com/foo/bar" {
+		t.Fatalf("unexpected cached sources: %+v", css)
+	}
+
+	// Once the module is in the cache, an offline SourceMgr can serve it.
+	sm.Release()
+	ps.Close()
+	osm, err := NewSourceManager(SourceManagerConfig{Cachedir: cachedir, Proxy: ps.URL, Offline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer osm.Release()
+
+	ovl, err := osm.ListVersions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ovl) != len(want) || ovl[0] != want[0] {
+		t.Fatalf("unexpected offline versions:\n\t(GOT): %v\n\t(WNT): %v", ovl, want)
+	}
+	if _, err := osm.ListPackages(id, want[0]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProxySourceMissingModule(t *testing.T) {
+	ps := newArchiveServer(map[string][]byte{})
+	defer ps.Close()
+
+	cachedir, err := ioutil.TempDir("", "proxysource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cachedir)
+
+	// The proxy not having the module lets the coordinator move on to the
+	// repository.
+	m := maybeProxySource{proxy: ps.URL, module: "github.com/foo/bar"}
+	if _, err := m.try(context.Background(), cachedir); err == nil {
+		t.Fatal("expected trying a module the proxy does not have to fail")
+	}
+
+	// Nor does it having versions without reporting the commits they were
+	// made from.
+	ps.set("/github.com/foo/bar/@v/list", []byte("v1.0.0\n"))
+	ps.set("/github.com/foo/bar/@v/v1.0.0.info", []byte(`{"Version":"v1.0.0","Time":"2018-06-01T10:00:00Z"}`))
+	if _, err := m.try(context.Background(), cachedir); err == nil {
+		t.Fatal("expected trying a proxy that does not report commits to fail")
+	}
+
+	if _, err := NewSourceManager(SourceManagerConfig{Cachedir: cachedir, Proxy: "proxy.example.com"}); err == nil {
+		t.Fatal("expected a proxy that is not a URL to be rejected")
+	}
+}
+
+func TestProxySourceBranches(t *testing.T) {
+	requiresBins(t, "git")
+
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("mirror/foo/bar")
+	repoPath := h.Path("mirror/foo/bar")
+	h.RunGit(repoPath, "init")
+	h.RunGit(repoPath, "config", "--local", "user.email", "test@example.com")
+	h.RunGit(repoPath, "config", "--local", "user.name", "Test author")
+	h.TempFile("mirror/foo/bar/bar.go", "package bar\n")
+	h.RunGit(repoPath, "add", "bar.go")
+	h.RunGit(repoPath, "commit", "--message=Initial commit")
+	h.RunGit(repoPath, "tag", "v1.0.0")
+	tagged := gitHead(t, repoPath)
+	h.TempFile("mirror/foo/bar/baz/baz.go", "package baz\n")
+	h.RunGit(repoPath, "add", "baz")
+	h.RunGit(repoPath, "commit", "--message=Add baz")
+	h.RunGit(repoPath, "branch", "-M", "devel")
+	head := gitHead(t, repoPath)
+
+	// The proxy only has the tagged version, and cannot resolve the commit at
+	// the tip of the branch.
+	ps := newArchiveServer(map[string][]byte{
+		"/github.com/foo/bar/@v/list":        []byte("v1.0.0\n"),
+		"/github.com/foo/bar/@v/v1.0.0.info": []byte(`{"Version":"v1.0.0","Time":"2018-06-01T10:00:00Z","Origin":{"VCS":"git","Hash":"` + tagged + `"}}`),
+		"/github.com/foo/bar/@v/v1.0.0.zip":  mkZip(t, map[string]string{"github.com/foo/bar@v1.0.0/bar.go": "package bar\n"}),
+	})
+	defer ps.Close()
+
+	h.TempDir("smcache")
+	sm, err := NewSourceManager(SourceManagerConfig{
+		Cachedir: h.Path("smcache"),
+		Logger:   log.New(test.Writer{TB: t}, "", 0),
+		Proxy:    ps.URL,
+		Mirrors: []Mirror{{
+			Prefix: "https://github.com/",
+			URL:    "file://" + filepath.ToSlash(h.Path("mirror")) + "/",
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Release()
+
+	id := mkPI("github.com/foo/bar")
+	vl, err := sm.ListVersions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The branch comes from the repository, along with the version on the
+	// proxy.
+	if len(vl) != 2 || vl[0] != NewVersion("v1.0.0").Pair(Revision(tagged)) {
+		t.Fatalf("expected the version on the proxy and the branch, got %v", vl)
+	}
+	if vl[1].Type() != IsBranch || vl[1].String() != "devel" || vl[1].Revision() != Revision(head) {
+		t.Fatalf("expected the devel branch at %s, got %v", head, vl[1])
+	}
+
+	// A branch constraint is still solvable, from the repository.
+	h.TempDir("root")
+	params := SolveParameters{
+		RootDir: h.Path("root"),
+		RootPackageTree: pkgtree.PackageTree{
+			ImportRoot: "example.com/root",
+			Packages: map[string]pkgtree.PackageOrErr{
+				"example.com/root": {
+					P: pkgtree.Package{
+						ImportPath: "example.com/root",
+						Name:       "root",
+						Imports:    []string{"github.com/foo/bar/baz"},
+					},
+				},
+			},
+		},
+		Manifest: simpleRootManifest{
+			c: ProjectConstraints{
+				"github.com/foo/bar": ProjectProperties{Constraint: NewBranch("devel")},
+			},
+		},
+		ProjectAnalyzer: naiveAnalyzer{},
+	}
+	s, err := Prepare(params, sm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	soln, err := s.Solve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lps := soln.Projects()
+	if len(lps) != 1 {
+		t.Fatalf("expected a single project in the solution, got %v", lps)
+	}
+	if pv, ok := lps[0].Version().(PairedVersion); !ok || pv.Type() != IsBranch || pv.String() != "devel" || pv.Revision() != Revision(head) {
+		t.Fatalf("expected %s to be locked at devel, at %s, got %v", id, head, lps[0].Version())
+	}
+	if n := ps.hitsFor("/github.com/foo/bar/@v/" + head + ".info"); n == 0 {
+		t.Error("expected the proxy to be asked for the commit at the tip of the branch")
+	}
+}
+
+// gitHead returns the commit at the HEAD of the git repository at path.
+func gitHead(t *testing.T, path string) string {
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = path
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -30,6 +30,48 @@ const (
 	PruneGoTestFiles
 )
 
+// pruneOptionChars maps each of the PruneOptions to the character used to
+// represent it in string form, in canonical order.
+var pruneOptionChars = []struct {
+	opt PruneOptions
+	c   byte
+}{
+	{PruneNestedVendorDirs, 'N'},
+	{PruneUnusedPackages, 'U'},
+	{PruneNonGoFiles, 'G'},
+	{PruneGoTestFiles, 'T'},
+}
+
+// String returns a compact representation of the options, with one character
+// for each option that is set: N for nested vendor directories, U for unused
+// packages, G for non-Go files and T for Go test files.
+func (po PruneOptions) String() string {
+	var buf []byte
+	for _, pc := range pruneOptionChars {
+		if po&pc.opt != 0 {
+			buf = append(buf, pc.c)
+		}
+	}
+	return string(buf)
+}
+
+// ParsePruneOptions parses the string representation of PruneOptions, as
+// produced by PruneOptions.String.
+func ParsePruneOptions(s string) (PruneOptions, error) {
+	var po PruneOptions
+outer:
+	for i := 0; i < len(s); i++ {
+		for _, pc := range pruneOptionChars {
+			if s[i] == pc.c {
+				po |= pc.opt
+				continue outer
+			}
+		}
+		return 0, errors.Errorf("unknown prune option %q in %q", s[i], s)
+	}
+	return po, nil
+}
+
 // PruneOptionSet represents trinary distinctions for each of the types of
 // prune rules (as expressed via PruneOptions): nested vendor directories,
 // unused packages, non-go files, and go test files.
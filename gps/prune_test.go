@@ -103,6 +103,43 @@ func TestCascadingPruneOptions(t *testing.T) {
 	}
 }
 
+func TestPruneOptionsString(t *testing.T) {
+	cases := []struct {
+		opts PruneOptions
+		str  string
+	}{
+		{0, ""},
+		{PruneNestedVendorDirs, "N"},
+		{PruneNestedVendorDirs | PruneGoTestFiles, "NT"},
+		{PruneUnusedPackages | PruneNonGoFiles, "UG"},
+		{PruneNestedVendorDirs | PruneUnusedPackages | PruneNonGoFiles | PruneGoTestFiles, "NUGT"},
+	}
+
+	for _, c := range cases {
+		if got := c.opts.String(); got != c.str {
+			t.Errorf("unexpected string for %d:\n\t(GOT): %q\n\t(WNT): %q", c.opts, got, c.str)
+		}
+
+		got, err := ParsePruneOptions(c.str)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %s", c.str, err)
+			continue
+		}
+		if got != c.opts {
+			t.Errorf("unexpected options parsed from %q:\n\t(GOT): %d\n\t(WNT): %d", c.str, got, c.opts)
+		}
+	}
+
+	// Order does not matter when parsing.
+	if got, _ := ParsePruneOptions("TN"); got != PruneNestedVendorDirs|PruneGoTestFiles {
+		t.Errorf("expected out-of-order options to parse, got %d", got)
+	}
+
+	if _, err := ParsePruneOptions("NX"); err == nil {
+		t.Error("expected an error when parsing an unknown prune option")
+	}
+}
+
 func TestPruneProject(t *testing.T) {
 	h := test.NewHelper(t)
 	defer h.Cleanup()
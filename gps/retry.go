@@ -0,0 +1,172 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// RetryClass is a class of failures that a RetryPolicy may retry. Classes are
+// combined with |.
+type RetryClass uint
+
+const (
+	// RetryVCSRemote is the failure of a vcs command working with a remote
+	// repository, such as git clone, git fetch or git ls-remote, whose output
+	// suggests it is transient: a dropped or refused connection, a timeout or
+	// an HTTP 5xx status. A missing repository or rejected credentials are
+	// never retried.
+	RetryVCSRemote RetryClass = 1 << iota
+	// RetryNetwork is the failure of an HTTP request to get any response, as
+	// on a timeout or a refused connection.
+	RetryNetwork
+	// RetryHTTPServerError is an HTTP response with a 5xx status.
+	RetryHTTPServerError
+	// RetryHTTPTooManyRequests is an HTTP response with the 429 status.
+	RetryHTTPTooManyRequests
+	// RetryHTTPClientError is an HTTP response with any other 4xx status,
+	// such as a 404 from a module proxy or archive server. go-get metadata is
+	// read from such responses, so they are never failures for it.
+	RetryHTTPClientError
+)
+
+// RetryPolicy says how the SourceMgr retries the operations that reach the
+// network - fetching sources, listing their versions and fetching go-get
+// metadata - when they fail in ways that may be transient. The zero
+// RetryPolicy retries nothing.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times an operation is attempted in all.
+	// Operations are not retried if it is less than 2.
+	MaxAttempts int
+	// Backoff is the delay before the first retry, which doubles before each
+	// of the next ones.
+	Backoff time.Duration
+	// MaxBackoff, if positive, caps the delay between attempts.
+	MaxBackoff time.Duration
+	// RetryOn is the classes of failures that are retried.
+	RetryOn RetryClass
+	// Logger, if set, is told of each retry.
+	Logger *log.Logger
+}
+
+// DefaultRetryPolicy retries the failures of vcs commands and HTTP requests
+// that are most likely to be transient up to three times, after 1, 2 and 4
+// seconds.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	Backoff:     time.Second,
+	MaxBackoff:  5 * time.Second,
+	RetryOn:     RetryVCSRemote | RetryNetwork | RetryHTTPServerError | RetryHTTPTooManyRequests,
+}
+
+// vcsRemoteError marks the failure of a vcs command working with a remote
+// repository.
+type vcsRemoteError struct {
+	error
+}
+
+func (e vcsRemoteError) Cause() error {
+	return e.error
+}
+
+// transientVCSOutputRE matches what vcs commands print when they fail for
+// reasons that are likely to go away by themselves.
+var transientVCSOutputRE = regexp.MustCompile(`(?i)connection (reset|refused|timed out)|timed? ?out|early eof|remote end hung up unexpectedly|rpc failed|transfer closed|network is unreachable|error: 5\d\d|\b5\d\d (internal server error|bad gateway|service unavailable|gateway time-?out)`)
+
+// transient reports whether the failure looks transient from the output of
+// the command.
+func (e vcsRemoteError) transient() bool {
+	return transientVCSOutputRE.MatchString(e.Error())
+}
+
+// httpStatusError is an HTTP response with an unexpected status.
+type httpStatusError struct {
+	url    string
+	code   int
+	status string
+}
+
+func (e httpStatusError) Error() string {
+	return fmt.Sprintf("failed HTTP request to URL %q: %s", e.url, e.status)
+}
+
+// retryClassOf returns the class of err, or 0 if it is in none. The classes
+// of errors wrapped by github.com/pkg/errors are found through their causes.
+func retryClassOf(err error) RetryClass {
+	for err != nil {
+		switch t := err.(type) {
+		case vcsRemoteError:
+			if !t.transient() {
+				return 0
+			}
+			return RetryVCSRemote
+		case *url.Error:
+			return RetryNetwork
+		case httpStatusError:
+			switch {
+			case t.code == http.StatusTooManyRequests:
+				return RetryHTTPTooManyRequests
+			case t.code >= 500:
+				return RetryHTTPServerError
+			case t.code >= 400:
+				return RetryHTTPClientError
+			}
+			return 0
+		}
+
+		c, ok := err.(interface {
+			Cause() error
+		})
+		if !ok {
+			return 0
+		}
+		err = c.Cause()
+	}
+	return 0
+}
+
+// retries reports whether err is to be retried.
+func (p RetryPolicy) retries(err error) bool {
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return false
+	}
+	return p.RetryOn&retryClassOf(err) != 0
+}
+
+// run calls f until it succeeds, fails in a way that p does not retry, or has
+// been attempted p.MaxAttempts times, waiting longer before each attempt.
+// retry is called before each wait, with the failure and the number of
+// attempts so far.
+func (p RetryPolicy) run(ctx context.Context, f func(context.Context) error, retry func(err error, attempts int, delay time.Duration)) error {
+	delay := p.Backoff
+	err := f(ctx)
+	for attempts := 1; err != nil && attempts < p.MaxAttempts && p.retries(err); attempts++ {
+		if ctx.Err() != nil {
+			return err
+		}
+		retry(err, attempts, delay)
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+
+		err = f(ctx)
+		delay *= 2
+		if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+			delay = p.MaxBackoff
+		}
+	}
+	return err
+}
@@ -0,0 +1,158 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/vcs"
+	"github.com/pkg/errors"
+)
+
+func TestRetryClassOf(t *testing.T) {
+	cases := []struct {
+		err  error
+		want RetryClass
+	}{
+		{unwrapVcsErr(vcs.NewRemoteError("unable to update repository", errors.New("exit status 128"), "fatal: unable to access 'https://github.com/foo/bar/': The requested URL returned error: 502")), RetryVCSRemote},
+		{errors.Wrap(unwrapVcsErr(vcs.NewRemoteError("unable to get repository", nil, "error: RPC failed; curl 56 Recv failure: Connection reset by peer")), "failed to fetch source"), RetryVCSRemote},
+		{vcsRemoteError{errors.New("ssh: connect to host github.com port 22: Connection timed out")}, RetryVCSRemote},
+		{unwrapVcsErr(vcs.NewRemoteError("unable to get repository", errors.New("exit status 128"), "remote: Repository not found.\nfatal: repository 'https://github.com/foo/bar/' not found")), 0},
+		{unwrapVcsErr(vcs.NewRemoteError("unable to get repository", errors.New("exit status 128"), "fatal: Authentication failed for 'https://github.com/foo/bar/'")), 0},
+		{vcsRemoteError{errors.New("git@github.com: Permission denied (publickey).")}, 0},
+		{unwrapVcsErr(vcs.NewLocalError("unable to update checked out version", nil, "")), 0},
+		{errors.Wrap(httpStatusError{code: http.StatusBadGateway}, "unable to fetch raw metadata"), RetryHTTPServerError},
+		{httpStatusError{code: http.StatusTooManyRequests}, RetryHTTPTooManyRequests},
+		{httpStatusError{code: http.StatusNotFound}, RetryHTTPClientError},
+		{httpStatusError{code: http.StatusMovedPermanently}, 0},
+		{errors.New("go-import metadata not found"), 0},
+		{context.Canceled, 0},
+	}
+	for _, c := range cases {
+		if got := retryClassOf(c.err); got != c.want {
+			t.Errorf("retryClassOf(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+
+	// Network failures are recognized as what http.Client.Do returns.
+	_, err := http.Get("http://127.0.0.1:0/")
+	if got := retryClassOf(errors.Wrap(err, "failed HTTP request")); got != RetryNetwork {
+		t.Errorf("expected a failed request to be a network failure, got %v", got)
+	}
+}
+
+func TestRetryPolicyRun(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		RetryOn:     RetryVCSRemote,
+	}
+	transient := vcsRemoteError{errors.New("connection reset by peer")}
+
+	failing := func(errs ...error) (func(context.Context) error, *int) {
+		var calls int
+		return func(context.Context) error {
+			calls++
+			if calls <= len(errs) {
+				return errs[calls-1]
+			}
+			return nil
+		}, &calls
+	}
+
+	var delays []time.Duration
+	record := func(err error, attempts int, delay time.Duration) {
+		delays = append(delays, delay)
+	}
+
+	f, calls := failing(transient, transient)
+	if err := policy.run(context.Background(), f, record); err != nil {
+		t.Fatalf("expected success on the third attempt, got %v", err)
+	}
+	if *calls != 3 || len(delays) != 2 || delays[0] != time.Millisecond || delays[1] != 2*time.Millisecond {
+		t.Fatalf("unexpected %d calls with delays %v", *calls, delays)
+	}
+
+	f, calls = failing(transient, transient, transient)
+	if err := policy.run(context.Background(), f, record); err != transient {
+		t.Fatalf("expected the last failure after running out of attempts, got %v", err)
+	}
+	if *calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", *calls)
+	}
+
+	permanent := errors.New("repository not found")
+	f, calls = failing(permanent)
+	if err := policy.run(context.Background(), f, record); err != permanent || *calls != 1 {
+		t.Fatalf("expected a single attempt failing with %v, got %d failing with %v", permanent, *calls, err)
+	}
+
+	f, calls = failing(transient)
+	if err := (RetryPolicy{}).run(context.Background(), f, record); err != transient || *calls != 1 {
+		t.Fatalf("expected the zero policy not to retry, got %d attempts failing with %v", *calls, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	policy.Backoff = time.Hour
+	f, calls = failing(transient, transient)
+	go cancel()
+	if err := policy.run(ctx, f, record); err != transient || *calls != 1 {
+		t.Fatalf("expected canceling to stop retrying, got %d attempts failing with %v", *calls, err)
+	}
+}
+
+func TestSupervisorRetriesHTTPMetadata(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `<meta name="go-import" content="%s/vanity git https://git.example.com/vanity">`, r.Host)
+	}))
+	defer ts.Close()
+	path := strings.TrimPrefix(ts.URL, "http://") + "/vanity/pkg"
+
+	var buf bytes.Buffer
+	superv := newSupervisor(context.Background())
+	superv.retry = RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		RetryOn:     RetryHTTPServerError,
+		Logger:      log.New(&buf, "", 0),
+	}
+
+	var root string
+	err := superv.do(context.Background(), path, ctHTTPMetadata, func(ctx context.Context) error {
+		var err error
+		root, _, _, err = getMetadata(ctx, path, "http")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.TrimSuffix(path, "/pkg"); root != want {
+		t.Fatalf("unexpected root %s, want %s", root, want)
+	}
+
+	if superv.ran[ctHTTPMetadata].retries != 2 {
+		t.Fatalf("expected 2 retries to be counted, got %d", superv.ran[ctHTTPMetadata].retries)
+	}
+	if n := strings.Count(buf.String(), "503 Service Unavailable"); n != 2 {
+		t.Fatalf("expected both retries to be logged, got:\n%s", buf.String())
+	}
+	if superv.ran[ctHTTPMetadata].count != 1 {
+		t.Fatalf("expected the retried call to be counted once, got %d", superv.ran[ctHTTPMetadata].count)
+	}
+}
@@ -43,9 +43,14 @@ type rootdata struct {
 	// A defensively copied instance of the root lock.
 	rl safeLock
 
-	// A defensively copied instance of params.RootPackageTree
+	// A defensively copied instance of params.RootPackageTree, limited to the
+	// imports made on the root's platforms.
 	rpt pkgtree.PackageTree
 
+	// The platforms declared by the root manifest. Imports not made on any of
+	// them are disregarded, in the root and in its dependencies.
+	plats []pkgtree.Platform
+
 	// The ProjectAnalyzer to use for all GetManifestAndLock calls.
 	an ProjectAnalyzer
 }
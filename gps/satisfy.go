@@ -22,7 +22,7 @@ func (s *solver) check(a atomWithPackages, pkgonly bool) error {
 	var err error
 	defer func() {
 		if err != nil {
-			s.traceInfo(err)
+			s.traceFailure(a, pkgonly, err)
 		}
 		s.mtr.pop()
 	}()
@@ -0,0 +1,240 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+)
+
+// solutionBucket is the top-level bucket of the bolt cache holding memoized
+// solutions. Like deductionBucket, it cannot be the name of a source.
+var solutionBucket = []byte("solutions")
+
+// solutionVersionsKey is the key of the bucket holding the digests of the
+// version lists of the projects in a memoized solution.
+var solutionVersionsKey = []byte("versions")
+
+// solutionMemoizer is implemented by SourceManagers that can persist the
+// solutions found by solvers, for later solvers given the same inputs to
+// reuse without solving again.
+type solutionMemoizer interface {
+	// memoizedSolution returns the projects of the solution memoized for key,
+	// if there is one and the versions of those projects have not changed
+	// since it was found.
+	memoizedSolution(ctx context.Context, key []byte) ([]LockedProject, bool)
+	// memoizeSolution persists the solution l for key.
+	memoizeSolution(ctx context.Context, key []byte, l Lock)
+}
+
+// solutionCache persists solutions in the bolt cache, along with the state of
+// the versions of the projects in them. Its methods are safe for concurrent
+// use, and do nothing on a nil *solutionCache.
+//
+// Implementation:
+//
+//	Bucket: "solutions"
+//	Sub-Bucket: "<hex key>"
+//	Keys/Values: the lock, as stored by cachePutLock
+//	Sub-Bucket: "versions"
+//	Keys/Values: "<root>": the digest of its version list
+type solutionCache struct {
+	*boltCache
+}
+
+// newSolutionCache returns a solutionCache backed by c.
+func (c *boltCache) newSolutionCache() *solutionCache {
+	return &solutionCache{boltCache: c}
+}
+
+// get returns the projects of the solution stored for key, and the digests of
+// the version lists of those projects when it was stored.
+func (c *solutionCache) get(key []byte) ([]LockedProject, map[ProjectRoot][]byte, bool) {
+	if c == nil {
+		return nil, nil, false
+	}
+
+	var l *safeLock
+	digests := make(map[ProjectRoot][]byte)
+	err := c.db.View(func(tx *bolt.Tx) error {
+		sb := tx.Bucket(solutionBucket)
+		if sb == nil {
+			return nil
+		}
+		b := sb.Bucket([]byte(hex.EncodeToString(key)))
+		if b == nil {
+			return nil
+		}
+		vb := b.Bucket(solutionVersionsKey)
+		if vb == nil {
+			return nil
+		}
+		err := vb.ForEach(func(k, v []byte) error {
+			digests[ProjectRoot(k)] = append([]byte(nil), v...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		l, err = cacheGetLock(b)
+		return err
+	})
+	if err != nil {
+		c.logger.Println(errors.Wrapf(err, "failed to get memoized solution %x from cache", key))
+		return nil, nil, false
+	}
+	if l == nil {
+		return nil, nil, false
+	}
+	return l.p, digests, true
+}
+
+// put stores the solution l for key, along with the digests of the version
+// lists of its projects.
+func (c *solutionCache) put(key []byte, l Lock, digests map[ProjectRoot][]byte) {
+	if c == nil {
+		return
+	}
+
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		sb, err := tx.CreateBucketIfNotExists(solutionBucket)
+		if err != nil {
+			return err
+		}
+		name := []byte(hex.EncodeToString(key))
+		if sb.Bucket(name) != nil {
+			if err := sb.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		b, err := sb.CreateBucket(name)
+		if err != nil {
+			return err
+		}
+		if err := cachePutLock(b, l); err != nil {
+			return err
+		}
+		vb, err := b.CreateBucket(solutionVersionsKey)
+		if err != nil {
+			return err
+		}
+		for pr, digest := range digests {
+			if err := vb.Put([]byte(pr), digest); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.logger.Println(errors.Wrapf(err, "failed to memoize solution %x", key))
+	}
+}
+
+// versionsDigest returns a digest of the versions listed for a project,
+// which changes when a version is added, removed or moved to a different
+// revision.
+func versionsDigest(vl []PairedVersion) []byte {
+	lines := make([]string, len(vl))
+	for i, v := range vl {
+		lines[i] = fmt.Sprintf("%d %s %s", v.Type(), v, v.Revision())
+	}
+	sort.Strings(lines)
+
+	h := sha256.New()
+	for _, line := range lines {
+		fmt.Fprintln(h, line)
+	}
+	return h.Sum(nil)
+}
+
+// projectVersionsDigest lists the versions of the project id, and returns
+// their digest.
+func (sm *SourceMgr) projectVersionsDigest(ctx context.Context, id ProjectIdentifier) ([]byte, error) {
+	srcg, err := sm.srcCoord.getSourceGatewayFor(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	vl, err := srcg.listVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return versionsDigest(vl), nil
+}
+
+func (sm *SourceMgr) memoizedSolution(ctx context.Context, key []byte) ([]LockedProject, bool) {
+	if sm.solutions == nil {
+		return nil, false
+	}
+	projects, digests, ok := sm.solutions.get(key)
+	if !ok || len(digests) != len(projects) {
+		return nil, false
+	}
+
+	// The solution still holds if none of the projects in it has had its
+	// versions change since, as the solver would only find it again.
+	for _, lp := range projects {
+		digest, err := sm.projectVersionsDigest(ctx, lp.Ident())
+		if err != nil || !bytes.Equal(digest, digests[lp.Ident().ProjectRoot]) {
+			return nil, false
+		}
+	}
+	return projects, true
+}
+
+func (sm *SourceMgr) memoizeSolution(ctx context.Context, key []byte, l Lock) {
+	if sm.solutions == nil {
+		return
+	}
+
+	projects := l.Projects()
+	digests := make(map[ProjectRoot][]byte, len(projects))
+	for _, lp := range projects {
+		digest, err := sm.projectVersionsDigest(ctx, lp.Ident())
+		if err != nil {
+			return
+		}
+		digests[lp.Ident().ProjectRoot] = digest
+	}
+	sm.solutions.put(key, l, digests)
+}
+
+// memoKey returns the key of the solver's solution among memoized ones. It
+// adds to HashInputs what else decides the solution: the version strategy,
+// which projects may change, and the versions they are locked to if not.
+func (s *solver) memoKey() []byte {
+	h := sha256.New()
+	h.Write(s.HashInputs())
+	fmt.Fprintf(h, "strategy %s\n", s.strategy)
+	if s.rd.chngall {
+		fmt.Fprintln(h, "change all")
+		return h.Sum(nil)
+	}
+
+	chng := make([]string, 0, len(s.rd.chng))
+	for pr := range s.rd.chng {
+		chng = append(chng, string(pr))
+	}
+	sort.Strings(chng)
+	for _, pr := range chng {
+		fmt.Fprintf(h, "change %s\n", pr)
+	}
+
+	locked := make([]LockedProject, len(s.rd.rl.p))
+	copy(locked, s.rd.rl.p)
+	sort.Slice(locked, func(i, j int) bool {
+		return locked[i].pi.ProjectRoot < locked[j].pi.ProjectRoot
+	})
+	for _, lp := range locked {
+		fmt.Fprintf(h, "locked %s %s %v %s\n", lp.pi.ProjectRoot, lp.pi.Source, lp.v, lp.r)
+	}
+	return h.Sum(nil)
+}
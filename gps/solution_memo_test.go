@@ -0,0 +1,105 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/golang/dep/gps/pkgtree"
+	"github.com/golang/dep/internal/test"
+)
+
+func TestMemoizedSolution(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("root")
+	h.TempDir("smcache")
+	h.TempFile("dep/dep.go", "package dep\n")
+
+	cfg := SourceManagerConfig{
+		Cachedir:         h.Path("smcache"),
+		Logger:           log.New(test.Writer{TB: t}, "", 0),
+		MemoizeSolutions: true,
+	}
+	params := SolveParameters{
+		RootDir: h.Path("root"),
+		RootPackageTree: pkgtree.PackageTree{
+			ImportRoot: "example.com/root",
+			Packages: map[string]pkgtree.PackageOrErr{
+				"example.com/root": {
+					P: pkgtree.Package{
+						ImportPath: "example.com/root",
+						Name:       "root",
+						Imports:    []string{"github.com/foo/dep"},
+					},
+				},
+			},
+		},
+		Manifest: simpleRootManifest{
+			c: ProjectConstraints{
+				"github.com/foo/dep": ProjectProperties{Source: h.Path("dep"), Constraint: Any()},
+			},
+		},
+		ProjectAnalyzer: naiveAnalyzer{},
+		ChangeAll:       true,
+	}
+
+	// solve runs a solver with a new SourceMgr, and reports whether it
+	// reused a memoized solution.
+	solve := func() (Solution, bool) {
+		sm, err := NewSourceManager(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sm.Release()
+
+		var buf bytes.Buffer
+		params.TraceLogger = log.New(&buf, "", 0)
+		s, err := Prepare(params, sm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		soln, err := s.Solve(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return soln, strings.Contains(buf.String(), "reusing memoized solution")
+	}
+
+	first, memoized := solve()
+	if memoized {
+		t.Fatal("expected the first solve not to find a memoized solution")
+	}
+	second, memoized := solve()
+	if !memoized {
+		t.Fatal("expected the second solve to reuse the first solution")
+	}
+	if !LocksAreEq(first, second, true) {
+		t.Fatalf("expected the memoized solution to be the same:\n\t(GOT): %v\n\t(WNT): %v", second.Projects(), first.Projects())
+	}
+	if second.Attempts() != 0 || !bytes.Equal(second.InputsDigest(), first.InputsDigest()) {
+		t.Fatalf("unexpected memoized solution with %d attempts and digest %x", second.Attempts(), second.InputsDigest())
+	}
+
+	// A new version of the dependency invalidates the solution.
+	h.TempFile("dep/new.go", "package dep\n")
+	third, memoized := solve()
+	if memoized {
+		t.Fatal("expected the solution to be found again after the dependency changed")
+	}
+	if third.Projects()[0].Version() == first.Projects()[0].Version() {
+		t.Fatalf("expected a new revision of the dependency, got %s", third.Projects()[0].Version())
+	}
+
+	// Other inputs lead to other solutions.
+	params.ChangeAll = false
+	if _, memoized := solve(); memoized {
+		t.Fatal("expected solving without changing all projects not to reuse the solution")
+	}
+}
@@ -391,6 +391,8 @@ type basicFixture struct {
 	maxAttempts int
 	// Use downgrade instead of default upgrade sorter
 	downgrade bool
+	// version strategy to use, if not the default
+	strategy VersionStrategy
 	// lock file simulator, if one's to be used at all
 	l fixLock
 	// solve failure expected, if any
@@ -519,6 +521,43 @@ var basicFixtures = map[string]basicFixture{
 		),
 		downgrade: true,
 	},
+	"minimal on overlapping constraints": {
+		ds: []depspec{
+			mkDepspec("root 0.0.0", "a *", "b >=1.1.0"),
+			mkDepspec("a 1.0.0", "shared >=2.0.0, <=4.0.0"),
+			mkDepspec("a 1.1.0", "shared >=2.0.0, <=4.0.0"),
+			mkDepspec("b 1.0.0", "shared >=3.0.0, <5.0.0"),
+			mkDepspec("b 1.1.0", "shared >=3.0.0, <5.0.0"),
+			mkDepspec("b 1.2.0", "shared >=3.0.0, <5.0.0"),
+			mkDepspec("shared 2.0.0"),
+			mkDepspec("shared 3.0.0"),
+			mkDepspec("shared 3.6.9"),
+			mkDepspec("shared 4.0.0"),
+			mkDepspec("shared 5.0.0"),
+		},
+		r: mksolution(
+			"a 1.0.0",
+			"b 1.1.0",
+			"shared 3.0.0",
+		),
+		strategy: MinimalStrategy,
+	},
+	"minimal backtracks on conflicting constraints": {
+		ds: []depspec{
+			mkDepspec("root 0.0.0", "foo *", "bar *"),
+			mkDepspec("foo 1.0.0", "baz 1.0.0"),
+			mkDepspec("foo 1.1.0", "baz 2.0.0"),
+			mkDepspec("bar 1.0.0", "baz 2.0.0"),
+			mkDepspec("baz 1.0.0"),
+			mkDepspec("baz 2.0.0"),
+		},
+		r: mksolution(
+			"foo 1.1.0",
+			"bar 1.0.0",
+			"baz 2.0.0",
+		),
+		strategy: MinimalStrategy,
+	},
 	"shared dependency where dependent version in turn affects other dependencies": {
 		ds: []depspec{
 			mkDepspec("root 0.0.0", "foo <=1.0.2", "bar 1.0.0"),
@@ -610,6 +649,27 @@ var basicFixtures = map[string]basicFixture{
 		changeall: true,
 		downgrade: true,
 	},
+	"minimal through lock": {
+		ds: []depspec{
+			mkDepspec("root 0.0.0", "foo *"),
+			mkDepspec("foo 1.0.0", "bar >=1.0.0"),
+			mkDepspec("foo 1.0.1", "bar >=1.0.1"),
+			mkDepspec("foo 1.0.2", "bar >=1.0.2"),
+			mkDepspec("bar 1.0.0"),
+			mkDepspec("bar 1.0.1"),
+			mkDepspec("bar 1.0.2"),
+		},
+		l: mklock(
+			"foo 1.0.1",
+			"bar 1.0.2",
+		),
+		r: mksolution(
+			"foo 1.0.0",
+			"bar 1.0.0",
+		),
+		changeall: true,
+		strategy:  MinimalStrategy,
+	},
 	"update one with only one": {
 		ds: []depspec{
 			mkDepspec("root 0.0.0", "foo *"),
@@ -608,6 +608,32 @@ var bimodalFixtures = map[string]bimodalFixture{
 			"b 1.0.0 foorev",
 		),
 	},
+	// The minimal strategy disregards preferred versions from deps' locks, and
+	// selects the lowest allowed version instead
+	"minimal strategy ignores dep prefv": {
+		ds: []depspec{
+			dsp(mkDepspec("root 0.0.0"),
+				pkg("root", "a")),
+			dsp(mkDepspec("a 1.0.0", "b >=1.0.0"),
+				pkg("a", "b")),
+			dsp(mkDepspec("b 1.0.0 foorev"),
+				pkg("b")),
+			dsp(mkDepspec("b 1.1.0 bazrev"),
+				pkg("b")),
+			dsp(mkDepspec("b 2.0.0 barrev"),
+				pkg("b")),
+		},
+		lm: map[string]fixLock{
+			"a 1.0.0": mklock(
+				"b 1.1.0 bazrev",
+			),
+		},
+		r: mksolution(
+			"a 1.0.0",
+			"b 1.0.0 foorev",
+		),
+		strategy: MinimalStrategy,
+	},
 	// Preferred version, as derived from a dep's lock, is attempted first, even
 	// if the root also has a direct dep on it (root doesn't need to use
 	// preferreds, because it has direct control AND because the root lock
@@ -1305,6 +1331,8 @@ type bimodalFixture struct {
 	maxAttempts int
 	// Use downgrade instead of default upgrade sorter
 	downgrade bool
+	// version strategy to use, if not the default
+	strategy VersionStrategy
 	// lock file simulator, if one's to be used at all
 	l fixLock
 	// map of locks for deps, if any. keys should be of the form:
@@ -29,17 +29,7 @@ type noVersionError struct {
 }
 
 func (e *noVersionError) Error() string {
-	if len(e.fails) == 0 {
-		return fmt.Sprintf("No versions found for project %q.", e.pn.ProjectRoot)
-	}
-
-	var buf bytes.Buffer
-	fmt.Fprintf(&buf, "No versions of %s met constraints:", e.pn.ProjectRoot)
-	for _, f := range e.fails {
-		fmt.Fprintf(&buf, "\n\t%s: %s", f.v, f.f.Error())
-	}
-
-	return buf.String()
+	return exportFailure(e).Error()
 }
 
 func (e *noVersionError) traceString() string {
@@ -169,31 +159,7 @@ type disjointConstraintFailure struct {
 }
 
 func (e *disjointConstraintFailure) Error() string {
-	if len(e.failsib) == 1 {
-		str := "Could not introduce %s, as it has a dependency on %s with constraint %s, which has no overlap with existing constraint %s from %s"
-		return fmt.Sprintf(str, a2vs(e.goal.depender), e.goal.dep.Ident, e.goal.dep.Constraint.String(), e.failsib[0].dep.Constraint.String(), a2vs(e.failsib[0].depender))
-	}
-
-	var buf bytes.Buffer
-
-	var sibs []dependency
-	if len(e.failsib) > 1 {
-		sibs = e.failsib
-
-		str := "Could not introduce %s, as it has a dependency on %s with constraint %s, which has no overlap with the following existing constraints:\n"
-		fmt.Fprintf(&buf, str, a2vs(e.goal.depender), e.goal.dep.Ident, e.goal.dep.Constraint.String())
-	} else {
-		sibs = e.nofailsib
-
-		str := "Could not introduce %s, as it has a dependency on %s with constraint %s, which does not overlap with the intersection of existing constraints from other currently selected packages:\n"
-		fmt.Fprintf(&buf, str, a2vs(e.goal.depender), e.goal.dep.Ident, e.goal.dep.Constraint.String())
-	}
-
-	for _, c := range sibs {
-		fmt.Fprintf(&buf, "\t%s from %s\n", c.dep.Constraint.String(), a2vs(c.depender))
-	}
-
-	return buf.String()
+	return exportFailure(e).Error()
 }
 
 func (e *disjointConstraintFailure) traceString() string {
@@ -232,13 +198,7 @@ type constraintNotAllowedFailure struct {
 }
 
 func (e *constraintNotAllowedFailure) Error() string {
-	return fmt.Sprintf(
-		"Could not introduce %s, as it has a dependency on %s with constraint %s, which does not allow the currently selected version of %s",
-		a2vs(e.goal.depender),
-		e.goal.dep.Ident,
-		e.goal.dep.Constraint,
-		e.v,
-	)
+	return exportFailure(e).Error()
 }
 
 func (e *constraintNotAllowedFailure) traceString() string {
@@ -269,24 +229,7 @@ type versionNotAllowedFailure struct {
 }
 
 func (e *versionNotAllowedFailure) Error() string {
-	if len(e.failparent) == 1 {
-		return fmt.Sprintf(
-			"Could not introduce %s, as it is not allowed by constraint %s from project %s.",
-			a2vs(e.goal),
-			e.failparent[0].dep.Constraint.String(),
-			e.failparent[0].depender.id,
-		)
-	}
-
-	var buf bytes.Buffer
-
-	fmt.Fprintf(&buf, "Could not introduce %s, as it is not allowed by constraints from the following projects:\n", a2vs(e.goal))
-
-	for _, f := range e.failparent {
-		fmt.Fprintf(&buf, "\t%s from %s\n", f.dep.Constraint.String(), a2vs(f.depender))
-	}
-
-	return buf.String()
+	return exportFailure(e).Error()
 }
 
 func (e *versionNotAllowedFailure) traceString() string {
@@ -375,50 +318,7 @@ type checkeeHasProblemPackagesFailure struct {
 }
 
 func (e *checkeeHasProblemPackagesFailure) Error() string {
-	var buf bytes.Buffer
-	indent := ""
-
-	if len(e.failpkg) > 1 {
-		indent = "\t"
-		fmt.Fprintf(
-			&buf, "Could not introduce %s due to multiple problematic subpackages:\n",
-			a2vs(e.goal),
-		)
-	}
-
-	for pkg, errdep := range e.failpkg {
-		var cause string
-		if errdep.err == nil {
-			cause = "is missing"
-		} else {
-			cause = fmt.Sprintf("does not contain usable Go code (%T).", errdep.err)
-		}
-
-		if len(e.failpkg) == 1 {
-			fmt.Fprintf(
-				&buf, "Could not introduce %s, as its subpackage %s %s.",
-				a2vs(e.goal),
-				pkg,
-				cause,
-			)
-		} else {
-			fmt.Fprintf(&buf, "\tSubpackage %s %s.", pkg, cause)
-		}
-
-		if len(errdep.deppers) == 1 {
-			fmt.Fprintf(
-				&buf, " (Package is required by %s.)",
-				a2vs(errdep.deppers[0]),
-			)
-		} else {
-			fmt.Fprintf(&buf, " Package is required by:")
-			for _, pa := range errdep.deppers {
-				fmt.Fprintf(&buf, "\n%s\t%s", indent, a2vs(pa))
-			}
-		}
-	}
-
-	return buf.String()
+	return exportFailure(e).Error()
 }
 
 func (e *checkeeHasProblemPackagesFailure) traceString() string {
@@ -0,0 +1,328 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// The types in this file are the structured forms of the failures the solver
+// encounters. Solve returns them in place of the solver's internal failure
+// types, so that callers can inspect exactly what went wrong.
+
+// FailureAtom identifies a project at a particular version that was involved
+// in a solve failure. The root project has a nil Version.
+type FailureAtom struct {
+	Project ProjectIdentifier
+	Version Version
+}
+
+// IsRoot indicates whether the atom represents the root project.
+func (a FailureAtom) IsRoot() bool {
+	return a.Version == nil
+}
+
+func (a FailureAtom) String() string {
+	if a.IsRoot() {
+		return "(root)"
+	}
+
+	return fmt.Sprintf("%s@%s", a.Project, a.Version)
+}
+
+// FailureConstraint is a constraint on a project that was involved in a solve
+// failure, along with the atom that introduced it.
+type FailureConstraint struct {
+	Constraint   Constraint
+	IntroducedBy FailureAtom
+}
+
+// FailedVersion is a version of a project that was tried, and rejected, during
+// solving.
+type FailedVersion struct {
+	Version Version
+	// Failure is the reason the version was rejected. Where possible, it is one
+	// of the structured failure types in this package.
+	Failure error
+}
+
+// NoVersionError indicates that none of the versions of a project that were
+// tried could be selected.
+type NoVersionError struct {
+	Project ProjectIdentifier
+	// Tried holds the versions that were tried, in the order they were tried.
+	Tried []FailedVersion
+}
+
+func (e *NoVersionError) Error() string {
+	if len(e.Tried) == 0 {
+		return fmt.Sprintf("No versions found for project %q.", e.Project.ProjectRoot)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "No versions of %s met constraints:", e.Project.ProjectRoot)
+	for _, f := range e.Tried {
+		fmt.Fprintf(&buf, "\n\t%s: %s", f.Version, f.Failure.Error())
+	}
+
+	return buf.String()
+}
+
+// DisjointConstraintFailure indicates that a project could not be introduced
+// because its constraint on one of its dependencies has no overlap with the
+// constraints on that dependency that are already in effect.
+type DisjointConstraintFailure struct {
+	// Depender is the atom that could not be introduced.
+	Depender FailureAtom
+	// Project is the dependency on which the constraints conflict.
+	Project ProjectIdentifier
+	// Constraint is Depender's constraint on Project.
+	Constraint Constraint
+	// Conflicting holds the constraints already in effect on Project that have
+	// no overlap with Constraint.
+	Conflicting []FailureConstraint
+	// Overlapping holds the constraints already in effect on Project that
+	// overlap with Constraint, even though their intersection does not.
+	Overlapping []FailureConstraint
+	// Current is the intersection of the constraints already in effect on
+	// Project.
+	Current Constraint
+}
+
+func (e *DisjointConstraintFailure) Error() string {
+	if len(e.Conflicting) == 1 {
+		str := "Could not introduce %s, as it has a dependency on %s with constraint %s, which has no overlap with existing constraint %s from %s"
+		return fmt.Sprintf(str, e.Depender, e.Project, e.Constraint.String(), e.Conflicting[0].Constraint.String(), e.Conflicting[0].IntroducedBy)
+	}
+
+	var buf bytes.Buffer
+
+	var sibs []FailureConstraint
+	if len(e.Conflicting) > 1 {
+		sibs = e.Conflicting
+
+		str := "Could not introduce %s, as it has a dependency on %s with constraint %s, which has no overlap with the following existing constraints:\n"
+		fmt.Fprintf(&buf, str, e.Depender, e.Project, e.Constraint.String())
+	} else {
+		sibs = e.Overlapping
+
+		str := "Could not introduce %s, as it has a dependency on %s with constraint %s, which does not overlap with the intersection of existing constraints from other currently selected packages:\n"
+		fmt.Fprintf(&buf, str, e.Depender, e.Project, e.Constraint.String())
+	}
+
+	for _, c := range sibs {
+		fmt.Fprintf(&buf, "\t%s from %s\n", c.Constraint.String(), c.IntroducedBy)
+	}
+
+	return buf.String()
+}
+
+// ConstraintNotAllowedFailure indicates that a project could not be introduced
+// because its constraint on one of its dependencies does not allow the version
+// of that dependency that is already selected.
+type ConstraintNotAllowedFailure struct {
+	// Depender is the atom that could not be introduced.
+	Depender FailureAtom
+	// Project is the dependency whose selected version is not allowed.
+	Project ProjectIdentifier
+	// Constraint is Depender's constraint on Project.
+	Constraint Constraint
+	// Selected is the version of Project that is already selected.
+	Selected Version
+}
+
+func (e *ConstraintNotAllowedFailure) Error() string {
+	return fmt.Sprintf(
+		"Could not introduce %s, as it has a dependency on %s with constraint %s, which does not allow the currently selected version of %s",
+		e.Depender,
+		e.Project,
+		e.Constraint,
+		e.Selected,
+	)
+}
+
+// VersionNotAllowedFailure indicates that a version of a project was rejected
+// because it is not allowed by the constraints already in effect on it.
+type VersionNotAllowedFailure struct {
+	// Rejected is the atom that was rejected.
+	Rejected FailureAtom
+	// Constraints holds the constraints in effect that do not allow the
+	// rejected version.
+	Constraints []FailureConstraint
+	// Current is the intersection of all the constraints in effect on the
+	// project.
+	Current Constraint
+}
+
+func (e *VersionNotAllowedFailure) Error() string {
+	if len(e.Constraints) == 1 {
+		return fmt.Sprintf(
+			"Could not introduce %s, as it is not allowed by constraint %s from project %s.",
+			e.Rejected,
+			e.Constraints[0].Constraint.String(),
+			e.Constraints[0].IntroducedBy.Project,
+		)
+	}
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "Could not introduce %s, as it is not allowed by constraints from the following projects:\n", e.Rejected)
+
+	for _, f := range e.Constraints {
+		fmt.Fprintf(&buf, "\t%s from %s\n", f.Constraint.String(), f.IntroducedBy)
+	}
+
+	return buf.String()
+}
+
+// ProblemPackage describes a package that is missing from, or contains errors
+// in, a version of a project.
+type ProblemPackage struct {
+	ImportPath string
+	// Err is the problem with the package, or nil if the package is missing.
+	Err error
+	// RequiredBy holds the selected atoms that require the package.
+	RequiredBy []FailureAtom
+}
+
+// CheckeeHasProblemPackagesFailure indicates that a version of a project was
+// rejected because packages required from it are missing or contain errors.
+type CheckeeHasProblemPackagesFailure struct {
+	// Rejected is the atom that was rejected.
+	Rejected FailureAtom
+	// Packages holds the problem packages, sorted by import path.
+	Packages []ProblemPackage
+}
+
+func (e *CheckeeHasProblemPackagesFailure) Error() string {
+	var buf bytes.Buffer
+	indent := ""
+
+	if len(e.Packages) > 1 {
+		indent = "\t"
+		fmt.Fprintf(
+			&buf, "Could not introduce %s due to multiple problematic subpackages:\n",
+			e.Rejected,
+		)
+	}
+
+	for _, pp := range e.Packages {
+		var cause string
+		if pp.Err == nil {
+			cause = "is missing"
+		} else {
+			cause = fmt.Sprintf("does not contain usable Go code (%T).", pp.Err)
+		}
+
+		if len(e.Packages) == 1 {
+			fmt.Fprintf(
+				&buf, "Could not introduce %s, as its subpackage %s %s.",
+				e.Rejected,
+				pp.ImportPath,
+				cause,
+			)
+		} else {
+			fmt.Fprintf(&buf, "\tSubpackage %s %s.", pp.ImportPath, cause)
+		}
+
+		if len(pp.RequiredBy) == 1 {
+			fmt.Fprintf(
+				&buf, " (Package is required by %s.)",
+				pp.RequiredBy[0],
+			)
+		} else {
+			fmt.Fprintf(&buf, " Package is required by:")
+			for _, a := range pp.RequiredBy {
+				fmt.Fprintf(&buf, "\n%s\t%s", indent, a)
+			}
+		}
+	}
+
+	return buf.String()
+}
+
+// exportFailure converts one of the solver's internal failures into its
+// structured form. Errors without a structured form are returned unchanged.
+func exportFailure(err error) error {
+	switch e := err.(type) {
+	case *noVersionError:
+		ne := &NoVersionError{
+			Project: e.pn,
+		}
+		for _, f := range e.fails {
+			ne.Tried = append(ne.Tried, FailedVersion{
+				Version: f.v,
+				Failure: exportFailure(f.f),
+			})
+		}
+		return ne
+	case *disjointConstraintFailure:
+		return &DisjointConstraintFailure{
+			Depender:    exportAtom(e.goal.depender),
+			Project:     e.goal.dep.Ident,
+			Constraint:  e.goal.dep.Constraint,
+			Conflicting: exportDependencies(e.failsib),
+			Overlapping: exportDependencies(e.nofailsib),
+			Current:     e.c,
+		}
+	case *constraintNotAllowedFailure:
+		return &ConstraintNotAllowedFailure{
+			Depender:   exportAtom(e.goal.depender),
+			Project:    e.goal.dep.Ident,
+			Constraint: e.goal.dep.Constraint,
+			Selected:   e.v,
+		}
+	case *versionNotAllowedFailure:
+		return &VersionNotAllowedFailure{
+			Rejected:    exportAtom(e.goal),
+			Constraints: exportDependencies(e.failparent),
+			Current:     e.c,
+		}
+	case *checkeeHasProblemPackagesFailure:
+		ce := &CheckeeHasProblemPackagesFailure{
+			Rejected: exportAtom(e.goal),
+		}
+		for pkg, errdep := range e.failpkg {
+			pp := ProblemPackage{
+				ImportPath: pkg,
+				Err:        errdep.err,
+			}
+			for _, a := range errdep.deppers {
+				pp.RequiredBy = append(pp.RequiredBy, exportAtom(a))
+			}
+			ce.Packages = append(ce.Packages, pp)
+		}
+		sort.Slice(ce.Packages, func(i, j int) bool {
+			return ce.Packages[i].ImportPath < ce.Packages[j].ImportPath
+		})
+		return ce
+	}
+
+	return err
+}
+
+func exportAtom(a atom) FailureAtom {
+	if a.v == rootRev || a.v == nil {
+		return FailureAtom{Project: a.id}
+	}
+	return FailureAtom{Project: a.id, Version: a.v}
+}
+
+func exportDependencies(deps []dependency) []FailureConstraint {
+	if len(deps) == 0 {
+		return nil
+	}
+
+	fcs := make([]FailureConstraint, len(deps))
+	for i, d := range deps {
+		fcs[i] = FailureConstraint{
+			Constraint:   d.dep.Constraint,
+			IntroducedBy: exportAtom(d.depender),
+		}
+	}
+	return fcs
+}
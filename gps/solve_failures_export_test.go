@@ -0,0 +1,142 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestExportFailure(t *testing.T) {
+	root := atom{id: mkPI("root"), v: rootRev}
+	rootDep := mkDep("root 0.0.0", "b 1.0.0", "b")
+	rootDep.depender = root
+
+	bad := errors.New("bad code")
+	fail := &noVersionError{
+		pn: mkPI("b"),
+		fails: []failedVersion{
+			{
+				v: NewVersion("2.0.0"),
+				f: &versionNotAllowedFailure{
+					goal:       mkAtom("b 2.0.0"),
+					failparent: []dependency{rootDep},
+					c:          mkSVC("1.0.0"),
+				},
+			},
+			{
+				v: NewVersion("1.0.0"),
+				f: &disjointConstraintFailure{
+					goal:      mkDep("b 1.0.0", "shared <=2.0.0", "shared"),
+					failsib:   []dependency{mkDep("c 1.0.0", "shared >3.0.0", "shared")},
+					nofailsib: []dependency{mkDep("d 1.0.0", "shared *", "shared")},
+					c:         mkSVC(">3.0.0"),
+				},
+			},
+			{
+				v: NewVersion("0.9.0"),
+				f: &checkeeHasProblemPackagesFailure{
+					goal: mkAtom("b 0.9.0"),
+					failpkg: map[string]errDeppers{
+						"b/foo": {deppers: []atom{root}},
+						"b/bar": {err: bad, deppers: []atom{mkAtom("c 1.0.0")}},
+					},
+				},
+			},
+			{
+				v: NewVersion("0.8.0"),
+				f: &constraintNotAllowedFailure{
+					goal: mkDep("b 0.8.0", "a 2.0.0", "a"),
+					v:    NewVersion("1.0.0"),
+				},
+			},
+			{
+				v: NewVersion("0.7.0"),
+				f: badOptsFailure("unexported failure"),
+			},
+		},
+	}
+
+	want := &NoVersionError{
+		Project: mkPI("b"),
+		Tried: []FailedVersion{
+			{
+				Version: NewVersion("2.0.0"),
+				Failure: &VersionNotAllowedFailure{
+					Rejected: FailureAtom{Project: mkPI("b"), Version: NewVersion("2.0.0")},
+					Constraints: []FailureConstraint{
+						{Constraint: mkSVC("1.0.0"), IntroducedBy: FailureAtom{Project: mkPI("root")}},
+					},
+					Current: mkSVC("1.0.0"),
+				},
+			},
+			{
+				Version: NewVersion("1.0.0"),
+				Failure: &DisjointConstraintFailure{
+					Depender:   FailureAtom{Project: mkPI("b"), Version: NewVersion("1.0.0")},
+					Project:    mkPI("shared"),
+					Constraint: mkSVC("<=2.0.0"),
+					Conflicting: []FailureConstraint{
+						{Constraint: mkSVC(">3.0.0"), IntroducedBy: FailureAtom{Project: mkPI("c"), Version: NewVersion("1.0.0")}},
+					},
+					Overlapping: []FailureConstraint{
+						{Constraint: mkSVC("*"), IntroducedBy: FailureAtom{Project: mkPI("d"), Version: NewVersion("1.0.0")}},
+					},
+					Current: mkSVC(">3.0.0"),
+				},
+			},
+			{
+				Version: NewVersion("0.9.0"),
+				Failure: &CheckeeHasProblemPackagesFailure{
+					Rejected: FailureAtom{Project: mkPI("b"), Version: NewVersion("0.9.0")},
+					Packages: []ProblemPackage{
+						{ImportPath: "b/bar", Err: bad, RequiredBy: []FailureAtom{{Project: mkPI("c"), Version: NewVersion("1.0.0")}}},
+						{ImportPath: "b/foo", RequiredBy: []FailureAtom{{Project: mkPI("root")}}},
+					},
+				},
+			},
+			{
+				Version: NewVersion("0.8.0"),
+				Failure: &ConstraintNotAllowedFailure{
+					Depender:   FailureAtom{Project: mkPI("b"), Version: NewVersion("0.8.0")},
+					Project:    mkPI("a"),
+					Constraint: mkSVC("2.0.0"),
+					Selected:   NewVersion("1.0.0"),
+				},
+			},
+			{
+				Version: NewVersion("0.7.0"),
+				Failure: badOptsFailure("unexported failure"),
+			},
+		},
+	}
+
+	got, ok := exportFailure(fail).(*NoVersionError)
+	if !ok {
+		t.Fatalf("expected a *NoVersionError, got %T", exportFailure(fail))
+	}
+	if got.Project != want.Project || len(got.Tried) != len(want.Tried) {
+		t.Fatalf("unexpected export:\n\t(GOT): %#v\n\t(WNT): %#v", got, want)
+	}
+	for i := range want.Tried {
+		if !reflect.DeepEqual(got.Tried[i], want.Tried[i]) {
+			t.Errorf("unexpected export of failure for %s:\n\t(GOT): %#v\n\t(WNT): %#v", want.Tried[i].Version, got.Tried[i].Failure, want.Tried[i].Failure)
+		}
+	}
+
+	// The message must not depend on the order in which the problem packages
+	// were visited.
+	wantMsg := `No versions of b met constraints:
+	2.0.0: Could not introduce b@2.0.0, as it is not allowed by constraint 1.0.0 from project root.
+	1.0.0: Could not introduce b@1.0.0, as it has a dependency on shared with constraint <=2.0.0, which has no overlap with existing constraint >3.0.0 from c@1.0.0
+	0.9.0: Could not introduce b@0.9.0 due to multiple problematic subpackages:
+	Subpackage b/bar does not contain usable Go code (*errors.errorString).. (Package is required by c@1.0.0.)	Subpackage b/foo is missing. (Package is required by (root).)
+	0.8.0: Could not introduce b@0.8.0, as it has a dependency on a with constraint 2.0.0, which does not allow the currently selected version of 1.0.0
+	0.7.0: unexported failure`
+	if fail.Error() != wantMsg {
+		t.Fatalf("unexpected message:\n\t(GOT): %q\n\t(WNT): %q", fail.Error(), wantMsg)
+	}
+}
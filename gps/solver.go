@@ -90,13 +90,25 @@ type SolveParameters struct {
 	// Upgrading is, by far, the most typical case. The field is named
 	// 'Downgrade' so that the bool's zero value corresponds to that most
 	// typical case.
+	//
+	// Setting Downgrade is equivalent to setting Strategy to
+	// DowngradeStrategy; it may not be combined with any other Strategy.
 	Downgrade bool
 
+	// Strategy determines which versions the solver prefers for projects that
+	// are not locked, or are marked for change. The zero value is
+	// UpgradeStrategy.
+	Strategy VersionStrategy
+
 	// TraceLogger is the logger to use for generating trace output. If set, the
 	// solver will generate informative trace output as it moves through the
 	// solving process.
 	TraceLogger *log.Logger
 
+	// TraceSink, if set, receives structured events describing the progress
+	// of the solving process, independently of TraceLogger.
+	TraceSink TraceSink
+
 	// stdLibFn is the function to use to recognize standard library import paths.
 	// Only overridden for tests. Defaults to paths.IsStandardImportPath if nil.
 	stdLibFn func(string) bool
@@ -107,6 +119,38 @@ type SolveParameters struct {
 	mkBridgeFn func(*solver, SourceManager, bool) sourceBridge
 }
 
+// VersionStrategy determines which versions of a project the solver tries
+// first.
+type VersionStrategy int
+
+const (
+	// UpgradeStrategy prefers the newest versions of projects.
+	UpgradeStrategy VersionStrategy = iota
+
+	// DowngradeStrategy prefers the oldest versions of projects.
+	DowngradeStrategy
+
+	// MinimalStrategy selects, for each project, the lowest version allowed by
+	// all the constraints on it, backtracking only when constraints conflict.
+	// Unlike DowngradeStrategy, versions preferred by the locks of
+	// dependencies are disregarded, so that the solution depends only on the
+	// constraints in play.
+	MinimalStrategy
+)
+
+func (vs VersionStrategy) String() string {
+	switch vs {
+	case UpgradeStrategy:
+		return "upgrade"
+	case DowngradeStrategy:
+		return "downgrade"
+	case MinimalStrategy:
+		return "minimal"
+	default:
+		return fmt.Sprintf("VersionStrategy(%d)", int(vs))
+	}
+}
+
 // solver is a CDCL-style constraint solver with satisfiability conditions
 // hardcoded to the needs of the Go package management problem space.
 type solver struct {
@@ -118,6 +162,12 @@ type solver struct {
 	// Logger used exclusively for trace output, or nil to suppress.
 	tl *log.Logger
 
+	// Sink for structured trace events, or nil to suppress.
+	ts TraceSink
+
+	// The strategy determining which versions are tried first.
+	strategy VersionStrategy
+
 	// The function to use to recognize standard library import paths.
 	stdLibFn func(string) bool
 
@@ -127,6 +177,9 @@ type solver struct {
 	// names a SourceManager operates on.
 	b sourceBridge
 
+	// The SourceManager, if it memoizes solutions, or nil.
+	memo solutionMemoizer
+
 	// A versionUnifier, to facilitate cross-type version comparison and set
 	// operations.
 	vUnify *versionUnifier
@@ -190,11 +243,19 @@ func (params SolveParameters) toRootdata() (rootdata, error) {
 		params.Manifest = simpleRootManifest{}
 	}
 
+	// The project is built for all platforms, unless its manifest says
+	// otherwise.
+	var plats []pkgtree.Platform
+	if pm, ok := params.Manifest.(PlatformManifest); ok {
+		plats = pm.Platforms()
+	}
+
 	rd := rootdata{
 		ir:      params.Manifest.IgnoredPackages(),
 		req:     params.Manifest.RequiredPackages(),
 		ovr:     params.Manifest.Overrides(),
-		rpt:     params.RootPackageTree.Copy(),
+		rpt:     params.RootPackageTree.Copy().ForPlatforms(plats),
+		plats:   plats,
 		chng:    make(map[ProjectRoot]struct{}),
 		rlm:     make(map[ProjectRoot]LockedProject),
 		chngall: params.ChangeAll,
@@ -288,18 +349,36 @@ func Prepare(params SolveParameters, sm SourceManager) (Solver, error) {
 		params.stdLibFn = paths.IsStandardImportPath
 	}
 
+	strategy := params.Strategy
+	if params.Downgrade {
+		if strategy != UpgradeStrategy && strategy != DowngradeStrategy {
+			return nil, badOptsFailure(fmt.Sprintf("Downgrade may not be combined with the %s strategy", strategy))
+		}
+		strategy = DowngradeStrategy
+	}
+	if strategy < UpgradeStrategy || strategy > MinimalStrategy {
+		return nil, badOptsFailure(fmt.Sprintf("unknown version strategy %s", strategy))
+	}
+
 	s := &solver{
 		tl:       params.TraceLogger,
+		ts:       params.TraceSink,
+		strategy: strategy,
 		stdLibFn: params.stdLibFn,
 		rd:       rd,
 	}
+	if memo, ok := sm.(solutionMemoizer); ok {
+		s.memo = memo
+	}
 
 	// Set up the bridge and ensure the root dir is in good, working order
-	// before doing anything else.
+	// before doing anything else. Both the downgrade and minimal strategies
+	// visit versions in ascending order.
+	down := strategy != UpgradeStrategy
 	if params.mkBridgeFn == nil {
-		s.b = mkBridge(s, sm, params.Downgrade)
+		s.b = mkBridge(s, sm, down)
 	} else {
-		s.b = params.mkBridgeFn(s, sm, params.Downgrade)
+		s.b = params.mkBridgeFn(s, sm, down)
 	}
 	err = s.b.verifyRootDir(params.RootDir)
 	if err != nil {
@@ -341,7 +420,8 @@ type Solver interface {
 
 	// Solve initiates a solving run. It will either abort due to a canceled
 	// Context, complete successfully with a Solution, or fail with an
-	// informative error.
+	// informative error. Where possible, that error is one of the structured
+	// failure types in this package, such as *NoVersionError.
 	//
 	// It is generally not allowed that this method be called twice for any
 	// given solver.
@@ -449,6 +529,24 @@ func (s *solver) Solve(ctx context.Context) (Solution, error) {
 	s.mtr = newMetrics()
 	s.vUnify.mtr = s.mtr
 
+	// Reuse the solution found for the same inputs before, if it still
+	// holds.
+	var key []byte
+	if s.memo != nil {
+		key = s.memoKey()
+		if p, ok := s.memo.memoizedSolution(ctx, key); ok {
+			soln := solution{
+				p:            p,
+				solv:         s,
+				analyzerInfo: s.rd.an.Info(),
+				hd:           s.HashInputs(),
+			}
+			s.traceMemoized(key)
+			s.traceFinish(soln, nil)
+			return soln, nil
+		}
+	}
+
 	// Prime the queues with the root project
 	if err := s.selectRoot(); err != nil {
 		return nil, err
@@ -475,11 +573,15 @@ func (s *solver) Solve(ctx context.Context) (Solution, error) {
 		}
 	}
 
+	if err == nil && s.memo != nil {
+		s.memo.memoizeSolution(ctx, key, soln)
+	}
+
 	s.traceFinish(soln, err)
 	if s.tl != nil {
 		s.mtr.dump(s.tl)
 	}
-	return soln, err
+	return soln, exportFailure(err)
 }
 
 // solve is the top-level loop for the solving process.
@@ -836,7 +938,11 @@ func (s *solver) createVersionQueue(bmi bimodalIdentifier) (*versionQueue, error
 	}
 
 	var prefv Version
-	if bmi.fromRoot {
+	switch {
+	case s.strategy == MinimalStrategy:
+		// The minimal strategy disregards the versions preferred by the locks
+		// of dependencies.
+	case bmi.fromRoot:
 		// If this bmi came from the root, then we want to search through things
 		// with a dependency on it in order to see if any have a lock that might
 		// express a prefv
@@ -876,7 +982,7 @@ func (s *solver) createVersionQueue(bmi bimodalIdentifier) (*versionQueue, error
 		//}
 		//}
 
-	} else {
+	default:
 		// Otherwise, just use the preferred version expressed in the bmi
 		prefv = bmi.prefv
 	}
@@ -931,7 +1037,7 @@ func (s *solver) findValidVersion(q *versionQueue, pl []string) error {
 
 	for {
 		cur := q.current()
-		s.traceInfo("try %s@%s", q.id, cur)
+		s.traceTry(q.id, cur)
 		err := s.check(atomWithPackages{
 			a: atom{
 				id: q.id,
@@ -1276,7 +1382,7 @@ func (s *solver) selectAtom(a atomWithPackages, pkgonly bool) error {
 	// queue consumption time?
 	_, l, _ := s.b.GetManifestAndLock(a.a.id, a.a.v, s.rd.an)
 	var lmap map[ProjectIdentifier]Version
-	if l != nil {
+	if l != nil && s.strategy != MinimalStrategy {
 		lmap = make(map[ProjectIdentifier]Version)
 		for _, lp := range l.Projects() {
 			lmap[lp.Ident()] = lp.Version()
@@ -148,6 +148,24 @@ func TestBadSolveOpts(t *testing.T) {
 	}
 
 	params.Lock, params.ToChange = nil, nil
+
+	params.Downgrade, params.Strategy = true, MinimalStrategy
+	_, err = Prepare(params, sm)
+	if err == nil {
+		t.Errorf("Should have errored on Downgrade combined with the minimal strategy")
+	} else if !strings.Contains(err.Error(), "Downgrade may not be combined with the minimal strategy") {
+		t.Error("Prepare should have given error on Downgrade with the minimal strategy, but gave:", err)
+	}
+
+	params.Downgrade, params.Strategy = false, VersionStrategy(42)
+	_, err = Prepare(params, sm)
+	if err == nil {
+		t.Errorf("Should have errored on unknown strategy")
+	} else if !strings.Contains(err.Error(), "unknown version strategy") {
+		t.Error("Prepare should have given error on unknown strategy, but gave:", err)
+	}
+
+	params.Strategy = UpgradeStrategy
 	_, err = Prepare(params, sm)
 	if err != nil {
 		t.Error("Basic conditions satisfied, prepare should have completed successfully, err as:", err)
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/golang/dep/gps/pkgtree"
 	"github.com/pkg/errors"
@@ -67,14 +68,16 @@ type sourceCoordinator struct {
 	psrcmut    sync.Mutex // guards protoSrcs map
 	protoSrcs  map[string][]chan srcReturn
 	cachedir   string
+	offline    bool
 	logger     *log.Logger
 }
 
-func newSourceCoordinator(superv *supervisor, deducer deducer, cachedir string, logger *log.Logger) *sourceCoordinator {
+func newSourceCoordinator(superv *supervisor, deducer deducer, cachedir string, offline bool, logger *log.Logger) *sourceCoordinator {
 	return &sourceCoordinator{
 		supervisor: superv,
 		deducer:    deducer,
 		cachedir:   cachedir,
+		offline:    offline,
 		logger:     logger,
 		srcs:       make(map[string]*sourceGateway),
 		nameToURL:  make(map[string]string),
@@ -199,34 +202,26 @@ func (sc *sourceCoordinator) getSourceGatewayFor(ctx context.Context, id Project
 	defer sc.srcmut.Unlock()
 
 	// Get or create a sourceGateway.
-	var srcGate *sourceGateway
-	var url, unfoldedURL string
-	var errs errorSlice
-	for _, m := range pd.mb {
-		url = m.URL().String()
-		if notFolded {
-			// If the normalizedName and foldedNormalName differ, then we're pretty well
-			// guaranteed that returned URL will also need folding into canonical form.
-			unfoldedURL = url
-			url = toFold(url)
-		}
-		if sg, has := sc.srcs[url]; has {
-			srcGate = sg
-			break
-		}
-		src, err := m.try(ctx, sc.cachedir)
-		if err == nil {
-			srcGate, err = newSourceGateway(ctx, src, sc.supervisor, sc.cachedir)
-			if err == nil {
-				sc.srcs[url] = srcGate
-				break
-			}
+	srcGate, m, errs := sc.gatewayFor(ctx, pd.mb, notFolded)
+	if srcGate == nil {
+		var err error = errs
+		if sc.offline && allOffline(errs) {
+			// None of the possible sources are in the cache; there's no need to
+			// list them all.
+			err = OfflineError{What: normalizedName}
+		} else {
+			// The deduction may be stale, if it was cached; deduce it
+			// afresh next time.
+			sc.deducer.forget(pd.root)
 		}
-		errs = append(errs, err)
+		doReturn(nil, err)
+		return nil, err
 	}
-	if srcGate == nil {
-		doReturn(nil, errs)
-		return nil, errs
+
+	url := m.URL().String()
+	unfoldedURL := url
+	if notFolded {
+		url = toFold(url)
 	}
 
 	// Record the name -> URL mapping, making sure that we also get the
@@ -247,6 +242,61 @@ func (sc *sourceCoordinator) getSourceGatewayFor(ctx context.Context, id Project
 	return srcGate, nil
 }
 
+// gatewayFor returns the sourceGateway of the first of mbs that can be
+// reached, creating it if there is none yet, along with that maybeSource.
+// Gateways are recorded under the URLs of their maybeSources, folded into
+// canonical form if fold is set. If none of mbs can be reached, it returns
+// the errors of trying each. sc.srcmut must be held for writing.
+func (sc *sourceCoordinator) gatewayFor(ctx context.Context, mbs maybeSources, fold bool) (*sourceGateway, maybeSource, errorSlice) {
+	var errs errorSlice
+	for i, m := range mbs {
+		url := m.URL().String()
+		if fold {
+			// If the normalizedName and foldedNormalName differ, then we're pretty well
+			// guaranteed that returned URL will also need folding into canonical form.
+			url = toFold(url)
+		}
+		if sg, has := sc.srcs[url]; has {
+			return sg, m, nil
+		}
+		src, err := m.try(ctx, sc.cachedir)
+		if err == nil {
+			if ps, ok := src.(*proxySource); ok {
+				// Module proxies do not serve branches; those come from the
+				// repositories the proxy is tried ahead of.
+				rest := mbs[i+1:]
+				ps.fallback = func(ctx context.Context) (*sourceGateway, error) {
+					sc.srcmut.Lock()
+					defer sc.srcmut.Unlock()
+					sg, _, errs := sc.gatewayFor(ctx, rest, fold)
+					if sg == nil {
+						return nil, errs
+					}
+					return sg, nil
+				}
+			}
+			var sg *sourceGateway
+			sg, err = newSourceGateway(ctx, src, sc.supervisor, sc.cachedir, sc.offline)
+			if err == nil {
+				markSourceUsed(m.cachePath(sc.cachedir))
+				sc.srcs[url] = sg
+				return sg, m, nil
+			}
+		}
+		errs = append(errs, err)
+	}
+	return nil, nil, errs
+}
+
+func allOffline(errs []error) bool {
+	for _, err := range errs {
+		if _, ok := err.(OfflineError); !ok {
+			return false
+		}
+	}
+	return len(errs) > 0
+}
+
 // sourceGateways manage all incoming calls for data from sources, serializing
 // and caching them as needed.
 type sourceGateway struct {
@@ -256,11 +306,13 @@ type sourceGateway struct {
 	cache    singleSourceCache
 	mu       sync.Mutex // global lock, serializes all behaviors
 	suprvsr  *supervisor
+	offline  bool // if set, never contact the upstream
 }
 
 // newSourceGateway returns a new gateway for src. If the source exists locally,
-// the local state may be cleaned, otherwise we ping upstream.
-func newSourceGateway(ctx context.Context, src source, superv *supervisor, cachedir string) (*sourceGateway, error) {
+// the local state may be cleaned, otherwise we ping upstream. Offline gateways
+// can only be created for sources that exist locally.
+func newSourceGateway(ctx context.Context, src source, superv *supervisor, cachedir string, offline bool) (*sourceGateway, error) {
 	var state sourceState
 	local := src.existsLocally(ctx)
 	if local {
@@ -270,6 +322,8 @@ func newSourceGateway(ctx context.Context, src source, superv *supervisor, cache
 		}); err != nil {
 			return nil, err
 		}
+	} else if offline {
+		return nil, OfflineError{What: src.upstreamURL()}
 	}
 
 	sg := &sourceGateway{
@@ -277,6 +331,7 @@ func newSourceGateway(ctx context.Context, src source, superv *supervisor, cache
 		src:      src,
 		cachedir: cachedir,
 		suprvsr:  superv,
+		offline:  offline,
 	}
 	sg.cache = sg.createSingleSourceCache()
 
@@ -290,8 +345,14 @@ func newSourceGateway(ctx context.Context, src source, superv *supervisor, cache
 }
 
 func (sg *sourceGateway) syncLocal(ctx context.Context) error {
+	wanted := sourceExistsLocally | sourceHasLatestLocally
+	if sg.offline {
+		// There's nothing to sync with, so just make sure the source is there.
+		wanted = sourceExistsLocally
+	}
+
 	sg.mu.Lock()
-	err := sg.require(ctx, sourceExistsLocally|sourceHasLatestLocally)
+	err := sg.require(ctx, wanted)
 	sg.mu.Unlock()
 	return err
 }
@@ -472,6 +533,11 @@ func (sg *sourceGateway) convertToRevision(ctx context.Context, v Version) (Revi
 	}
 
 	if sg.srcState&sourceHasLatestVersionList != 0 {
+		if sg.offline {
+			// The version list came from the cache, so it may simply be out
+			// of date.
+			return "", OfflineError{What: fmt.Sprintf("version %q of %s", v, sg.src.upstreamURL())}
+		}
 		// We have the latest version list already and didn't get a match, so
 		// this is definitely a failure case.
 		return "", fmt.Errorf("version %q does not exist in source", v)
@@ -486,6 +552,9 @@ func (sg *sourceGateway) convertToRevision(ctx context.Context, v Version) (Revi
 
 	r, has = sg.cache.toRevision(v)
 	if !has {
+		if sg.offline {
+			return "", OfflineError{What: fmt.Sprintf("version %q of %s", v, sg.src.upstreamURL())}
+		}
 		return "", fmt.Errorf("version %q does not exist in source", v)
 	}
 
@@ -542,6 +611,44 @@ func (sg *sourceGateway) disambiguateRevision(ctx context.Context, r Revision) (
 	return sg.src.disambiguateRevision(ctx, r)
 }
 
+func (sg *sourceGateway) revisionTime(ctx context.Context, r Revision) (time.Time, error) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	err := sg.require(ctx, sourceExistsLocally)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	t, err := sg.src.revisionTime(ctx, r)
+	// As with exporting, the revision may be missing from a stale local copy.
+	if err != nil && sg.srcState&sourceHasLatestLocally == 0 {
+		if err = sg.require(ctx, sourceHasLatestLocally); err == nil {
+			t, err = sg.src.revisionTime(ctx, r)
+		}
+	}
+	return t, err
+}
+
+func (sg *sourceGateway) commitsBetween(ctx context.Context, from, to Revision) (int, error) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	err := sg.require(ctx, sourceExistsLocally)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := sg.src.commitsBetween(ctx, from, to)
+	// As with exporting, the revisions may be missing from a stale local copy.
+	if err != nil && sg.srcState&sourceHasLatestLocally == 0 {
+		if err = sg.require(ctx, sourceHasLatestLocally); err == nil {
+			n, err = sg.src.commitsBetween(ctx, from, to)
+		}
+	}
+	return n, err
+}
+
 // createSingleSourceCache creates a singleSourceCache instance for use by
 // the encapsulated source.
 func (sg *sourceGateway) createSingleSourceCache() singleSourceCache {
@@ -603,6 +710,10 @@ func (sg *sourceGateway) loadLatestVersionList(ctx context.Context) (sourceState
 // data if necessary. Returns an error if the state could not be reached.
 // caller must hold sg.mu
 func (sg *sourceGateway) require(ctx context.Context, wanted sourceState) (err error) {
+	if sg.offline {
+		return sg.requireOffline(ctx, wanted)
+	}
+
 	todo := (^sg.srcState) & wanted
 	var flag sourceState = 1
 
@@ -645,6 +756,41 @@ func (sg *sourceGateway) require(ctx context.Context, wanted sourceState) (err e
 	return nil
 }
 
+// requireOffline is the offline counterpart of require. Only the local copy of
+// the source is consulted, and it stands in for the upstream; states that
+// would require contacting the upstream fail with an OfflineError.
+// caller must hold sg.mu
+func (sg *sourceGateway) requireOffline(ctx context.Context, wanted sourceState) error {
+	todo := (^sg.srcState) & wanted
+	if todo == 0 {
+		return nil
+	}
+
+	if todo&sourceHasLatestLocally != 0 {
+		return OfflineError{What: "an up-to-date copy of " + sg.src.upstreamURL()}
+	}
+	if !sg.src.existsLocally(ctx) {
+		return OfflineError{What: sg.src.upstreamURL()}
+	}
+
+	if todo&sourceHasLatestVersionList != 0 {
+		if _, ok := sg.cache.getAllVersions(); !ok {
+			var pvl []PairedVersion
+			if err := sg.suprvsr.do(ctx, sg.src.sourceType(), ctListVersions, func(ctx context.Context) error {
+				var err error
+				pvl, err = sg.src.listCachedVersions(ctx)
+				return errors.Wrapf(err, "failed to list cached versions for %s", sg.src.upstreamURL())
+			}); err != nil {
+				return err
+			}
+			sg.cache.setVersionMap(pvl)
+		}
+	}
+
+	sg.srcState |= todo
+	return nil
+}
+
 // source is an abstraction around the different underlying types (git, bzr, hg,
 // svn, maybe raw on-disk code, and maybe eventually a registry) that can
 // provide versioned project source trees.
@@ -657,10 +803,17 @@ type source interface {
 	// maybeClean is a no-op when the underlying source does not support cleaning.
 	maybeClean(context.Context) error
 	listVersions(context.Context) ([]PairedVersion, error)
+	// listCachedVersions lists versions using only the local copy of the
+	// source, without contacting the upstream.
+	listCachedVersions(context.Context) ([]PairedVersion, error)
 	getManifestAndLock(context.Context, ProjectRoot, Revision, ProjectAnalyzer) (Manifest, Lock, error)
 	listPackages(context.Context, ProjectRoot, Revision) (pkgtree.PackageTree, error)
 	revisionPresentIn(Revision) (bool, error)
 	disambiguateRevision(context.Context, Revision) (Revision, error)
+	revisionTime(context.Context, Revision) (time.Time, error)
+	// commitsBetween counts the commits reachable from the second revision,
+	// but not from the first.
+	commitsBetween(context.Context, Revision, Revision) (int, error)
 	exportRevisionTo(context.Context, Revision, string) error
 	sourceType() string
 	// existsCallsListVersions returns true if calling existsUpstream actually lists
@@ -6,6 +6,7 @@ package gps
 
 import (
 	"encoding/binary"
+	"strings"
 	"time"
 
 	"github.com/boltdb/bolt"
@@ -17,6 +18,8 @@ import (
 )
 
 var (
+	cacheKeyBuild      = []byte("b")
+	cacheKeyTestBuild  = []byte("tb")
 	cacheKeyComment    = []byte("c")
 	cacheKeyConstraint = cacheKeyComment
 	cacheKeyError      = []byte("e")
@@ -382,9 +385,61 @@ func cachePutPackageOrErr(b *bolt.Bucket, poe pkgtree.PackageOrErr) error {
 			}
 		}
 	}
+
+	if err := cachePutImportConstraints(b, cacheKeyBuild, poe.P.ImportConstraints); err != nil {
+		return err
+	}
+	return cachePutImportConstraints(b, cacheKeyTestBuild, poe.P.TestImportConstraints)
+}
+
+// cachePutImportConstraints stores the build constraints of imports in a new
+// bucket named key. Each import gets a bucket of its own within it, holding the
+// constraints of the files making the import, with the lines of each joined by
+// newlines.
+func cachePutImportConstraints(b *bolt.Bucket, key []byte, ic map[string][]pkgtree.BuildConstraint) error {
+	if len(ic) == 0 {
+		return nil
+	}
+
+	bb, err := b.CreateBucket(key)
+	if err != nil {
+		return err
+	}
+	for imp, bcs := range ic {
+		ib, err := bb.CreateBucket([]byte(imp))
+		if err != nil {
+			return err
+		}
+		k := make(nuts.Key, nuts.KeyLen(uint64(len(bcs)-1)))
+		for i, bc := range bcs {
+			k.Put(uint64(i))
+			if err := ib.Put(k, []byte(strings.Join(bc, "\n"))); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
+// cacheGetImportConstraints returns the build constraints of imports stored
+// by cachePutImportConstraints in the bucket named key, if there is one.
+func cacheGetImportConstraints(b *bolt.Bucket, key []byte) (map[string][]pkgtree.BuildConstraint, error) {
+	bb := b.Bucket(key)
+	if bb == nil {
+		return nil, nil
+	}
+
+	ic := make(map[string][]pkgtree.BuildConstraint)
+	err := bb.ForEach(func(imp, _ []byte) error {
+		return bb.Bucket(imp).ForEach(func(_, v []byte) error {
+			bc := pkgtree.BuildConstraint(strings.Split(string(v), "\n"))
+			ic[string(imp)] = append(ic[string(imp)], bc)
+			return nil
+		})
+	})
+	return ic, err
+}
+
 // cacheGetPackageOrErr returns a new pkgtree.PackageOrErr with fields retrieved
 // from the bolt.Bucket.
 func cacheGetPackageOrErr(b *bolt.Bucket) (pkgtree.PackageOrErr, error) {
@@ -415,6 +470,13 @@ func cacheGetPackageOrErr(b *bolt.Bucket) (pkgtree.PackageOrErr, error) {
 			return pkgtree.PackageOrErr{}, err
 		}
 	}
+	var err error
+	if p.ImportConstraints, err = cacheGetImportConstraints(b, cacheKeyBuild); err != nil {
+		return pkgtree.PackageOrErr{}, err
+	}
+	if p.TestImportConstraints, err = cacheGetImportConstraints(b, cacheKeyTestBuild); err != nil {
+		return pkgtree.PackageOrErr{}, err
+	}
 	return pkgtree.PackageOrErr{P: p}, nil
 }
 
@@ -97,6 +97,15 @@ func TestBoltCacheTimeout(t *testing.T) {
 						"os",
 						"sort",
 					},
+					TestImports: []string{
+						"testing",
+					},
+					ImportConstraints: map[string][]pkgtree.BuildConstraint{
+						"os": {{"linux", "!appengine"}, {"windows"}},
+					},
+					TestImportConstraints: map[string][]pkgtree.BuildConstraint{
+						"testing": {{"linux"}},
+					},
 				},
 			},
 		},
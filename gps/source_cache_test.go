@@ -546,6 +546,17 @@ func packageOrErrEqual(a, b pkgtree.PackageOrErr) bool {
 		}
 	}
 
+	if len(a.P.ImportConstraints) != 0 || len(b.P.ImportConstraints) != 0 {
+		if !reflect.DeepEqual(a.P.ImportConstraints, b.P.ImportConstraints) {
+			return false
+		}
+	}
+	if len(a.P.TestImportConstraints) != 0 || len(b.P.TestImportConstraints) != 0 {
+		if !reflect.DeepEqual(a.P.TestImportConstraints, b.P.TestImportConstraints) {
+			return false
+		}
+	}
+
 	return true
 }
 
@@ -11,16 +11,19 @@ import (
 
 // unwrapVcsErr recognizes *vcs.LocalError and *vsc.RemoteError, and returns a form
 // preserving the actual vcs command output and error, in addition to the message.
+// Remote errors stay marked as such, as vcsRemoteErrors.
 // All other types pass through unchanged.
 func unwrapVcsErr(err error) error {
 	var cause error
 	var out, msg string
+	var remote bool
 
 	switch t := err.(type) {
 	case *vcs.LocalError:
 		cause, out, msg = t.Original(), t.Out(), t.Error()
 	case *vcs.RemoteError:
 		cause, out, msg = t.Original(), t.Out(), t.Error()
+		remote = true
 
 	default:
 		return err
@@ -31,5 +34,8 @@ func unwrapVcsErr(err error) error {
 	} else {
 		cause = errors.Wrap(cause, out)
 	}
+	if remote {
+		return vcsRemoteError{errors.Wrap(cause, msg)}
+	}
 	return errors.Wrap(cause, msg)
 }
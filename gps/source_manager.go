@@ -162,6 +162,8 @@ type SourceMgr struct {
 	cancelAll   context.CancelFunc    // cancel func to kill all running work
 	deduceCoord *deductionCoordinator // subsystem that manages import path deduction
 	srcCoord    *sourceCoordinator    // subsystem that manages sources
+	cache       *boltCache            // persistent cache, if one is in use
+	solutions   *solutionCache        // memoized solutions, if they are kept
 	sigmut      sync.Mutex            // mutex protecting signal handling setup/teardown
 	qch         chan struct{}         // quit chan for signal handler
 	relonce     sync.Once             // once-er to ensure we only release once
@@ -180,11 +182,45 @@ type SourceManagerConfig struct {
 	Cachedir       string      // Where to store local instances of upstream sources.
 	Logger         *log.Logger // Optional info/warn logger. Discards if nil.
 	DisableLocking bool        // True if the SourceManager should NOT use a lock file to protect the Cachedir from multiple processes.
+	Offline        bool        // True if the SourceManager should serve all requests from the Cachedir, never contacting upstream sources.
+	Proxy          string      // Optional base URL of a Go module proxy, tried before the repositories of projects.
+	Mirrors        []Mirror    // Optional rewrites of repository URLs, for fetching from mirrors.
+
+	// DeductionCacheTTL is how long the go-get metadata of import paths is
+	// cached on disk, sparing later SourceMgrs from fetching it again. It is
+	// not cached on disk if zero.
+	DeductionCacheTTL time.Duration
+
+	// Retry is how operations reaching the network are retried when they
+	// fail. They are not retried by the zero RetryPolicy.
+	Retry RetryPolicy
+
+	// MemoizeSolutions is whether the solutions found by solvers using the
+	// SourceMgr are kept on disk, for solvers given the same inputs to reuse.
+	MemoizeSolutions bool
 }
 
 // NewSourceManager produces an instance of gps's built-in SourceManager.
 //
 // The returned SourceManager aggressively caches information wherever possible.
+// If c.Offline is set, it relies entirely on the sources already cached in
+// c.Cachedir; requests that cannot be served from there fail with an
+// OfflineError.
+// If c.Proxy is set, projects are fetched from that module proxy, speaking
+// the GOPROXY protocol, falling back to their repositories for modules it
+// does not have.
+// If c.Mirrors is set, repositories are fetched from the mirrors whose
+// prefixes match their URLs.
+// If c.Retry is set, operations reaching the network that fail in the ways it
+// retries are attempted again, after a backoff.
+// If c.DeductionCacheTTL is set, go-get metadata is kept in the bolt cache
+// under c.Cachedir for that long, and discarded earlier if fetching a source
+// it led to fails. Should the cache be in use by another process, it is
+// done without.
+// If c.MemoizeSolutions is set, the solutions found by solvers using the
+// SourceManager are kept in the bolt cache too, keyed by the digest of their
+// inputs. A solver given the same inputs reuses the solution, instead of
+// solving again, if the versions of none of the projects in it have changed.
 // If tools need to do preliminary work involving upstream repository analysis
 // prior to invoking a solve run, it is recommended that they create this
 // SourceManager as early as possible and use it to their ends. That way, the
@@ -198,6 +234,17 @@ func NewSourceManager(c SourceManagerConfig) (*SourceMgr, error) {
 		c.Logger = log.New(ioutil.Discard, "", 0)
 	}
 
+	if c.Proxy != "" {
+		if err := ValidateProxy(c.Proxy); err != nil {
+			return nil, err
+		}
+	}
+	for _, m := range c.Mirrors {
+		if err := ValidateMirror(m); err != nil {
+			return nil, err
+		}
+	}
+
 	err := fs.EnsureDir(filepath.Join(c.Cachedir, "sources"), 0777)
 	if err != nil {
 		return nil, err
@@ -277,7 +324,24 @@ func NewSourceManager(c SourceManagerConfig) (*SourceMgr, error) {
 
 	ctx, cf := context.WithCancel(context.TODO())
 	superv := newSupervisor(ctx)
-	deducer := newDeductionCoordinator(superv)
+	superv.retry = c.Retry
+	deducer := newDeductionCoordinator(superv, c)
+
+	var cache *boltCache
+	var solutions *solutionCache
+	if c.DeductionCacheTTL > 0 || c.MemoizeSolutions {
+		cache, err = newBoltCache(c.Cachedir, 0, c.Logger)
+		if err != nil {
+			c.Logger.Printf("not caching go-get metadata or solutions: %s\n", err)
+		} else {
+			if c.DeductionCacheTTL > 0 {
+				deducer.cache = cache.newDeductionCache(c.DeductionCacheTTL)
+			}
+			if c.MemoizeSolutions {
+				solutions = cache.newSolutionCache()
+			}
+		}
+	}
 
 	sm := &SourceMgr{
 		cachedir:    c.Cachedir,
@@ -285,7 +349,9 @@ func NewSourceManager(c SourceManagerConfig) (*SourceMgr, error) {
 		suprvsr:     superv,
 		cancelAll:   cf,
 		deduceCoord: deducer,
-		srcCoord:    newSourceCoordinator(superv, deducer, c.Cachedir, c.Logger),
+		srcCoord:    newSourceCoordinator(superv, deducer, c.Cachedir, c.Offline, c.Logger),
+		cache:       cache,
+		solutions:   solutions,
 		qch:         make(chan struct{}),
 	}
 
@@ -379,6 +445,18 @@ func (e CouldNotCreateLockError) Error() string {
 	return e.Err.Error()
 }
 
+// OfflineError is returned by an offline SourceMgr when a request would require
+// contacting an upstream source, because the information is not in its cache.
+type OfflineError struct {
+	// What describes the missing information: an import path, a source URL or
+	// a version within a source.
+	What string
+}
+
+func (e OfflineError) Error() string {
+	return "offline: not in cache: " + e.What
+}
+
 // Release lets go of any locks held by the SourceManager. Once called, it is no
 // longer safe to call methods against it; all method calls will immediately
 // result in errors.
@@ -393,6 +471,13 @@ func (sm *SourceMgr) Release() {
 		// Close the source coordinator.
 		sm.srcCoord.close()
 
+		// Close the persistent cache, if any.
+		if sm.cache != nil {
+			if err := sm.cache.close(); err != nil {
+				sm.cache.logger.Println(err)
+			}
+		}
+
 		// Close the file handle for the lock file and remove it from disk
 		sm.lf.Unlock()
 		os.Remove(filepath.Join(sm.cachedir, "sm.lock"))
@@ -614,6 +699,38 @@ func (sm *SourceMgr) SourceURLsForPath(ip string) ([]*url.URL, error) {
 	return deduced.mb.possibleURLs(), nil
 }
 
+// RevisionTime returns the time at which the given revision was committed to
+// the source for the given ProjectIdentifier.
+func (sm *SourceMgr) RevisionTime(id ProjectIdentifier, r Revision) (time.Time, error) {
+	if atomic.LoadInt32(&sm.releasing) == 1 {
+		return time.Time{}, ErrSourceManagerIsReleased
+	}
+
+	srcg, err := sm.srcCoord.getSourceGatewayFor(context.TODO(), id)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return srcg.revisionTime(context.TODO(), r)
+}
+
+// CommitsBetween returns the number of commits in the source for the given
+// ProjectIdentifier that are reachable from the revision to, but not from the
+// revision from; that is, how far to is ahead of from. It is only supported
+// by git and hg sources.
+func (sm *SourceMgr) CommitsBetween(id ProjectIdentifier, from, to Revision) (int, error) {
+	if atomic.LoadInt32(&sm.releasing) == 1 {
+		return 0, ErrSourceManagerIsReleased
+	}
+
+	srcg, err := sm.srcCoord.getSourceGatewayFor(context.TODO(), id)
+	if err != nil {
+		return 0, err
+	}
+
+	return srcg.commitsBetween(context.TODO(), from, to)
+}
+
 // disambiguateRevision looks up a revision in the underlying source, spitting
 // it back out in an unabbreviated, disambiguated form.
 //
@@ -634,8 +751,9 @@ type timeCount struct {
 }
 
 type durCount struct {
-	count int
-	dur   time.Duration
+	count   int
+	dur     time.Duration
+	retries int // how many times failed calls were retried
 }
 
 type supervisor struct {
@@ -644,6 +762,7 @@ type supervisor struct {
 	cond    sync.Cond  // Wraps mu so callers can wait until all calls end
 	running map[callInfo]timeCount
 	ran     map[callType]durCount
+	retry   RetryPolicy // How calls that fail are retried
 }
 
 func newSupervisor(ctx context.Context) *supervisor {
@@ -659,7 +778,8 @@ func newSupervisor(ctx context.Context) *supervisor {
 
 // do executes the incoming closure using a conjoined context, and keeps
 // counters to ensure the sourceMgr can't finish Release()ing until after all
-// calls have returned.
+// calls have returned. The closure is retried as the supervisor's
+// RetryPolicy says.
 func (sup *supervisor) do(inctx context.Context, name string, typ callType, f func(context.Context) error) error {
 	ci := callInfo{
 		name: name,
@@ -672,7 +792,17 @@ func (sup *supervisor) do(inctx context.Context, name string, typ callType, f fu
 	}
 
 	cctx, cancelFunc := constext.Cons(inctx, octx)
-	err = f(cctx)
+	err = sup.retry.run(cctx, f, func(err error, attempts int, delay time.Duration) {
+		sup.mu.Lock()
+		durCnt := sup.ran[typ]
+		durCnt.retries++
+		sup.ran[typ] = durCnt
+		sup.mu.Unlock()
+
+		if sup.retry.Logger != nil {
+			sup.retry.Logger.Printf("%s (%s) failed, retrying in %v (attempt %d of %d): %s\n", typ, name, delay, attempts, sup.retry.MaxAttempts, err)
+		}
+	})
 	sup.done(ci)
 	cancelFunc()
 	return err
@@ -777,6 +907,8 @@ func (ct callType) String() string {
 		return "Fetching latest data into local source cache"
 	case ctExportTree:
 		return "Writing code tree out to disk"
+	case ctValidateLocal:
+		return "Validating local source cache"
 	default:
 		panic("unknown calltype")
 	}
@@ -112,3 +112,32 @@ func TestSourceManager_InferConstraint(t *testing.T) {
 		})
 	}
 }
+
+func TestSourceMgrOffline(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("smcache")
+
+	sm, err := NewSourceManager(SourceManagerConfig{
+		Cachedir: h.Path("smcache"),
+		Offline:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Release()
+
+	cases := []struct {
+		id   ProjectIdentifier
+		want error
+	}{
+		{mkPI("github.com/sdboyer/deptest"), OfflineError{What: "github.com/sdboyer/deptest"}},
+		{mkPI("golang.org/x/net"), OfflineError{What: "go get metadata for golang.org/x/net"}},
+	}
+
+	for _, c := range cases {
+		if _, err := sm.ListVersions(c.id); err != c.want {
+			t.Errorf("expected %v listing versions of %s, got %v", c.want, c.id, err)
+		}
+	}
+}
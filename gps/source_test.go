@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
 	"testing"
@@ -41,9 +42,9 @@ func testSourceGateway(t *testing.T) {
 	do := func(wantstate sourceState) func(t *testing.T) {
 		return func(t *testing.T) {
 			superv := newSupervisor(ctx)
-			deducer := newDeductionCoordinator(superv)
+			deducer := newDeductionCoordinator(superv, SourceManagerConfig{})
 			logger := log.New(test.Writer{TB: t}, "", 0)
-			sc := newSourceCoordinator(superv, deducer, cachedir, logger)
+			sc := newSourceCoordinator(superv, deducer, cachedir, false, logger)
 			defer sc.close()
 
 			id := mkPI("github.com/sdboyer/deptest")
@@ -189,3 +190,80 @@ func testSourceGateway(t *testing.T) {
 	t.Run("empty", do(sourceExistsUpstream|sourceHasLatestVersionList))
 	t.Run("exists", do(sourceExistsLocally))
 }
+
+func TestOfflineSourceGateway(t *testing.T) {
+	requiresBins(t, "git")
+
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("smcache")
+	cpath := h.Path("smcache")
+	os.Mkdir(filepath.Join(cpath, "sources"), 0777)
+
+	h.TempDir("repo")
+	repoPath := h.Path("repo")
+	h.RunGit(repoPath, "init")
+	h.RunGit(repoPath, "config", "--local", "user.email", "test@example.com")
+	h.RunGit(repoPath, "config", "--local", "user.name", "Test author")
+	h.TempFile("repo/foo.go", "package foo\n")
+	h.RunGit(repoPath, "add", "foo.go")
+	h.RunGit(repoPath, "commit", `--message="Initial commit"`)
+	h.RunGit(repoPath, "tag", "v1.0.0")
+
+	un := "file://" + filepath.ToSlash(repoPath)
+	u, err := url.Parse(un)
+	if err != nil {
+		t.Fatalf("Error parsing URL %s: %s", un, err)
+	}
+
+	ctx := context.Background()
+	superv := newSupervisor(ctx)
+	src, err := maybeGitSource{u}.try(ctx, cpath)
+	if err != nil {
+		t.Fatalf("Unexpected error while setting up gitSource for test repo: %s", err)
+	}
+
+	// Nothing is cached yet.
+	_, err = newSourceGateway(ctx, src, superv, cpath, true)
+	if want := (OfflineError{What: un}); err != want {
+		t.Fatalf("expected %v for uncached source, got %v", want, err)
+	}
+
+	if err = src.initLocal(ctx); err != nil {
+		t.Fatalf("Error on cloning git repo: %s", err)
+	}
+
+	// With the upstream gone, everything must come from the cache.
+	if err = os.RemoveAll(repoPath); err != nil {
+		t.Fatal(err)
+	}
+
+	sg, err := newSourceGateway(ctx, src, superv, cpath, true)
+	if err != nil {
+		t.Fatalf("unexpected error creating gateway for cached source: %s", err)
+	}
+	if err = sg.syncLocal(ctx); err != nil {
+		t.Fatalf("unexpected error syncing cached source: %s", err)
+	}
+
+	pvs, err := sg.listVersions(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error listing cached versions: %s", err)
+	}
+	if len(pvs) != 2 {
+		t.Fatalf("expected the master branch and the v1.0.0 tag, got %v", pvs)
+	}
+
+	ptree, err := sg.listPackages(ctx, "example.com/foo", NewVersion("v1.0.0"))
+	if err != nil {
+		t.Fatalf("unexpected error listing packages: %s", err)
+	}
+	if _, has := ptree.Packages["example.com/foo"]; !has {
+		t.Fatalf("expected package example.com/foo, got %v", ptree.Packages)
+	}
+
+	_, err = sg.listPackages(ctx, "example.com/foo", NewVersion("v2.0.0"))
+	if want := (OfflineError{What: fmt.Sprintf("version %q of %s", "v2.0.0", un)}); err != want {
+		t.Fatalf("expected %v for uncached version, got %v", want, err)
+	}
+}
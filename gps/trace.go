@@ -6,6 +6,7 @@ package gps
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -22,6 +23,11 @@ const (
 )
 
 func (s *solver) traceCheckPkgs(bmi bimodalIdentifier) {
+	s.traceEvent(TraceEvent{
+		Kind:     TraceCheckPackages,
+		Project:  bmi.id,
+		Packages: bmi.pl,
+	})
 	if s.tl == nil {
 		return
 	}
@@ -31,6 +37,14 @@ func (s *solver) traceCheckPkgs(bmi bimodalIdentifier) {
 }
 
 func (s *solver) traceCheckQueue(q *versionQueue, bmi bimodalIdentifier, cont bool, offset int) {
+	s.traceEvent(TraceEvent{
+		Kind:          TraceCheckQueue,
+		Project:       bmi.id,
+		Packages:      bmi.pl,
+		Continue:      cont,
+		QueueLength:   len(q.pi),
+		QueueComplete: q.allLoaded,
+	})
 	if s.tl == nil {
 		return
 	}
@@ -59,6 +73,12 @@ func (s *solver) traceCheckQueue(q *versionQueue, bmi bimodalIdentifier, cont bo
 // traceStartBacktrack is called with the bmi that first failed, thus initiating
 // backtracking
 func (s *solver) traceStartBacktrack(bmi bimodalIdentifier, err error, pkgonly bool) {
+	s.traceEvent(TraceEvent{
+		Kind:         TraceStartBacktrack,
+		Project:      bmi.id,
+		Packages:     bmi.pl,
+		PackagesOnly: pkgonly,
+	})
 	if s.tl == nil {
 		return
 	}
@@ -77,6 +97,12 @@ func (s *solver) traceStartBacktrack(bmi bimodalIdentifier, err error, pkgonly b
 // traceBacktrack is called when a package or project is poppped off during
 // backtracking
 func (s *solver) traceBacktrack(bmi bimodalIdentifier, pkgonly bool) {
+	s.traceEvent(TraceEvent{
+		Kind:         TraceBacktrack,
+		Project:      bmi.id,
+		Packages:     bmi.pl,
+		PackagesOnly: pkgonly,
+	})
 	if s.tl == nil {
 		return
 	}
@@ -94,6 +120,12 @@ func (s *solver) traceBacktrack(bmi bimodalIdentifier, pkgonly bool) {
 
 // Called just once after solving has finished, whether success or not
 func (s *solver) traceFinish(sol solution, err error) {
+	if s.ts != nil {
+		s.traceEvent(TraceEvent{
+			Kind:    TraceFinish,
+			Failure: exportFailure(err),
+		})
+	}
 	if s.tl == nil {
 		return
 	}
@@ -109,9 +141,18 @@ func (s *solver) traceFinish(sol solution, err error) {
 	}
 }
 
+// traceMemoized is called when a memoized solution is reused instead of
+// solving.
+func (s *solver) traceMemoized(key []byte) {
+	if s.tl == nil {
+		return
+	}
+	s.tl.Printf("%s reusing memoized solution %x", successChar, key)
+}
+
 // traceSelectRoot is called just once, when the root project is selected
 func (s *solver) traceSelectRoot(ptree pkgtree.PackageTree, cdeps []completeDep) {
-	if s.tl == nil {
+	if s.tl == nil && s.ts == nil {
 		return
 	}
 
@@ -119,6 +160,23 @@ func (s *solver) traceSelectRoot(ptree pkgtree.PackageTree, cdeps []completeDep)
 	// so who cares
 	rm, _ := ptree.ToReachMap(true, true, false, s.rd.ir)
 
+	if s.ts != nil {
+		pkgs := make([]string, 0, len(rm))
+		for pkg := range rm {
+			pkgs = append(pkgs, pkg)
+		}
+		sort.Strings(pkgs)
+
+		s.traceEvent(TraceEvent{
+			Kind:     TraceSelectRoot,
+			Project:  ProjectIdentifier{ProjectRoot: ProjectRoot(ptree.ImportRoot)},
+			Packages: pkgs,
+		})
+	}
+	if s.tl == nil {
+		return
+	}
+
 	s.tl.Printf("Root project is %q", s.rd.rpt.ImportRoot)
 
 	var expkgs int
@@ -134,6 +192,13 @@ func (s *solver) traceSelectRoot(ptree pkgtree.PackageTree, cdeps []completeDep)
 
 // traceSelect is called when an atom is successfully selected
 func (s *solver) traceSelect(awp atomWithPackages, pkgonly bool) {
+	s.traceEvent(TraceEvent{
+		Kind:         TraceSelect,
+		Project:      awp.a.id,
+		Version:      awp.a.v,
+		Packages:     awp.pl,
+		PackagesOnly: pkgonly,
+	})
 	if s.tl == nil {
 		return
 	}
@@ -149,6 +214,32 @@ func (s *solver) traceSelect(awp atomWithPackages, pkgonly bool) {
 	s.tl.Printf("%s\n", tracePrefix(msg, prefix, prefix))
 }
 
+// traceTry is called when a version of a project is about to be checked.
+func (s *solver) traceTry(id ProjectIdentifier, v Version) {
+	s.traceEvent(TraceEvent{
+		Kind:    TraceTryVersion,
+		Project: id,
+		Version: v,
+	})
+	s.traceInfo("try %s@%s", id, v)
+}
+
+// traceFailure is called when an atom, or packages from it, could not be
+// selected.
+func (s *solver) traceFailure(awp atomWithPackages, pkgonly bool, err error) {
+	if s.ts != nil {
+		s.traceEvent(TraceEvent{
+			Kind:         TraceFailure,
+			Project:      awp.a.id,
+			Version:      awp.a.v,
+			Packages:     awp.pl,
+			PackagesOnly: pkgonly,
+			Failure:      exportFailure(err),
+		})
+	}
+	s.traceInfo(err)
+}
+
 func (s *solver) traceInfo(args ...interface{}) {
 	if s.tl == nil {
 		return
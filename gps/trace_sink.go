@@ -0,0 +1,114 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+// TraceSink receives structured events describing the progress of a solve run.
+// It is a machine-readable alternative to the output written to
+// SolveParameters.TraceLogger.
+//
+// Events are delivered synchronously, from the goroutine running Solve, in the
+// order in which they occur.
+type TraceSink interface {
+	TraceEvent(TraceEvent)
+}
+
+// TraceEventKind identifies the kind of step in a solve run that a TraceEvent
+// describes.
+type TraceEventKind string
+
+const (
+	// TraceSelectRoot is emitted once, when the root project is selected.
+	// Packages holds the root project's transitively valid internal packages.
+	TraceSelectRoot TraceEventKind = "select-root"
+
+	// TraceCheckQueue is emitted when the solver starts, or after
+	// backtracking continues, searching the queue of versions of Project for
+	// one that is acceptable.
+	TraceCheckQueue TraceEventKind = "check-queue"
+
+	// TraceCheckPackages is emitted when the solver revisits the already
+	// selected Project to add Packages from it.
+	TraceCheckPackages TraceEventKind = "check-packages"
+
+	// TraceTryVersion is emitted when a version of Project is tried.
+	TraceTryVersion TraceEventKind = "try-version"
+
+	// TraceFailure is emitted when a version of Project, or Packages from it,
+	// could not be selected. Failure holds the reason.
+	TraceFailure TraceEventKind = "failure"
+
+	// TraceSelect is emitted when a version of Project, or additional Packages
+	// from it, is selected.
+	TraceSelect TraceEventKind = "select"
+
+	// TraceStartBacktrack is emitted when the solver begins backtracking,
+	// because no version of Project, or not all of Packages from it, could be
+	// selected.
+	TraceStartBacktrack TraceEventKind = "start-backtrack"
+
+	// TraceBacktrack is emitted when a version of Project, or Packages from it,
+	// is unselected during backtracking.
+	TraceBacktrack TraceEventKind = "backtrack"
+
+	// TraceFinish is emitted once, when solving finishes. If solving failed,
+	// Failure holds the reason.
+	TraceFinish TraceEventKind = "finish"
+)
+
+// TraceEvent describes a single step in a solve run. Fields that are not
+// relevant to the event's Kind are left at their zero value.
+type TraceEvent struct {
+	Kind TraceEventKind
+
+	// Depth is the number of projects selected at the time of the event,
+	// including the root project.
+	Depth int
+	// Attempts is the number of times the solver has completed backtracking
+	// at the time of the event.
+	Attempts int
+
+	Project ProjectIdentifier
+	Version Version
+	// Packages holds the packages from Project the event concerns.
+	Packages []string
+	// PackagesOnly indicates that the event concerns only adding or removing
+	// Packages from the already selected Project, rather than a version of
+	// it.
+	PackagesOnly bool
+
+	// Continue indicates, for TraceCheckQueue events, that the search of the
+	// queue is being continued after backtracking.
+	Continue bool
+	// QueueLength is the number of versions of Project left to try, for
+	// TraceCheckQueue events.
+	QueueLength int
+	// QueueComplete indicates, for TraceCheckQueue events, whether all the
+	// versions of Project have been loaded into the queue; if not,
+	// QueueLength is a lower bound.
+	QueueComplete bool
+	// Unselected is the number of projects, or sets of packages from them,
+	// still waiting to be selected.
+	Unselected int
+
+	// Failure is the reason for a TraceFailure event, or for a failed solve
+	// in a TraceFinish event. Where possible, it is one of the structured
+	// failure types in this package.
+	Failure error
+}
+
+// traceEvent fills in the solver's current state on the event, and delivers it
+// to the trace sink, if there is one.
+func (s *solver) traceEvent(ev TraceEvent) {
+	if s.ts == nil {
+		return
+	}
+
+	ev.Depth = len(s.sel.projects)
+	ev.Attempts = s.attempts
+	if s.unsel != nil {
+		ev.Unselected = s.unsel.Len()
+	}
+	s.ts.TraceEvent(ev)
+}
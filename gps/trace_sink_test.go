@@ -0,0 +1,136 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"context"
+	"testing"
+)
+
+type recordingTraceSink []TraceEvent
+
+func (r *recordingTraceSink) TraceEvent(ev TraceEvent) {
+	*r = append(*r, ev)
+}
+
+func solveWithTraceSink(t *testing.T, name string) (recordingTraceSink, error) {
+	fix := basicFixtures[name]
+	sm := newdepspecSM(fix.ds, nil)
+
+	var sink recordingTraceSink
+	params := SolveParameters{
+		RootDir:         string(fix.ds[0].n),
+		RootPackageTree: fix.rootTree(),
+		Manifest:        fix.rootmanifest(),
+		Lock:            dummyLock{},
+		ProjectAnalyzer: naiveAnalyzer{},
+		TraceSink:       &sink,
+		stdLibFn:        func(string) bool { return false },
+		mkBridgeFn:      overrideMkBridge,
+	}
+
+	s, err := Prepare(params, sm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Solve(context.Background())
+	return sink, err
+}
+
+func TestTraceSinkEvents(t *testing.T) {
+	type want struct {
+		kind    TraceEventKind
+		project ProjectRoot
+		version string
+	}
+
+	check := func(t *testing.T, got recordingTraceSink, wants []want) {
+		if len(got) != len(wants) {
+			for _, ev := range got {
+				t.Logf("%s %s %v", ev.Kind, ev.Project.ProjectRoot, ev.Version)
+			}
+			t.Fatalf("expected %d events, got %d", len(wants), len(got))
+		}
+		for i, w := range wants {
+			ev := got[i]
+			var v string
+			if ev.Version != nil {
+				v = ev.Version.String()
+			}
+			if ev.Kind != w.kind || ev.Project.ProjectRoot != w.project || v != w.version {
+				t.Errorf("event %d: expected %s %s %s, got %s %s %s", i, w.kind, w.project, w.version, ev.Kind, ev.Project.ProjectRoot, v)
+			}
+		}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		got, err := solveWithTraceSink(t, "simple dependency tree")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// ba and bb are both brought in by b, and may be visited in either
+		// order.
+		if len(got) == 20 && got[13].Project.ProjectRoot == "bb" {
+			for i := 13; i < 16; i++ {
+				got[i], got[i+3] = got[i+3], got[i]
+			}
+		}
+
+		check(t, got, []want{
+			{TraceSelectRoot, "root", ""},
+			{TraceCheckQueue, "a", ""},
+			{TraceTryVersion, "a", "1.0.0"},
+			{TraceSelect, "a", "1.0.0"},
+			{TraceCheckQueue, "aa", ""},
+			{TraceTryVersion, "aa", "1.0.0"},
+			{TraceSelect, "aa", "1.0.0"},
+			{TraceCheckQueue, "b", ""},
+			{TraceTryVersion, "b", "1.0.0"},
+			{TraceSelect, "b", "1.0.0"},
+			{TraceCheckQueue, "ab", ""},
+			{TraceTryVersion, "ab", "1.0.0"},
+			{TraceSelect, "ab", "1.0.0"},
+			{TraceCheckQueue, "ba", ""},
+			{TraceTryVersion, "ba", "1.0.0"},
+			{TraceSelect, "ba", "1.0.0"},
+			{TraceCheckQueue, "bb", ""},
+			{TraceTryVersion, "bb", "1.0.0"},
+			{TraceSelect, "bb", "1.0.0"},
+			{TraceFinish, "", ""},
+		})
+
+		if last := got[len(got)-1]; last.Failure != nil || last.Depth != 7 {
+			t.Fatalf("expected successful finish with 7 projects selected, got %v with %d", last.Failure, last.Depth)
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		got, err := solveWithTraceSink(t, "no version that matches requirement")
+		if err == nil {
+			t.Fatal("expected solving to fail")
+		}
+
+		check(t, got, []want{
+			{TraceSelectRoot, "root", ""},
+			{TraceCheckQueue, "foo", ""},
+			{TraceTryVersion, "foo", "2.1.3"},
+			{TraceFailure, "foo", "2.1.3"},
+			{TraceTryVersion, "foo", "2.0.0"},
+			{TraceFailure, "foo", "2.0.0"},
+			{TraceStartBacktrack, "foo", ""},
+			{TraceFinish, "", ""},
+		})
+
+		if _, ok := got[3].Failure.(*VersionNotAllowedFailure); !ok {
+			t.Errorf("expected a *VersionNotAllowedFailure, got %T", got[3].Failure)
+		}
+		if q := got[1]; q.QueueLength != 2 || !q.QueueComplete {
+			t.Errorf("expected a complete queue of 2 versions, got %d (complete: %v)", q.QueueLength, q.QueueComplete)
+		}
+		if _, ok := got[len(got)-1].Failure.(*NoVersionError); !ok {
+			t.Errorf("expected finish with a *NoVersionError, got %T", got[len(got)-1].Failure)
+		}
+	})
+}
@@ -0,0 +1,111 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/dep/gps/pkgtree"
+	"github.com/golang/dep/internal/fs"
+	"github.com/pkg/errors"
+)
+
+// unpackedTrees keeps the trees of a source that has no repository to check
+// revisions out of, such as one made of archives, in the cache directory of
+// the source. Each tree is fetched once, and kept in a directory named after
+// its revision.
+//
+// It implements the parts of the source interface that read trees, for such
+// sources to embed.
+type unpackedTrees struct {
+	dir string
+	// fetchTree fetches the tree at the revision, and puts it in place.
+	fetchTree func(context.Context, Revision) error
+}
+
+// treePath returns the directory holding the tree at the revision.
+func (t *unpackedTrees) treePath(r Revision) string {
+	return filepath.Join(t.dir, string(r))
+}
+
+// hasTree reports whether the tree at the revision has been fetched.
+func (t *unpackedTrees) hasTree(r Revision) bool {
+	fi, err := os.Stat(t.treePath(r))
+	return err == nil && fi.IsDir()
+}
+
+// treeFor returns the directory holding the tree at the revision, fetching
+// it if necessary.
+func (t *unpackedTrees) treeFor(ctx context.Context, r Revision) (string, error) {
+	if !t.hasTree(r) {
+		if err := t.fetchTree(ctx, r); err != nil {
+			return "", err
+		}
+	}
+	return t.treePath(r), nil
+}
+
+// placeTree puts the tree at the revision in place. unpack writes it within
+// a temporary directory, and returns the directory holding it; what names it
+// in errors.
+func (t *unpackedTrees) placeTree(r Revision, what string, unpack func(tmp string) (string, error)) error {
+	tmp, err := ioutil.TempDir(t.dir, "unpack-")
+	if err != nil {
+		return errors.Wrapf(err, "failed to create temporary directory in %s", t.dir)
+	}
+	defer os.RemoveAll(tmp)
+
+	root, err := unpack(tmp)
+	if err != nil {
+		return err
+	}
+	if err := fs.RenameWithFallback(root, t.treePath(r)); err != nil {
+		return errors.Wrapf(err, "failed to move unpacked %s into place", what)
+	}
+	return nil
+}
+
+func (t *unpackedTrees) getManifestAndLock(ctx context.Context, pr ProjectRoot, r Revision, an ProjectAnalyzer) (Manifest, Lock, error) {
+	tree, err := t.treeFor(ctx, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m, l, err := an.DeriveManifestAndLock(tree, pr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if l != nil && l != Lock(nil) {
+		l = prepLock(l)
+	}
+
+	return prepManifest(m), l, nil
+}
+
+func (t *unpackedTrees) listPackages(ctx context.Context, pr ProjectRoot, r Revision) (pkgtree.PackageTree, error) {
+	tree, err := t.treeFor(ctx, r)
+	if err != nil {
+		return pkgtree.PackageTree{}, err
+	}
+	return pkgtree.ListPackages(tree, string(pr))
+}
+
+func (t *unpackedTrees) exportRevisionTo(ctx context.Context, r Revision, to string) error {
+	tree, err := t.treeFor(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	// Only make the parent dir, as CopyDir will balk on trying to write to an
+	// empty but existing dir.
+	if err := os.MkdirAll(filepath.Dir(to), 0777); err != nil {
+		return err
+	}
+	return fs.CopyDir(tree, to)
+}
@@ -11,7 +11,9 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Masterminds/semver"
 	"github.com/golang/dep/gps/pkgtree"
@@ -55,6 +57,18 @@ func (bs *baseVCSSource) disambiguateRevision(ctx context.Context, r Revision) (
 	return Revision(ci.Commit), nil
 }
 
+func (bs *baseVCSSource) revisionTime(ctx context.Context, r Revision) (time.Time, error) {
+	ci, err := bs.repo.CommitInfo(string(r))
+	if err != nil {
+		return time.Time{}, unwrapVcsErr(err)
+	}
+	return ci.Date, nil
+}
+
+func (bs *baseVCSSource) commitsBetween(ctx context.Context, from, to Revision) (int, error) {
+	return 0, errors.Errorf("counting commits is not supported for %s repositories", bs.repo.Vcs())
+}
+
 func (bs *baseVCSSource) getManifestAndLock(ctx context.Context, pr ProjectRoot, r Revision, an ProjectAnalyzer) (Manifest, Lock, error) {
 	err := bs.repo.updateVersion(ctx, r.String())
 	if err != nil {
@@ -231,10 +245,90 @@ func (s *gitSource) listVersions(ctx context.Context) (vlist []PairedVersion, er
 	// Ensure no prompting for PWs
 	cmd.SetEnv(append([]string{"GIT_ASKPASS=", "GIT_TERMINAL_PROMPT=0"}, os.Environ()...))
 	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, vcsRemoteError{errors.Wrap(err, string(out))}
+	}
+
+	return s.versionsFromRefs(out)
+}
+
+// revisionTime returns the commit time of the revision, rather than the author
+// time that the generic CommitInfo-based approach would.
+func (s *gitSource) revisionTime(ctx context.Context, r Revision) (time.Time, error) {
+	cmd := commandContext(ctx, "git", "log", "-1", "--format=%ct", string(r), "--")
+	cmd.SetDir(s.repo.LocalPath())
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, string(out))
+	}
+
+	sec, err := strconv.ParseInt(string(bytes.TrimSpace(out)), 10, 64)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "unexpected commit time for %s", r)
+	}
+	return time.Unix(sec, 0).UTC(), nil
+}
+
+func (s *gitSource) commitsBetween(ctx context.Context, from, to Revision) (int, error) {
+	cmd := commandContext(ctx, "git", "rev-list", "--count", string(from)+".."+string(to), "--")
+	cmd.SetDir(s.repo.LocalPath())
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return 0, errors.Wrap(err, string(out))
+	}
+
+	n, err := strconv.Atoi(string(bytes.TrimSpace(out)))
+	if err != nil {
+		return 0, errors.Wrapf(err, "unexpected commit count between %s and %s", from, to)
+	}
+	return n, nil
+}
+
+// listCachedVersions lists the versions in the local clone of the repository,
+// as of the last time it was fetched, without contacting the upstream.
+func (s *gitSource) listCachedVersions(ctx context.Context) ([]PairedVersion, error) {
+	r := s.repo
+
+	cmd := commandContext(ctx, "git", "ls-remote", ".")
+	cmd.SetDir(r.LocalPath())
+	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return nil, errors.Wrap(err, string(out))
 	}
 
+	// The upstream's branches, and its HEAD, are recorded in the clone as
+	// remote-tracking refs. Rewrite them to look as they would coming from the
+	// upstream itself, and drop the clone's own branches and HEAD, which may
+	// be stale or detached.
+	const remotePrefix = "refs/remotes/origin/"
+	var head string
+	var refs []string
+	for _, line := range strings.Split(string(bytes.TrimSpace(out)), "\n") {
+		fields := strings.SplitN(line, "\t", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		hash, ref := fields[0], fields[1]
+
+		switch {
+		case ref == remotePrefix+"HEAD":
+			head = hash + "\tHEAD"
+		case strings.HasPrefix(ref, remotePrefix):
+			refs = append(refs, hash+"\trefs/heads/"+strings.TrimPrefix(ref, remotePrefix))
+		case strings.HasPrefix(ref, "refs/tags/"):
+			refs = append(refs, line)
+		}
+	}
+	if head != "" {
+		// HEAD must come first.
+		refs = append([]string{head}, refs...)
+	}
+
+	return s.versionsFromRefs([]byte(strings.Join(refs, "\n")))
+}
+
+// versionsFromRefs converts the output of git ls-remote into a version list.
+func (s *gitSource) versionsFromRefs(out []byte) (vlist []PairedVersion, err error) {
 	all := bytes.Split(bytes.TrimSpace(out), []byte("\n"))
 	if len(all) == 1 && len(all[0]) == 0 {
 		return nil, fmt.Errorf("no data returned from ls-remote")
@@ -371,8 +465,20 @@ func (s *gopkginSource) listVersions(ctx context.Context) ([]PairedVersion, erro
 	if err != nil {
 		return nil, err
 	}
+	return s.filterVersions(ovlist), nil
+}
+
+func (s *gopkginSource) listCachedVersions(ctx context.Context) ([]PairedVersion, error) {
+	ovlist, err := s.gitSource.listCachedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.filterVersions(ovlist), nil
+}
 
-	// Apply gopkg.in's filtering rules
+// filterVersions applies gopkg.in's filtering rules to the versions of the
+// underlying git repository.
+func (s *gopkginSource) filterVersions(ovlist []PairedVersion) []PairedVersion {
 	vlist := make([]PairedVersion, len(ovlist))
 	k := 0
 	var dbranch int // index of branch to be marked default
@@ -445,7 +551,7 @@ func (s *gopkginSource) listVersions(ctx context.Context) ([]PairedVersion, erro
 		vlist = append(vlist, defaultBranch)
 	}
 
-	return vlist, nil
+	return vlist
 }
 
 // bzrSource is a generic bzr repository implementation that should work with
@@ -466,6 +572,11 @@ func (s *bzrSource) listVersionsRequiresLocal() bool {
 	return true
 }
 
+func (s *bzrSource) listCachedVersions(ctx context.Context) ([]PairedVersion, error) {
+	// bzr versions are always listed from the local copy.
+	return s.listVersions(ctx)
+}
+
 func (s *bzrSource) listVersions(ctx context.Context) ([]PairedVersion, error) {
 	r := s.repo
 
@@ -535,10 +646,26 @@ func (s *hgSource) exportRevisionTo(ctx context.Context, rev Revision, to string
 	return os.RemoveAll(filepath.Join(to, ".hg"))
 }
 
+func (s *hgSource) commitsBetween(ctx context.Context, from, to Revision) (int, error) {
+	// Print a byte per changeset, rather than parsing a list of them.
+	cmd := commandContext(ctx, "hg", "log", "--rev", fmt.Sprintf("only(%s, %s)", to, from), "--template", ".")
+	cmd.SetDir(s.repo.LocalPath())
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return 0, errors.Wrap(err, string(out))
+	}
+	return len(bytes.TrimSpace(out)), nil
+}
+
 func (s *hgSource) listVersionsRequiresLocal() bool {
 	return true
 }
 
+func (s *hgSource) listCachedVersions(ctx context.Context) ([]PairedVersion, error) {
+	// hg versions are always listed from the local copy.
+	return s.listVersions(ctx)
+}
+
 func (s *hgSource) listVersions(ctx context.Context) ([]PairedVersion, error) {
 	var vlist []PairedVersion
 
@@ -17,6 +17,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/golang/dep/internal/test"
 )
@@ -867,3 +868,179 @@ func requiresBins(t *testing.T, bins ...string) {
 		}
 	}
 }
+
+func TestGitSourceListCachedVersions(t *testing.T) {
+	requiresBins(t, "git")
+
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("smcache")
+	cpath := h.Path("smcache")
+	os.Mkdir(filepath.Join(cpath, "sources"), 0777)
+
+	h.TempDir("repo")
+	repoPath := h.Path("repo")
+
+	// Create a test repo with a tag, and a second branch besides master
+	h.RunGit(repoPath, "init")
+	h.RunGit(repoPath, "symbolic-ref", "HEAD", "refs/heads/master")
+	h.RunGit(repoPath, "config", "--local", "user.email", "test@example.com")
+	h.RunGit(repoPath, "config", "--local", "user.name", "Test author")
+	h.RunGit(repoPath, "commit", "--allow-empty", `--message="Initial commit"`)
+	h.RunGit(repoPath, "tag", "v1.0.0")
+	h.RunGit(repoPath, "branch", "other")
+
+	un := "file://" + filepath.ToSlash(repoPath)
+	u, err := url.Parse(un)
+	if err != nil {
+		t.Fatalf("Error parsing URL %s: %s", un, err)
+	}
+	mb := maybeGitSource{u}
+
+	ctx := context.Background()
+	isrc, err := mb.try(ctx, cpath)
+	if err != nil {
+		t.Fatalf("Unexpected error while setting up gitSource for test repo: %s", err)
+	}
+
+	err = isrc.initLocal(ctx)
+	if err != nil {
+		t.Fatalf("Error on cloning git repo: %s", err)
+	}
+
+	src, ok := isrc.(*gitSource)
+	if !ok {
+		t.Fatalf("Expected a gitSource, got a %T", isrc)
+	}
+
+	want, err := src.listVersions(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error getting version pairs from git repo: %s", err)
+	}
+
+	// Detach the clone's HEAD, and add a version upstream that hasn't been
+	// fetched. Neither should affect the cached versions.
+	h.RunGit(src.repo.LocalPath(), "checkout", "v1.0.0")
+	h.RunGit(repoPath, "commit", "--allow-empty", `--message="Second commit"`)
+	h.RunGit(repoPath, "tag", "v1.1.0")
+
+	got, err := src.listCachedVersions(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error getting cached version pairs from git repo: %s", err)
+	}
+
+	SortPairedForUpgrade(want)
+	SortPairedForUpgrade(got)
+	if len(got) != 3 || !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected cached versions:\n\t(GOT): %#v\n\t(WNT): %#v", got, want)
+	}
+}
+
+func TestGitSourceRevisionTime(t *testing.T) {
+	requiresBins(t, "git")
+
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("smcache")
+	cpath := h.Path("smcache")
+	os.Mkdir(filepath.Join(cpath, "sources"), 0777)
+
+	h.TempDir("repo")
+	repoPath := h.Path("repo")
+
+	// Author and commit the revision at different times; the commit time is
+	// the one that identifies it.
+	h.RunGit(repoPath, "init")
+	h.RunGit(repoPath, "config", "--local", "user.email", "test@example.com")
+	h.RunGit(repoPath, "config", "--local", "user.name", "Test author")
+	cmd := exec.Command("git", "commit", "--allow-empty", "--message=Initial commit", "--date=2017-01-02T03:04:05Z")
+	cmd.Dir = repoPath
+	cmd.Env = append(os.Environ(), "GIT_COMMITTER_DATE=2018-03-01T12:30:05+01:00")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git commit failed: %s\n%s", err, out)
+	}
+
+	un := "file://" + filepath.ToSlash(repoPath)
+	u, err := url.Parse(un)
+	if err != nil {
+		t.Fatalf("Error parsing URL %s: %s", un, err)
+	}
+
+	ctx := context.Background()
+	isrc, err := maybeGitSource{u}.try(ctx, cpath)
+	if err != nil {
+		t.Fatalf("Unexpected error while setting up gitSource for test repo: %s", err)
+	}
+	if err = isrc.initLocal(ctx); err != nil {
+		t.Fatalf("Error on cloning git repo: %s", err)
+	}
+
+	rev, err := isrc.disambiguateRevision(ctx, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := isrc.revisionTime(ctx, rev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2018, 3, 1, 11, 30, 5, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Unexpected revision time %s, want %s", got, want)
+	}
+
+	if _, err := isrc.revisionTime(ctx, "0123456789012345678901234567890123456789"); err == nil {
+		t.Error("Expected an error for a revision that does not exist")
+	}
+}
+
+func TestGitSourceCommitsBetween(t *testing.T) {
+	requiresBins(t, "git")
+
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("smcache")
+	cpath := h.Path("smcache")
+	os.Mkdir(filepath.Join(cpath, "sources"), 0777)
+
+	h.TempDir("repo")
+	repoPath := h.Path("repo")
+	h.RunGit(repoPath, "init")
+	h.RunGit(repoPath, "config", "--local", "user.email", "test@example.com")
+	h.RunGit(repoPath, "config", "--local", "user.name", "Test author")
+	h.RunGit(repoPath, "commit", "--allow-empty", "--message=Initial commit")
+	h.RunGit(repoPath, "tag", "v1.0.0")
+	h.RunGit(repoPath, "commit", "--allow-empty", "--message=Second commit")
+	h.RunGit(repoPath, "commit", "--allow-empty", "--message=Third commit")
+
+	un := "file://" + filepath.ToSlash(repoPath)
+	u, err := url.Parse(un)
+	if err != nil {
+		t.Fatalf("Error parsing URL %s: %s", un, err)
+	}
+
+	ctx := context.Background()
+	isrc, err := maybeGitSource{u}.try(ctx, cpath)
+	if err != nil {
+		t.Fatalf("Unexpected error while setting up gitSource for test repo: %s", err)
+	}
+	if err = isrc.initLocal(ctx); err != nil {
+		t.Fatalf("Error on cloning git repo: %s", err)
+	}
+
+	from, err := isrc.disambiguateRevision(ctx, "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := isrc.disambiguateRevision(ctx, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := isrc.commitsBetween(ctx, from, to); err != nil || n != 2 {
+		t.Errorf("Expected HEAD to be 2 commits ahead of v1.0.0, got %d (%v)", n, err)
+	}
+	if n, err := isrc.commitsBetween(ctx, to, from); err != nil || n != 0 {
+		t.Errorf("Expected v1.0.0 to be no commits ahead of HEAD, got %d (%v)", n, err)
+	}
+	if _, err := isrc.commitsBetween(ctx, from, "0123456789012345678901234567890123456789"); err == nil {
+		t.Error("Expected an error for a revision that does not exist")
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gomod
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/internal/importers/base"
+	"github.com/golang/dep/internal/modfile"
+	"github.com/pkg/errors"
+)
+
+// Importer imports Go module configuration into the dep configuration format.
+type Importer struct {
+	*base.Importer
+
+	mod *modfile.File
+}
+
+// NewImporter for Go modules.
+func NewImporter(logger *log.Logger, verbose bool, sm gps.SourceManager) *Importer {
+	return &Importer{Importer: base.NewImporter(logger, verbose, sm)}
+}
+
+// Name of the importer.
+func (g *Importer) Name() string {
+	return "gomod"
+}
+
+// HasDepMetadata checks if a directory contains config that the importer can handle.
+func (g *Importer) HasDepMetadata(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, modfile.FileName))
+	return err == nil
+}
+
+// Import the config found in the directory.
+func (g *Importer) Import(dir string, pr gps.ProjectRoot) (*dep.Manifest, *dep.Lock, error) {
+	err := g.load(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m, l := g.convert(pr)
+	return m, l, nil
+}
+
+func (g *Importer) load(dir string) error {
+	g.Logger.Println("Detected go.mod file...")
+
+	path := filepath.Join(dir, modfile.FileName)
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrapf(err, "unable to open %s", path)
+	}
+	defer f.Close()
+
+	g.mod, err = modfile.Parse(f)
+	if err != nil {
+		return errors.Wrapf(err, "unable to parse %s", path)
+	}
+
+	return nil
+}
+
+func (g *Importer) convert(pr gps.ProjectRoot) (*dep.Manifest, *dep.Lock) {
+	g.Logger.Println("Converting from go.mod...")
+
+	reqs := g.mod.Requirements()
+	required := make(map[string]bool, len(reqs))
+	packages := make([]base.ImportedPackage, 0, len(reqs))
+	for _, req := range reqs {
+		required[req.Path] = true
+
+		if req.Local != "" {
+			g.Logger.Printf(
+				"  Warning: Skipping project. %s is replaced by the local directory %s, which dep does not support\n",
+				req.Path, req.Local,
+			)
+			continue
+		}
+
+		ip := base.ImportedPackage{
+			Name:           req.Path,
+			ConstraintHint: req.Constraint(),
+		}
+		if req.Source != "" {
+			ip.Source = modfile.TrimMajorSuffix(req.Source)
+		}
+
+		switch {
+		case req.IsExcluded():
+			g.Logger.Printf(
+				"  Warning: Not locking %s, as its required version %s is excluded\n",
+				req.Path, req.Version,
+			)
+		case modfile.IsPseudoVersion(req.Version):
+			ip.LockHint = g.lookupRevision(ip, modfile.PseudoVersionRev(req.Version))
+		default:
+			ip.LockHint = modfile.Tag(req.Version)
+		}
+
+		packages = append(packages, ip)
+	}
+
+	for _, ex := range g.mod.Exclude {
+		if !required[ex.Path] && g.Verbose {
+			g.Logger.Printf("  Ignoring exclusion of %s %s, as it is not a direct requirement.\n", ex.Path, ex.Version)
+		}
+	}
+
+	g.ImportPackages(packages, true)
+	return g.Manifest, g.Lock
+}
+
+// lookupRevision expands the abbreviated revision that a pseudo-version
+// carries into the full revision, which is what dep locks to. The abbreviated
+// revision is returned if it cannot be expanded.
+func (g *Importer) lookupRevision(ip base.ImportedPackage, rev string) string {
+	pr, err := g.SourceManager.DeduceProjectRoot(ip.Name)
+	if err != nil {
+		// The project will be skipped, with a warning, when it is imported.
+		return rev
+	}
+
+	pi := gps.ProjectIdentifier{ProjectRoot: pr, Source: ip.Source}
+	c, err := g.SourceManager.InferConstraint(rev, pi)
+	if err != nil {
+		g.Logger.Printf("  Warning: Unable to find revision %s of %v: %s\n", rev, pi, err)
+		return rev
+	}
+	if r, ok := c.(gps.Revision); ok {
+		return string(r)
+	}
+	return rev
+}
@@ -0,0 +1,148 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gomod
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/internal/importers/importertest"
+	"github.com/golang/dep/internal/modfile"
+	"github.com/golang/dep/internal/test"
+	"github.com/pkg/errors"
+)
+
+func TestGomodConfig_Convert(t *testing.T) {
+	testCases := map[string]struct {
+		importertest.TestCase
+		mod modfile.File
+	}{
+		"tagged version": {
+			importertest.TestCase{
+				WantConstraint: importertest.V1Constraint,
+				WantRevision:   importertest.V1Rev,
+				WantVersion:    importertest.V1Tag,
+			},
+			modfile.File{
+				Require: []modfile.Module{
+					{Path: importertest.Project, Version: importertest.V1Tag},
+				},
+			},
+		},
+		"pseudo-version": {
+			importertest.TestCase{
+				WantRevision: importertest.UntaggedRev,
+			},
+			modfile.File{
+				Require: []modfile.Module{
+					{Path: importertest.Project, Version: "v1.0.1-0.20170809183805-" + importertest.UntaggedRev[:12]},
+				},
+			},
+		},
+		"excluded version": {
+			importertest.TestCase{
+				WantConstraint: "^1.0.0, !=1.0.0",
+				WantWarning: fmt.Sprintf(
+					"Warning: Not locking %s, as its required version %s is excluded",
+					importertest.Project, importertest.V1Tag,
+				),
+			},
+			modfile.File{
+				Require: []modfile.Module{
+					{Path: importertest.Project, Version: importertest.V1Tag},
+				},
+				Exclude: []modfile.Module{
+					{Path: importertest.Project, Version: importertest.V1Tag},
+				},
+			},
+		},
+		"local replacement": {
+			importertest.TestCase{
+				WantWarning: fmt.Sprintf(
+					"Warning: Skipping project. %s is replaced by the local directory ../fork, which dep does not support",
+					importertest.Project,
+				),
+			},
+			modfile.File{
+				Require: []modfile.Module{
+					{Path: importertest.Project, Version: importertest.V1Tag},
+				},
+				Replace: []modfile.Replace{
+					{
+						Old: modfile.Module{Path: importertest.Project},
+						New: modfile.Module{Path: "../fork"},
+					},
+				},
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		name := name
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			err := testCase.Execute(t, func(logger *log.Logger, sm gps.SourceManager) (*dep.Manifest, *dep.Lock) {
+				g := NewImporter(logger, true, sm)
+				g.mod = &testCase.mod
+				return g.convert(importertest.RootProject)
+			})
+			if err != nil {
+				t.Fatalf("%#v", err)
+			}
+		})
+	}
+}
+
+func TestGomodConfig_Import(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	ctx := importertest.NewTestContext(h)
+	sm, err := ctx.SourceManager()
+	h.Must(err)
+	defer sm.Release()
+
+	h.TempDir(filepath.Join("src", importertest.RootProject))
+	h.TempCopy(filepath.Join(importertest.RootProject, modfile.FileName), modfile.FileName)
+	projectRoot := h.Path(importertest.RootProject)
+
+	// Capture stderr so we can verify output
+	verboseOutput := &bytes.Buffer{}
+	ctx.Err = log.New(verboseOutput, "", 0)
+
+	g := NewImporter(ctx.Err, false, sm) // Disable verbose so that we don't print values that change each test run
+	if !g.HasDepMetadata(projectRoot) {
+		t.Fatal("Expected the importer to detect the go.mod file")
+	}
+
+	m, l, err := g.Import(projectRoot, importertest.RootProject)
+	h.Must(err)
+
+	if m == nil {
+		t.Fatal("Expected the manifest to be generated")
+	}
+
+	if l == nil {
+		t.Fatal("Expected the lock to be generated")
+	}
+
+	goldenFile := "golden.txt"
+	got := verboseOutput.String()
+	want := h.GetTestFileString(goldenFile)
+	if want != got {
+		if *test.UpdateGolden {
+			if err := h.WriteTestFile(goldenFile, got); err != nil {
+				t.Fatalf("%+v", errors.Wrapf(err, "Unable to write updated golden file %s", goldenFile))
+			}
+		} else {
+			t.Fatalf("want %s, got %s", want, got)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+module github.com/golang/notexist
+
+require (
+	github.com/sdboyer/deptest v0.8.1
+	github.com/sdboyer/deptestdos v2.0.0+incompatible
+)
@@ -0,0 +1,357 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package modfile reads the go.mod files used by Go modules, and works out the
+// requirements they express in terms dep understands.
+package modfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// FileName is the name of the file describing a Go module.
+const FileName = "go.mod"
+
+// Module is a module path, at a version. The version may be empty where it is
+// optional, such as on the left side of a replace directive.
+type Module struct {
+	Path    string
+	Version string
+}
+
+func (m Module) String() string {
+	if m.Version == "" {
+		return m.Path
+	}
+	return m.Path + " " + m.Version
+}
+
+// Replace is a replace directive, substituting New for Old.
+type Replace struct {
+	Old Module
+	New Module
+}
+
+// File is the content of a go.mod file.
+type File struct {
+	Module  string
+	Require []Module
+	Exclude []Module
+	Replace []Replace
+}
+
+// Parse reads a go.mod file from r. Directives other than module, require,
+// exclude and replace are ignored.
+func Parse(r io.Reader) (*File, error) {
+	f := &File{}
+	var block string
+	var lineno int
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineno++
+		toks, err := tokenize(scanner.Text())
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s:%d", FileName, lineno)
+		}
+		if len(toks) == 0 {
+			continue
+		}
+
+		if block != "" {
+			if len(toks) == 1 && toks[0] == ")" {
+				block = ""
+				continue
+			}
+			if err := f.add(block, toks); err != nil {
+				return nil, errors.Wrapf(err, "%s:%d", FileName, lineno)
+			}
+			continue
+		}
+
+		if len(toks) == 2 && toks[1] == "(" {
+			block = toks[0]
+			continue
+		}
+		if err := f.add(toks[0], toks[1:]); err != nil {
+			return nil, errors.Wrapf(err, "%s:%d", FileName, lineno)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", FileName)
+	}
+	if block != "" {
+		return nil, errors.Errorf("%s: unterminated %s block", FileName, block)
+	}
+
+	return f, nil
+}
+
+// add records the directive verb, with the arguments args.
+func (f *File) add(verb string, args []string) error {
+	switch verb {
+	case "module":
+		if len(args) != 1 {
+			return errors.New("usage: module module/path")
+		}
+		f.Module = args[0]
+	case "require", "exclude":
+		if len(args) != 2 {
+			return errors.Errorf("usage: %s module/path v1.2.3", verb)
+		}
+		m := Module{Path: args[0], Version: args[1]}
+		if verb == "require" {
+			f.Require = append(f.Require, m)
+		} else {
+			f.Exclude = append(f.Exclude, m)
+		}
+	case "replace":
+		var rep Replace
+		switch {
+		case len(args) == 3 && args[1] == "=>":
+			rep.Old = Module{Path: args[0]}
+			rep.New = Module{Path: args[2]}
+		case len(args) == 4 && args[1] == "=>":
+			rep.Old = Module{Path: args[0]}
+			rep.New = Module{Path: args[2], Version: args[3]}
+		case len(args) == 4 && args[2] == "=>":
+			rep.Old = Module{Path: args[0], Version: args[1]}
+			rep.New = Module{Path: args[3]}
+		case len(args) == 5 && args[2] == "=>":
+			rep.Old = Module{Path: args[0], Version: args[1]}
+			rep.New = Module{Path: args[3], Version: args[4]}
+		default:
+			return errors.New("usage: replace module/path [v1.2.3] => other/module v1.4.5 | ../local/directory")
+		}
+		if rep.New.Version == "" && !IsLocalPath(rep.New.Path) {
+			return errors.Errorf("replacement module %s must have a version", rep.New.Path)
+		}
+		f.Replace = append(f.Replace, rep)
+	}
+
+	return nil
+}
+
+// tokenize splits a line of a go.mod file into its tokens, dropping comments.
+func tokenize(line string) ([]string, error) {
+	var toks []string
+	for {
+		line = strings.TrimLeftFunc(line, unicode.IsSpace)
+		switch {
+		case line == "" || strings.HasPrefix(line, "//"):
+			return toks, nil
+		case line[0] == '"' || line[0] == '`':
+			end := strings.IndexByte(line[1:], line[0])
+			if end < 0 {
+				return nil, errors.New("unterminated quoted string")
+			}
+			tok, err := strconv.Unquote(line[:end+2])
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, tok)
+			line = line[end+2:]
+		default:
+			end := strings.IndexFunc(line, unicode.IsSpace)
+			if end < 0 {
+				end = len(line)
+			}
+			toks = append(toks, line[:end])
+			line = line[end:]
+		}
+	}
+}
+
+// Requirement is a module required by a go.mod file, after replace and exclude
+// directives have been applied to it.
+type Requirement struct {
+	// Path is the required module path.
+	Path string
+	// Version is the version of the module to use. If the module is replaced
+	// by another, this is the version of the replacement.
+	Version string
+	// Source is the path of the module replacing this one, if any.
+	Source string
+	// Local is the directory replacing this module, if any. Version is empty
+	// in this case.
+	Local string
+	// Excluded holds the versions of the module that are excluded.
+	Excluded []string
+}
+
+// Requirements returns the modules required by the file, in the order they are
+// required.
+func (f *File) Requirements() []Requirement {
+	reqs := make([]Requirement, 0, len(f.Require))
+	for _, m := range f.Require {
+		req := Requirement{
+			Path:    m.Path,
+			Version: m.Version,
+		}
+
+		if rep, ok := f.replacementFor(m); ok {
+			if IsLocalPath(rep.Path) {
+				req.Version = ""
+				req.Local = rep.Path
+			} else {
+				req.Version = rep.Version
+				if rep.Path != m.Path {
+					req.Source = rep.Path
+				}
+			}
+		} else {
+			for _, ex := range f.Exclude {
+				if ex.Path == m.Path {
+					req.Excluded = append(req.Excluded, ex.Version)
+				}
+			}
+		}
+
+		reqs = append(reqs, req)
+	}
+	return reqs
+}
+
+// replacementFor returns the module that replaces m, if there is one. A
+// replacement for the specific version of m takes precedence over one for all
+// versions of it.
+func (f *File) replacementFor(m Module) (Module, bool) {
+	var ret Module
+	var found bool
+	for _, rep := range f.Replace {
+		if rep.Old.Path != m.Path {
+			continue
+		}
+		if rep.Old.Version == m.Version {
+			return rep.New, true
+		}
+		if rep.Old.Version == "" {
+			ret, found = rep.New, true
+		}
+	}
+	return ret, found
+}
+
+// Constraint returns the semver constraint, in the form accepted by
+// gps.NewSemverConstraintIC, that the requirement places on its module. It is
+// empty if the requirement is not on a tagged version.
+func (r Requirement) Constraint() string {
+	if r.Version == "" || IsPseudoVersion(r.Version) {
+		return ""
+	}
+
+	c := "^" + Tag(r.Version)
+	for _, ex := range r.Excluded {
+		c += ", !=" + Tag(ex)
+	}
+	return c
+}
+
+// IsExcluded reports whether the version of the requirement is itself
+// excluded.
+func (r Requirement) IsExcluded() bool {
+	for _, ex := range r.Excluded {
+		if ex == r.Version {
+			return true
+		}
+	}
+	return false
+}
+
+var pseudoVersionRE = regexp.MustCompile(`^v[0-9]+\.[0-9]+\.[0-9]+-(?:[0-9A-Za-z.-]*\.)?[0-9]{14}-([0-9a-f]{12})(?:\+incompatible)?$`)
+
+// IsPseudoVersion reports whether v is a pseudo-version, which identifies an
+// untagged revision, such as v0.0.0-20180311230137-0a2b4bff4b6e.
+func IsPseudoVersion(v string) bool {
+	return pseudoVersionRE.MatchString(v)
+}
+
+// PseudoVersionRev returns the abbreviated revision identified by the
+// pseudo-version v, or an empty string if v is not a pseudo-version.
+func PseudoVersionRev(v string) string {
+	m := pseudoVersionRE.FindStringSubmatch(v)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// Tag returns the tag that the version v refers to, by dropping the
+// +incompatible suffix that marks major versions 2 and above of modules
+// without a go.mod file.
+func Tag(v string) string {
+	return strings.TrimSuffix(v, "+incompatible")
+}
+
+// IsLocalPath reports whether the replacement path refers to a directory,
+// rather than a module.
+func IsLocalPath(path string) bool {
+	return path == "." || path == ".." ||
+		strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../") ||
+		strings.HasPrefix(path, "/") || strings.HasPrefix(path, `.\`) ||
+		strings.HasPrefix(path, `..\`) || (len(path) > 1 && path[1] == ':')
+}
+
+var majorSuffixRE = regexp.MustCompile(`/v([2-9]|[1-9][0-9]+)$`)
+
+// TrimMajorSuffix drops the /vN suffix that distinguishes major versions 2 and
+// above of a module, so that the path matches the repository root. gopkg.in
+// paths, which carry their major version differently, are left as they are.
+func TrimMajorSuffix(path string) string {
+	if strings.HasPrefix(path, "gopkg.in/") {
+		return path
+	}
+	return majorSuffixRE.ReplaceAllString(path, "")
+}
+
+// Format returns the content of the go.mod file describing f. Directives with
+// more than one entry are grouped into blocks.
+func (f *File) Format() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "module %s\n", f.Module)
+
+	writeDirective := func(verb string, lines []string) {
+		switch len(lines) {
+		case 0:
+			return
+		case 1:
+			fmt.Fprintf(&buf, "\n%s %s\n", verb, lines[0])
+		default:
+			fmt.Fprintf(&buf, "\n%s (\n", verb)
+			for _, line := range lines {
+				fmt.Fprintf(&buf, "\t%s\n", line)
+			}
+			buf.WriteString(")\n")
+		}
+	}
+
+	var lines []string
+	for _, m := range f.Require {
+		lines = append(lines, m.String())
+	}
+	writeDirective("require", lines)
+
+	lines = nil
+	for _, m := range f.Exclude {
+		lines = append(lines, m.String())
+	}
+	writeDirective("exclude", lines)
+
+	lines = nil
+	for _, rep := range f.Replace {
+		lines = append(lines, rep.Old.String()+" => "+rep.New.String())
+	}
+	writeDirective("replace", lines)
+
+	return buf.Bytes()
+}
@@ -0,0 +1,234 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modfile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testModFile = `// A comment.
+module "github.com/foo/bar"
+
+go 1.11
+
+require (
+	github.com/pkg/errors v0.8.0
+	github.com/sdboyer/deptest v1.0.0 // indirect
+	github.com/sdboyer/deptestdos/v2 v2.0.0
+	golang.org/x/net v0.0.0-20180724234803-3673e40ba225
+	gopkg.in/yaml.v2 v2.2.1
+)
+
+require github.com/golang/protobuf v1.1.0+incompatible
+
+exclude (
+	github.com/sdboyer/deptest v1.0.1
+	github.com/sdboyer/deptest v1.0.2
+)
+
+replace github.com/pkg/errors => github.com/fork/errors v0.8.1
+replace (
+	gopkg.in/yaml.v2 v2.2.0 => gopkg.in/yaml.v2 v2.2.2
+	golang.org/x/net => ../net
+)
+`
+
+func TestParse(t *testing.T) {
+	f, err := Parse(strings.NewReader(testModFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &File{
+		Module: "github.com/foo/bar",
+		Require: []Module{
+			{"github.com/pkg/errors", "v0.8.0"},
+			{"github.com/sdboyer/deptest", "v1.0.0"},
+			{"github.com/sdboyer/deptestdos/v2", "v2.0.0"},
+			{"golang.org/x/net", "v0.0.0-20180724234803-3673e40ba225"},
+			{"gopkg.in/yaml.v2", "v2.2.1"},
+			{"github.com/golang/protobuf", "v1.1.0+incompatible"},
+		},
+		Exclude: []Module{
+			{"github.com/sdboyer/deptest", "v1.0.1"},
+			{"github.com/sdboyer/deptest", "v1.0.2"},
+		},
+		Replace: []Replace{
+			{Old: Module{Path: "github.com/pkg/errors"}, New: Module{"github.com/fork/errors", "v0.8.1"}},
+			{Old: Module{"gopkg.in/yaml.v2", "v2.2.0"}, New: Module{"gopkg.in/yaml.v2", "v2.2.2"}},
+			{Old: Module{Path: "golang.org/x/net"}, New: Module{Path: "../net"}},
+		},
+	}
+	if !reflect.DeepEqual(f, want) {
+		t.Fatalf("unexpected file:\n\t(GOT): %+v\n\t(WNT): %+v", f, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := map[string]string{
+		"require without version": "require github.com/pkg/errors\n",
+		"unterminated block":      "require (\n\tgithub.com/pkg/errors v0.8.0\n",
+		"unterminated string":     "module \"github.com/foo/bar\n",
+		"bad replace":             "replace github.com/pkg/errors github.com/fork/errors\n",
+		"unversioned replacement": "replace github.com/pkg/errors => github.com/fork/errors\n",
+	}
+
+	for name, mod := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(mod)); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestRequirements(t *testing.T) {
+	f, err := Parse(strings.NewReader(testModFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Requirement{
+		{Path: "github.com/pkg/errors", Version: "v0.8.1", Source: "github.com/fork/errors"},
+		{Path: "github.com/sdboyer/deptest", Version: "v1.0.0", Excluded: []string{"v1.0.1", "v1.0.2"}},
+		{Path: "github.com/sdboyer/deptestdos/v2", Version: "v2.0.0"},
+		{Path: "golang.org/x/net", Local: "../net"},
+		{Path: "gopkg.in/yaml.v2", Version: "v2.2.1"},
+		{Path: "github.com/golang/protobuf", Version: "v1.1.0+incompatible"},
+	}
+	got := f.Requirements()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected requirements:\n\t(GOT): %+v\n\t(WNT): %+v", got, want)
+	}
+
+	constraints := []string{
+		"^v0.8.1",
+		"^v1.0.0, !=v1.0.1, !=v1.0.2",
+		"^v2.0.0",
+		"",
+		"^v2.2.1",
+		"^v1.1.0",
+	}
+	for i, req := range got {
+		if c := req.Constraint(); c != constraints[i] {
+			t.Errorf("unexpected constraint for %s: %q, want %q", req.Path, c, constraints[i])
+		}
+	}
+}
+
+func TestRequirementIsExcluded(t *testing.T) {
+	req := Requirement{Path: "github.com/foo/bar", Version: "v1.0.1", Excluded: []string{"v1.0.1"}}
+	if !req.IsExcluded() {
+		t.Error("expected v1.0.1 to be excluded")
+	}
+	req.Version = "v1.0.0"
+	if req.IsExcluded() {
+		t.Error("expected v1.0.0 not to be excluded")
+	}
+}
+
+func TestPseudoVersions(t *testing.T) {
+	cases := map[string]string{
+		"v0.0.0-20180724234803-3673e40ba225":              "3673e40ba225",
+		"v1.2.4-0.20180724234803-3673e40ba225":            "3673e40ba225",
+		"v1.2.3-pre.0.20180724234803-3673e40ba225":        "3673e40ba225",
+		"v2.0.0-20180724234803-3673e40ba225+incompatible": "3673e40ba225",
+		"v1.2.3":              "",
+		"v1.2.3-pre":          "",
+		"v1.2.3+incompatible": "",
+	}
+
+	for v, rev := range cases {
+		if got := PseudoVersionRev(v); got != rev {
+			t.Errorf("PseudoVersionRev(%q) = %q, want %q", v, got, rev)
+		}
+		if got := IsPseudoVersion(v); got != (rev != "") {
+			t.Errorf("IsPseudoVersion(%q) = %v", v, got)
+		}
+	}
+}
+
+func TestIsLocalPath(t *testing.T) {
+	cases := map[string]bool{
+		"../fork":              true,
+		"./fork":               true,
+		"/src/fork":            true,
+		`C:\src\fork`:          true,
+		"github.com/foo/bar":   false,
+		"gopkg.in/yaml.v2":     false,
+		"example.com/../thing": false,
+	}
+
+	for path, want := range cases {
+		if got := IsLocalPath(path); got != want {
+			t.Errorf("IsLocalPath(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestTrimMajorSuffix(t *testing.T) {
+	cases := map[string]string{
+		"github.com/foo/bar":     "github.com/foo/bar",
+		"github.com/foo/bar/v2":  "github.com/foo/bar",
+		"github.com/foo/bar/v10": "github.com/foo/bar",
+		"github.com/foo/bar/v1":  "github.com/foo/bar/v1",
+		"github.com/foo/v2/bar":  "github.com/foo/v2/bar",
+		"gopkg.in/foo/bar.v2":    "gopkg.in/foo/bar.v2",
+		"gopkg.in/foo/v2":        "gopkg.in/foo/v2",
+	}
+
+	for path, want := range cases {
+		if got := TrimMajorSuffix(path); got != want {
+			t.Errorf("TrimMajorSuffix(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	f := &File{
+		Module: "github.com/foo/bar",
+		Require: []Module{
+			{"github.com/pkg/errors", "v0.8.0"},
+			{"golang.org/x/net", "v0.0.0-20180724234803-3673e40ba225"},
+		},
+		Exclude: []Module{
+			{"github.com/pkg/errors", "v0.8.1"},
+		},
+		Replace: []Replace{
+			{Old: Module{Path: "github.com/pkg/errors"}, New: Module{"github.com/fork/errors", "v0.8.0"}},
+			{Old: Module{"golang.org/x/net", "v0.0.0-20180724234803-3673e40ba225"}, New: Module{Path: "../net"}},
+		},
+	}
+
+	want := `module github.com/foo/bar
+
+require (
+	github.com/pkg/errors v0.8.0
+	golang.org/x/net v0.0.0-20180724234803-3673e40ba225
+)
+
+exclude github.com/pkg/errors v0.8.1
+
+replace (
+	github.com/pkg/errors => github.com/fork/errors v0.8.0
+	golang.org/x/net v0.0.0-20180724234803-3673e40ba225 => ../net
+)
+`
+	got := string(f.Format())
+	if got != want {
+		t.Fatalf("unexpected go.mod:\n\t(GOT):\n%s\n\t(WNT):\n%s", got, want)
+	}
+
+	// The formatted file must parse back to the same content.
+	parsed, err := Parse(strings.NewReader(got))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, f) {
+		t.Fatalf("formatted file did not round trip:\n\t(GOT): %+v\n\t(WNT): %+v", parsed, f)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modfile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SumFileName is the name of the file holding the checksums of the modules
+// that a Go module depends on.
+const SumFileName = "go.sum"
+
+// Sum is what go.sum records of a module version: the hash of its module zip,
+// and that of its go.mod file.
+type Sum struct {
+	Module    Module
+	Hash      string
+	GoModHash string
+}
+
+// TreeSum computes the Sum of the module version m, whose tree is in dir.
+//
+// The files hashed are those the go command puts in the module zip: nested
+// modules, the packages in vendor directories, and files that are not regular
+// are left out. A module without a go.mod file has the one the go command
+// makes up for it.
+func TreeSum(m Module, dir string) (Sum, error) {
+	files := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if isVendoredPackage(rel) {
+			return nil
+		}
+		if fi.IsDir() {
+			if path == dir {
+				return nil
+			}
+			switch fi.Name() {
+			case ".bzr", ".git", ".hg", ".svn":
+				return filepath.SkipDir
+			}
+			if gfi, err := os.Lstat(filepath.Join(path, FileName)); err == nil && !gfi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if fi.Mode().IsRegular() {
+			files[m.Path+"@"+m.Version+"/"+rel] = path
+		}
+		return nil
+	})
+	if err != nil {
+		return Sum{}, errors.Wrapf(err, "failed to list the files of %s", m)
+	}
+
+	h, err := hash1(files)
+	if err != nil {
+		return Sum{}, errors.Wrapf(err, "failed to hash the files of %s", m)
+	}
+
+	gomod, err := ioutil.ReadFile(filepath.Join(dir, FileName))
+	if os.IsNotExist(err) {
+		gomod, err = []byte(fmt.Sprintf("module %s\n", m.Path)), nil
+	}
+	if err != nil {
+		return Sum{}, errors.Wrapf(err, "failed to read the %s file of %s", FileName, m)
+	}
+	return Sum{Module: m, Hash: h, GoModHash: hashGoMod(gomod)}, nil
+}
+
+// isVendoredPackage reports whether the file or directory, named relative to
+// the module root, is within a package in a vendor directory. Files directly
+// in a vendor directory, such as vendor/modules.txt, are not.
+//
+// It matches the go command up to Go 1.23, which checksums predate.
+func isVendoredPackage(name string) bool {
+	var i int
+	if strings.HasPrefix(name, "vendor/") {
+		i += len("vendor/")
+	} else if j := strings.Index(name, "/vendor/"); j >= 0 {
+		i += len("/vendor/")
+	} else {
+		return false
+	}
+	return strings.Contains(name[i:], "/")
+}
+
+// hash1 computes the "h1:" hash of files, which maps the names they are known
+// by to their paths: the sha256 of a summary listing the sha256 of each file,
+// along with its name.
+func hash1(files map[string]string) (string, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		if strings.Contains(name, "\n") {
+			return "", errors.Errorf("file name %q contains a newline", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	summary := sha256.New()
+	for _, name := range names {
+		f, err := os.Open(files[name])
+		if err != nil {
+			return "", err
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(summary, "%x  %s\n", h.Sum(nil), name)
+	}
+	return "h1:" + base64.StdEncoding.EncodeToString(summary.Sum(nil)), nil
+}
+
+// hashGoMod computes the "h1:" hash of the go.mod file of a module.
+func hashGoMod(data []byte) string {
+	summary := sha256.New()
+	fmt.Fprintf(summary, "%x  %s\n", sha256.Sum256(data), FileName)
+	return "h1:" + base64.StdEncoding.EncodeToString(summary.Sum(nil))
+}
+
+// FormatSums returns the content of the go.sum file holding sums, ordered by
+// module path and version.
+func FormatSums(sums []Sum) []byte {
+	sorted := append([]Sum(nil), sums...)
+	sort.Slice(sorted, func(i, j int) bool {
+		mi, mj := sorted[i].Module, sorted[j].Module
+		if mi.Path != mj.Path {
+			return mi.Path < mj.Path
+		}
+		return mi.Version < mj.Version
+	})
+
+	var buf bytes.Buffer
+	for i, s := range sorted {
+		if i > 0 && s.Module == sorted[i-1].Module {
+			continue
+		}
+		fmt.Fprintf(&buf, "%s %s %s\n", s.Module.Path, s.Module.Version, s.Hash)
+		fmt.Fprintf(&buf, "%s %s/%s %s\n", s.Module.Path, s.Module.Version, FileName, s.GoModHash)
+	}
+	return buf.Bytes()
+}
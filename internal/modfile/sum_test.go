@@ -0,0 +1,88 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modfile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/golang/dep/internal/test"
+)
+
+func TestTreeSum(t *testing.T) {
+	h := test.NewHelper(t)
+	h.Parallel()
+	defer h.Cleanup()
+
+	// The expected hashes are those the go command gives module zips of the
+	// files that are not left out.
+	cases := []struct {
+		m         Module
+		gomod     string
+		hash      string
+		gomodHash string
+	}{
+		{
+			m:         Module{Path: "example.com/foo", Version: "v1.0.0"},
+			hash:      "h1:oWY4RTy8ricsBClJip422wq8MWYSSiQSN2aoEvel7QY=",
+			gomodHash: "h1:tJ2YS1a8pyA3nrypRdbsq6Ias2I/0YUVbjNBUoLstcw=",
+		},
+		{
+			m:         Module{Path: "example.com/bar", Version: "v1.2.0"},
+			gomod:     "module example.com/bar\n\ngo 1.12\n",
+			hash:      "h1:DGaz2CPZbFx0Ctb7bnC1UjNU8rGtU0JHmmNIQrgPqlg=",
+			gomodHash: "h1:tIY1kQZyk/FNhKvKd3hdf4dIVfk35Q5yYfSEpKT2BUE=",
+		},
+	}
+	for _, c := range cases {
+		dir := c.m.Path
+		h.TempFile(dir+"/a.go", "package foo\n")
+		h.TempFile(dir+"/sub/b.go", "package sub\n")
+		h.TempFile(dir+"/vendor/modules.txt", "# modules\n")
+		if c.gomod != "" {
+			h.TempFile(dir+"/"+FileName, c.gomod)
+		}
+
+		// None of these are in the module zip.
+		h.TempFile(dir+"/vendor/example.com/dep/dep.go", "package dep\n")
+		h.TempFile(dir+"/sub/vendor/example.com/dep/dep.go", "package dep\n")
+		h.TempFile(dir+"/nested/"+FileName, "module example.com/nested\n")
+		h.TempFile(dir+"/nested/c.go", "package nested\n")
+		h.TempFile(dir+"/.git/config", "[core]\n")
+		if runtime.GOOS != "windows" {
+			h.Must(os.Symlink("a.go", filepath.Join(h.Path(dir), "link.go")))
+		}
+
+		s, err := TreeSum(c.m, h.Path(dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Module != c.m || s.Hash != c.hash || s.GoModHash != c.gomodHash {
+			t.Errorf("unexpected sum of %s:\n\t(GOT): %+v\n\t(WNT): %s %s", c.m, s, c.hash, c.gomodHash)
+		}
+	}
+}
+
+func TestFormatSums(t *testing.T) {
+	sums := []Sum{
+		{Module: Module{Path: "github.com/foo/bar", Version: "v1.0.0"}, Hash: "h1:bar=", GoModHash: "h1:barmod="},
+		{Module: Module{Path: "github.com/foo/baz", Version: "v0.0.0-20180301113005-0123456789ab"}, Hash: "h1:baz=", GoModHash: "h1:bazmod="},
+		{Module: Module{Path: "github.com/foo/bar", Version: "v1.0.0"}, Hash: "h1:bar=", GoModHash: "h1:barmod="},
+		{Module: Module{Path: "github.com/foo/bar", Version: "v0.9.0"}, Hash: "h1:old=", GoModHash: "h1:oldmod="},
+	}
+
+	want := `github.com/foo/bar v0.9.0 h1:old=
+github.com/foo/bar v0.9.0/go.mod h1:oldmod=
+github.com/foo/bar v1.0.0 h1:bar=
+github.com/foo/bar v1.0.0/go.mod h1:barmod=
+github.com/foo/baz v0.0.0-20180301113005-0123456789ab h1:baz=
+github.com/foo/baz v0.0.0-20180301113005-0123456789ab/go.mod h1:bazmod=
+`
+	if got := string(FormatSums(sums)); got != want {
+		t.Errorf("unexpected go.sum:\n\t(GOT):\n%s\n\t(WNT):\n%s", got, want)
+	}
+}
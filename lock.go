@@ -9,6 +9,7 @@ import (
 	"encoding/hex"
 	"io"
 	"sort"
+	"strings"
 
 	"github.com/golang/dep/gps"
 	"github.com/pelletier/go-toml"
@@ -47,12 +48,54 @@ type solveMeta struct {
 }
 
 type rawLockedProject struct {
-	Name     string   `toml:"name"`
-	Branch   string   `toml:"branch,omitempty"`
-	Revision string   `toml:"revision"`
-	Version  string   `toml:"version,omitempty"`
-	Source   string   `toml:"source,omitempty"`
-	Packages []string `toml:"packages"`
+	Name      string   `toml:"name"`
+	Branch    string   `toml:"branch,omitempty"`
+	Revision  string   `toml:"revision"`
+	Version   string   `toml:"version,omitempty"`
+	Source    string   `toml:"source,omitempty"`
+	Packages  []string `toml:"packages"`
+	PruneOpts string   `toml:"pruneopts,omitempty"`
+	Digest    string   `toml:"digest,omitempty"`
+}
+
+// vendorDigestVersion is the version of the algorithm used to compute the
+// vendor digests recorded in the lock. It is written as a prefix of each
+// digest, so that digests computed by a different algorithm are never
+// mistaken for current ones.
+const vendorDigestVersion = "1"
+
+// parseVendorDigest parses a digest as written in the lock. Digests written
+// with an unknown algorithm version are treated as absent.
+func parseVendorDigest(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return nil, errors.Errorf("invalid vendor digest %q", s)
+	}
+	if parts[0] != vendorDigestVersion {
+		return nil, nil
+	}
+
+	digest, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.Errorf("invalid vendor digest %q", s)
+	}
+	return digest, nil
+}
+
+func formatVendorDigest(digest []byte) string {
+	if len(digest) == 0 {
+		return ""
+	}
+	return vendorDigestVersion + ":" + hex.EncodeToString(digest)
+}
+
+// ReadLock reads a Lock from r, which holds the contents of a Gopkg.lock file.
+func ReadLock(r io.Reader) (*Lock, error) {
+	return readLock(r)
 }
 
 func readLock(r io.Reader) (*Lock, error) {
@@ -107,6 +150,18 @@ func fromRawLock(raw rawLock) (*Lock, error) {
 			Source:      ld.Source,
 		}
 		l.P[i] = gps.NewLockedProject(id, v, ld.Packages)
+
+		if ld.Digest != "" || ld.PruneOpts != "" {
+			opts, err := gps.ParsePruneOptions(ld.PruneOpts)
+			if err != nil {
+				return nil, errors.Wrapf(err, "lock file has invalid prune options for %s", ld.Name)
+			}
+			digest, err := parseVendorDigest(ld.Digest)
+			if err != nil {
+				return nil, errors.Wrapf(err, "lock file has invalid digest for %s", ld.Name)
+			}
+			l.P[i] = l.P[i].WithVendorDigest(opts, digest)
+		}
 	}
 
 	return l, nil
@@ -164,6 +219,11 @@ func (l *Lock) toRaw() rawLock {
 		v := lp.Version()
 		ld.Revision, ld.Branch, ld.Version = gps.VersionComponentStrings(v)
 
+		if digest := lp.VendorDigest(); len(digest) > 0 {
+			ld.PruneOpts = lp.PruneOptions().String()
+			ld.Digest = formatVendorDigest(digest)
+		}
+
 		raw.Projects[k] = ld
 	}
 
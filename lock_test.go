@@ -138,6 +138,64 @@ func TestWriteLock(t *testing.T) {
 	}
 }
 
+func TestLockVendorDigest(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	golden := "lock/golden2.toml"
+	want := h.GetTestFileString(golden)
+
+	memo, _ := hex.DecodeString("2252a285ab27944a4d7adcba8dbd03980f59ba652f12db39fa93b927c345593e")
+	digest, _ := hex.DecodeString("9a8e4f0f0b1c6fd7bd4b3e5ad1e1c60a3c0b4c3b5b1e4d0e8a4c6f8b2d0e1f3a")
+	l := &Lock{
+		SolveMeta: SolveMeta{
+			InputsDigest: memo,
+		},
+		P: []gps.LockedProject{
+			gps.NewLockedProject(
+				gps.ProjectIdentifier{ProjectRoot: gps.ProjectRoot("github.com/golang/dep")},
+				gps.NewVersion("0.12.2").Pair(gps.Revision("d05d5aca9f895d19e9265839bffeadd74a2d2ecb")),
+				[]string{"."},
+			).WithVendorDigest(gps.PruneNestedVendorDirs|gps.PruneUnusedPackages|gps.PruneGoTestFiles, digest),
+		},
+	}
+
+	got, err := l.MarshalTOML()
+	if err != nil {
+		t.Fatalf("Error while marshaling valid lock to TOML: %q", err)
+	}
+
+	if string(got) != want {
+		if *test.UpdateGolden {
+			if err = h.WriteTestFile(golden, string(got)); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			t.Errorf("Valid lock did not marshal to TOML as expected:\n\t(GOT): %s\n\t(WNT): %s", string(got), want)
+		}
+	}
+
+	lf := h.GetTestFile(golden)
+	defer lf.Close()
+	rl, err := readLock(lf)
+	if err != nil {
+		t.Fatalf("Should have read Lock correctly, but got err %q", err)
+	}
+
+	if !reflect.DeepEqual(rl, l) {
+		t.Errorf("Lock with vendor digests did not round trip:\n\t(GOT): %#v\n\t(WNT): %#v", rl, l)
+	}
+
+	// Digests computed with an unknown algorithm are treated as absent.
+	got, err = parseVendorDigest("2:9a8e4f0f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Errorf("expected digest with unknown version to be ignored, got %x", got)
+	}
+}
+
 func TestReadLockErrors(t *testing.T) {
 	h := test.NewHelper(t)
 	defer h.Cleanup()
@@ -150,6 +208,8 @@ func TestReadLockErrors(t *testing.T) {
 		{"specified both", "lock/error0.toml"},
 		{"invalid hash", "lock/error1.toml"},
 		{"no branch or version", "lock/error2.toml"},
+		{"invalid prune options", "lock/error3.toml"},
+		{"invalid digest", "lock/error4.toml"},
 	}
 
 	for _, tst := range tests {
@@ -8,9 +8,11 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"path/filepath"
 	"reflect"
 	"regexp"
 	"sort"
+	"strings"
 	"sync"
 
 	"github.com/golang/dep/gps"
@@ -28,6 +30,8 @@ var (
 	errInvalidOverride     = errors.Errorf("%q must be a TOML array of tables", "override")
 	errInvalidRequired     = errors.Errorf("%q must be a TOML list of strings", "required")
 	errInvalidIgnored      = errors.Errorf("%q must be a TOML list of strings", "ignored")
+	errInvalidPlatforms    = errors.Errorf("%q must be a TOML list of strings", "platforms")
+	errInvalidBuildTags    = errors.Errorf("%q must be a TOML list of strings", "build-tags")
 	errInvalidPrune        = errors.Errorf("%q must be a TOML table of booleans", "prune")
 	errInvalidPruneProject = errors.Errorf("%q must be a TOML array of tables", "prune.project")
 	errInvalidMetadata     = errors.New("metadata should be a TOML table")
@@ -41,6 +45,8 @@ var (
 	errInvalidRootPruneValue   = errors.New("root prune options must be omitted instead of being set to false")
 	errInvalidPruneProjectName = errors.Errorf("%q in %q must be a string", "name", "prune.project")
 	errNoName                  = errors.New("no name provided")
+
+	errBuildTagsWithoutPlatforms = errors.Errorf("%q has no effect unless %q is also set", "build-tags", "platforms")
 )
 
 // Manifest holds manifest file data and implements gps.RootManifest.
@@ -51,7 +57,16 @@ type Manifest struct {
 	Ignored  []string
 	Required []string
 
+	// TargetPlatforms lists the platforms the project is built for, as GOOS or
+	// GOOS/GOARCH, and BuildTags the build tags set on each of them.
+	TargetPlatforms []string
+	BuildTags       []string
+
 	PruneOptions gps.CascadingPruneOptions
+
+	// relativeSources maps the absolute paths that relative local sources
+	// were resolved to back to the paths as they were written.
+	relativeSources map[string]string
 }
 
 type rawManifest struct {
@@ -59,6 +74,8 @@ type rawManifest struct {
 	Overrides    []rawProject    `toml:"override,omitempty"`
 	Ignored      []string        `toml:"ignored,omitempty"`
 	Required     []string        `toml:"required,omitempty"`
+	Platforms    []string        `toml:"platforms,omitempty"`
+	BuildTags    []string        `toml:"build-tags,omitempty"`
 	PruneOptions rawPruneOptions `toml:"prune,omitempty"`
 }
 
@@ -182,7 +199,7 @@ func validateManifest(s string) ([]error, error) {
 					return warns, errInvalidOverride
 				}
 			}
-		case "ignored", "required":
+		case "ignored", "required", "platforms", "build-tags":
 			valid := true
 			if rawList, ok := val.([]interface{}); ok {
 				// Check element type of the array. TOML doesn't let mixing of types in
@@ -201,6 +218,12 @@ func validateManifest(s string) ([]error, error) {
 				if prop == "required" {
 					return warns, errInvalidRequired
 				}
+				if prop == "platforms" {
+					return warns, errInvalidPlatforms
+				}
+				if prop == "build-tags" {
+					return warns, errInvalidBuildTags
+				}
 			}
 		case "prune":
 			pruneWarns, err := validatePruneOptions(val, true)
@@ -358,6 +381,9 @@ func readManifest(r io.Reader) (*Manifest, []error, error) {
 	}
 
 	warns = append(warns, checkRedundantPruneOptions(m.PruneOptions)...)
+	if len(m.BuildTags) > 0 && len(m.TargetPlatforms) == 0 {
+		warns = append(warns, errBuildTagsWithoutPlatforms)
+	}
 	return m, warns, nil
 }
 
@@ -368,6 +394,14 @@ func fromRawManifest(raw rawManifest, buf *bytes.Buffer) (*Manifest, error) {
 	m.Ovr = make(gps.ProjectConstraints, len(raw.Overrides))
 	m.Ignored = raw.Ignored
 	m.Required = raw.Required
+	m.TargetPlatforms = raw.Platforms
+	m.BuildTags = raw.BuildTags
+
+	for _, plat := range m.TargetPlatforms {
+		if _, err := pkgtree.ParsePlatform(plat); err != nil {
+			return nil, err
+		}
+	}
 
 	for i := 0; i < len(raw.Constraints); i++ {
 		name, prj, err := toProject(raw.Constraints[i])
@@ -512,10 +546,48 @@ func toProject(raw rawProject) (n gps.ProjectRoot, pp gps.ProjectProperties, err
 	}
 
 	pp.Source = raw.Source
+	if isLocalSource(pp.Source) && (raw.Branch != "" || raw.Version != "" || raw.Revision != "") {
+		return n, pp, errors.Errorf("%s is sourced from the local directory %s, so it cannot be constrained to a branch, version or revision", n, pp.Source)
+	}
 
 	return n, pp, nil
 }
 
+// isLocalSource reports whether a source given in the manifest refers to a
+// directory on the local filesystem.
+func isLocalSource(source string) bool {
+	return isRelativeSource(source) || gps.IsLocalSource(source)
+}
+
+// isRelativeSource reports whether a source given in the manifest is a path
+// to a local directory, relative to the project root.
+func isRelativeSource(source string) bool {
+	source = filepath.ToSlash(source)
+	return source == "." || source == ".." ||
+		strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")
+}
+
+// resolveLocalSources makes the sources that are paths relative to the project
+// root absolute, as gps requires. The paths as written are kept, so that they
+// are preserved when the manifest is written back.
+func (m *Manifest) resolveLocalSources(root string) {
+	for _, pcs := range []gps.ProjectConstraints{m.Constraints, m.Ovr} {
+		for pr, pp := range pcs {
+			if !isRelativeSource(pp.Source) {
+				continue
+			}
+
+			abs := filepath.Join(root, filepath.FromSlash(pp.Source))
+			if m.relativeSources == nil {
+				m.relativeSources = make(map[string]string)
+			}
+			m.relativeSources[abs] = pp.Source
+			pp.Source = abs
+			pcs[pr] = pp
+		}
+	}
+}
+
 // MarshalTOML serializes this manifest into TOML via an intermediate raw form.
 func (m *Manifest) MarshalTOML() ([]byte, error) {
 	raw := m.toRaw()
@@ -532,15 +604,17 @@ func (m *Manifest) toRaw() rawManifest {
 		Overrides:   make([]rawProject, 0, len(m.Ovr)),
 		Ignored:     m.Ignored,
 		Required:    m.Required,
+		Platforms:   m.TargetPlatforms,
+		BuildTags:   m.BuildTags,
 	}
 
 	for n, prj := range m.Constraints {
-		raw.Constraints = append(raw.Constraints, toRawProject(n, prj))
+		raw.Constraints = append(raw.Constraints, m.toRawProject(n, prj))
 	}
 	sort.Sort(sortedRawProjects(raw.Constraints))
 
 	for n, prj := range m.Ovr {
-		raw.Overrides = append(raw.Overrides, toRawProject(n, prj))
+		raw.Overrides = append(raw.Overrides, m.toRawProject(n, prj))
 	}
 	sort.Sort(sortedRawProjects(raw.Overrides))
 
@@ -566,6 +640,16 @@ func (s sortedRawProjects) Less(i, j int) bool {
 	return l.Source < r.Source
 }
 
+// toRawProject converts a project for the manifest file, restoring its source
+// to the relative path it was written as, if need be.
+func (m *Manifest) toRawProject(name gps.ProjectRoot, project gps.ProjectProperties) rawProject {
+	raw := toRawProject(name, project)
+	if rel, ok := m.relativeSources[raw.Source]; ok {
+		raw.Source = rel
+	}
+	return raw
+}
+
 func toRawProject(name gps.ProjectRoot, project gps.ProjectProperties) rawProject {
 	raw := rawProject{
 		Name:   string(name),
@@ -612,6 +696,28 @@ func (m *Manifest) IgnoredPackages() *pkgtree.IgnoredRuleset {
 	return pkgtree.NewIgnoredRuleset(m.Ignored)
 }
 
+var _ gps.PlatformManifest = &Manifest{}
+
+// Platforms returns the platforms the project is built for, with the build
+// tags in the manifest set on each of them. Platforms that cannot be parsed
+// are skipped; readManifest rejects them.
+func (m *Manifest) Platforms() []pkgtree.Platform {
+	if len(m.TargetPlatforms) == 0 {
+		return nil
+	}
+
+	plats := make([]pkgtree.Platform, 0, len(m.TargetPlatforms))
+	for _, s := range m.TargetPlatforms {
+		plat, err := pkgtree.ParsePlatform(s)
+		if err != nil {
+			continue
+		}
+		plat.Tags = m.BuildTags
+		plats = append(plats, plat)
+	}
+	return plats
+}
+
 // HasConstraintsOn checks if the manifest contains either constraints or
 // overrides on the provided ProjectRoot.
 func (m *Manifest) HasConstraintsOn(root gps.ProjectRoot) bool {
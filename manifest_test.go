@@ -10,11 +10,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/pkgtree"
 	"github.com/golang/dep/internal/test"
 )
 
@@ -133,6 +135,79 @@ func TestReadManifestErrors(t *testing.T) {
 	}
 }
 
+func TestReadManifestPlatforms(t *testing.T) {
+	m, warns, err := readManifest(strings.NewReader(`
+platforms = ["linux/amd64", "windows"]
+build-tags = ["appengine"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) > 0 {
+		t.Fatalf("unexpected warnings: %v", warns)
+	}
+
+	want := []pkgtree.Platform{
+		{GOOS: "linux", GOARCH: "amd64", Tags: []string{"appengine"}},
+		{GOOS: "windows", Tags: []string{"appengine"}},
+	}
+	if got := m.Platforms(); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected platforms:\n\t(GOT): %+v\n\t(WNT): %+v", got, want)
+	}
+
+	if _, _, err := readManifest(strings.NewReader(`platforms = ["linux/vax"]`)); err == nil {
+		t.Error("expected an unknown GOARCH to be rejected")
+	}
+
+	_, warns, err = readManifest(strings.NewReader(`build-tags = ["appengine"]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warns) != 1 || warns[0] != errBuildTagsWithoutPlatforms {
+		t.Errorf("expected a warning about build tags without platforms, got %v", warns)
+	}
+}
+
+func TestReadManifestLocalSources(t *testing.T) {
+	m, _, err := readManifest(strings.NewReader(`
+[[override]]
+  name = "github.com/foo/bar"
+  source = "../bar"
+
+[[constraint]]
+  name = "github.com/foo/baz"
+  source = "/src/baz"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := filepath.FromSlash("/src/project")
+	m.resolveLocalSources(root)
+	if got, want := m.Ovr["github.com/foo/bar"].Source, filepath.Join(root, "..", "bar"); got != want {
+		t.Errorf("unexpected source %q for a relative path; wanted %q", got, want)
+	}
+	if got := m.Constraints["github.com/foo/baz"].Source; got != "/src/baz" {
+		t.Errorf("unexpected source %q for an absolute path", got)
+	}
+
+	// The relative path must be written back as it was.
+	raw := m.toRaw()
+	if got := raw.Overrides[0].Source; got != "../bar" {
+		t.Errorf("relative source written back as %q", got)
+	}
+
+	_, _, err = readManifest(strings.NewReader(`
+[[constraint]]
+  name = "github.com/foo/bar"
+  source = "../bar"
+  version = "1.0.0"
+`))
+	if err == nil {
+		t.Error("expected a version on a local source to be rejected")
+	}
+}
+
 func TestValidateManifest(t *testing.T) {
 	cases := []struct {
 		name       string
@@ -197,6 +272,31 @@ func TestValidateManifest(t *testing.T) {
 			wantWarn:  []error{},
 			wantError: errInvalidIgnored,
 		},
+		{
+			name: "valid platforms and build tags",
+			tomlString: `
+			platforms = ["linux/amd64", "windows"]
+			build-tags = ["appengine"]
+			`,
+			wantWarn:  []error{},
+			wantError: nil,
+		},
+		{
+			name: "invalid platforms",
+			tomlString: `
+			platforms = "linux/amd64"
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidPlatforms,
+		},
+		{
+			name: "invalid build tags",
+			tomlString: `
+			build-tags = [1]
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidBuildTags,
+		},
 		{
 			name: "empty ignored",
 			tomlString: `
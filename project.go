@@ -149,10 +149,13 @@ func (p *Project) ParseRootPackageTree() (pkgtree.PackageTree, error) {
 			return pkgtree.PackageTree{}, errors.Wrap(err, "analysis of current project's packages failed")
 		}
 		// We don't care about (unreachable) hidden packages for the root project,
-		// so drop all of those.
+		// so drop all of those. Imports that are not made on any of the
+		// project's platforms go first, so that packages only they reach are
+		// dropped, too.
 		var ig *pkgtree.IgnoredRuleset
 		if p.Manifest != nil {
 			ig = p.Manifest.IgnoredPackages()
+			ptree = ptree.ForPlatforms(p.Manifest.Platforms())
 		}
 		p.RootPackageTree = ptree.TrimHiddenPackages(true, true, ig)
 	}
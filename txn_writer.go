@@ -11,8 +11,11 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/pkgtree"
 	"github.com/golang/dep/internal/fs"
 	"github.com/pelletier/go-toml"
 	"github.com/pkg/errors"
@@ -84,6 +87,9 @@ type SafeWriter struct {
 // - If oldLock is provided without newLock, error.
 //
 // - If vendor is VendorAlways without a newLock, error.
+//
+// Vendor digests recorded in oldLock are carried over to the projects in
+// newLock that are unchanged.
 func NewSafeWriter(manifest *Manifest, oldLock, newLock *Lock, vendor VendorBehavior, prune gps.CascadingPruneOptions) (*SafeWriter, error) {
 	sw := &SafeWriter{
 		Manifest:     manifest,
@@ -96,6 +102,10 @@ func NewSafeWriter(manifest *Manifest, oldLock, newLock *Lock, vendor VendorBeha
 			return nil, errors.New("must provide newLock when oldLock is specified")
 		}
 
+		if newLock != oldLock {
+			carryVendorDigests(oldLock, newLock)
+		}
+
 		sw.lockDiff = gps.DiffLocks(oldLock, newLock)
 		if sw.lockDiff != nil {
 			sw.writeLock = true
@@ -118,6 +128,22 @@ func NewSafeWriter(manifest *Manifest, oldLock, newLock *Lock, vendor VendorBeha
 	return sw, nil
 }
 
+// carryVendorDigests copies the prune options and vendor digests recorded in
+// from onto the equivalent projects in to, if they do not already have them.
+func carryVendorDigests(from, to *Lock) {
+	old := make(map[gps.ProjectRoot]gps.LockedProject, len(from.P))
+	for _, lp := range from.P {
+		old[lp.Ident().ProjectRoot] = lp
+	}
+
+	for i, lp := range to.P {
+		olp, has := old[lp.Ident().ProjectRoot]
+		if has && len(lp.VendorDigest()) == 0 && olp.Eq(lp) {
+			to.P[i] = lp.WithVendorDigest(olp.PruneOptions(), olp.VendorDigest())
+		}
+	}
+}
+
 // HasLock checks if a Lock is present in the SafeWriter
 func (sw *SafeWriter) HasLock() bool {
 	return sw.lock != nil
@@ -309,6 +335,20 @@ func (sw *SafeWriter) Write(root string, sm gps.SourceManager, examples bool, lo
 		}
 	}
 
+	// Vendor is written before the lock, so that the digests of the
+	// freshly written trees can be recorded in it.
+	var vu vendorUpdate
+	if sw.writeVendor {
+		var lockChanged bool
+		vu, lockChanged, err = sw.writeVendorTree(filepath.Join(td, "vendor"), vpath, sm, logger)
+		if err != nil {
+			return errors.Wrap(err, "error while writing out vendor tree")
+		}
+		if lockChanged {
+			sw.writeLock = true
+		}
+	}
+
 	if sw.writeLock {
 		l, err := sw.lock.MarshalTOML()
 		if err != nil {
@@ -320,21 +360,9 @@ func (sw *SafeWriter) Write(root string, sm gps.SourceManager, examples bool, lo
 		}
 	}
 
-	if sw.writeVendor {
-		var onWrite func(gps.WriteProgress)
-		if logger != nil {
-			onWrite = func(progress gps.WriteProgress) {
-				logger.Println(progress)
-			}
-		}
-		err = gps.WriteDepTree(filepath.Join(td, "vendor"), sw.lock, sm, sw.pruneOptions, onWrite)
-		if err != nil {
-			return errors.Wrap(err, "error while writing out vendor tree")
-		}
-	}
-
-	// Ensure vendor/.git is preserved if present
-	if hasDotGit(vpath) {
+	// Ensure vendor/.git is preserved if present. This is only necessary when
+	// the whole vendor dir is replaced.
+	if !vu.incremental && hasDotGit(vpath) {
 		err = fs.RenameWithFallback(filepath.Join(vpath, ".git"), filepath.Join(td, "vendor/.git"))
 		if _, ok := err.(*os.LinkError); ok {
 			return errors.Wrap(err, "failed to preserve vendor/.git")
@@ -347,6 +375,7 @@ func (sw *SafeWriter) Write(root string, sm gps.SourceManager, examples bool, lo
 		from, to string
 	}
 	var restore []pathpair
+	var remove []string
 	var failerr error
 	var vendorbak string
 
@@ -389,27 +418,56 @@ func (sw *SafeWriter) Write(root string, sm gps.SourceManager, examples bool, lo
 
 	if sw.writeVendor {
 		if _, err := os.Stat(vpath); err == nil {
-			// Move out the old vendor dir. just do it into an adjacent dir, to
-			// try to mitigate the possibility of a pointless cross-filesystem
-			// move with a temp directory.
+			// Move out the old vendor dir, or the parts of it that are being
+			// replaced. just do it into an adjacent dir, to try to mitigate the
+			// possibility of a pointless cross-filesystem move with a temp
+			// directory.
 			vendorbak = vpath + ".orig"
 			if _, err := os.Stat(vendorbak); err == nil {
 				// If the adjacent dir already exists, bite the bullet and move
 				// to a proper tempdir.
 				vendorbak = filepath.Join(td, ".vendor.orig")
 			}
+		}
 
-			failerr = fs.RenameWithFallback(vpath, vendorbak)
+		if vu.incremental {
+			// Swap only the stale paths, leaving untouched projects in place.
+			for _, path := range vu.stale {
+				oldloc := filepath.Join(vpath, filepath.FromSlash(path))
+				tmploc := filepath.Join(vendorbak, filepath.FromSlash(path))
+				if failerr = os.MkdirAll(filepath.Dir(tmploc), 0777); failerr != nil {
+					goto fail
+				}
+				if failerr = fs.RenameWithFallback(oldloc, tmploc); failerr != nil {
+					goto fail
+				}
+				restore = append(restore, pathpair{from: tmploc, to: oldloc})
+			}
+
+			for _, path := range vu.exported {
+				newloc := filepath.Join(vpath, filepath.FromSlash(path))
+				if failerr = os.MkdirAll(filepath.Dir(newloc), 0777); failerr != nil {
+					goto fail
+				}
+				if failerr = fs.RenameWithFallback(filepath.Join(td, "vendor", filepath.FromSlash(path)), newloc); failerr != nil {
+					goto fail
+				}
+				remove = append(remove, newloc)
+			}
+		} else {
+			if vendorbak != "" {
+				failerr = fs.RenameWithFallback(vpath, vendorbak)
+				if failerr != nil {
+					goto fail
+				}
+				restore = append(restore, pathpair{from: vendorbak, to: vpath})
+			}
+
+			// Move in the new one.
+			failerr = fs.RenameWithFallback(filepath.Join(td, "vendor"), vpath)
 			if failerr != nil {
 				goto fail
 			}
-			restore = append(restore, pathpair{from: vendorbak, to: vpath})
-		}
-
-		// Move in the new one.
-		failerr = fs.RenameWithFallback(filepath.Join(td, "vendor"), vpath)
-		if failerr != nil {
-			goto fail
 		}
 	}
 
@@ -417,20 +475,185 @@ func (sw *SafeWriter) Write(root string, sm gps.SourceManager, examples bool, lo
 	// dir, but if we wrote vendor, we have to clean that up directly
 	if sw.writeVendor {
 		// Nothing we can really do about an error at this point, so ignore it
-		os.RemoveAll(vendorbak)
+		for _, path := range vu.stale {
+			removeEmptyParents(vpath, filepath.Join(vpath, filepath.FromSlash(path)))
+		}
+		if vendorbak != "" {
+			os.RemoveAll(vendorbak)
+		}
 	}
 
 	return nil
 
 fail:
-	// If we failed at any point, move all the things back into place, then bail.
+	// If we failed at any point, remove anything new from the vendor dir and
+	// move all the things back into place, then bail.
+	for _, path := range remove {
+		os.RemoveAll(path)
+	}
+	restored := true
 	for _, pair := range restore {
 		// Nothing we can do on err here, as we're already in recovery mode.
-		fs.RenameWithFallback(pair.from, pair.to)
+		if fs.RenameWithFallback(pair.from, pair.to) != nil {
+			restored = false
+		}
+	}
+	if restored && vendorbak != "" {
+		os.RemoveAll(vendorbak)
 	}
 	return failerr
 }
 
+// vendorUpdate describes how a vendor tree staged by writeVendorTree is to be
+// moved into place.
+type vendorUpdate struct {
+	// incremental indicates that only the paths below are to be swapped in
+	// the existing vendor directory; otherwise, the whole staged vendor
+	// directory replaces it.
+	incremental bool
+	// exported holds the slash-separated paths of the projects written to the
+	// staging directory.
+	exported []string
+	// stale holds the slash-separated paths in the existing vendor directory
+	// that are to be replaced or removed.
+	stale []string
+}
+
+// writeVendorTree writes the projects in the lock into the staging directory
+// to.
+//
+// If the existing vendor directory, from, can be updated in place, only the
+// projects that were added or changed in the lock, or whose tree no longer
+// matches the digest recorded for it, are exported and pruned anew; the rest
+// are left where they are. The digest of every project's tree is then recorded
+// in the lock; the returned bool indicates whether any recorded digests or
+// prune options changed as a result.
+func (sw *SafeWriter) writeVendorTree(to, from string, sm gps.SourceManager, logger *log.Logger) (vendorUpdate, bool, error) {
+	var vu vendorUpdate
+	lps := sw.lock.Projects()
+
+	var status map[string]pkgtree.VendorStatus
+	if isDir, _ := fs.IsDir(from); isDir && !hasNestedProjects(lps) {
+		wantSums := make(map[string][]byte, len(lps))
+		for _, lp := range lps {
+			pr := lp.Ident().ProjectRoot
+			if lp.PruneOptions() == sw.pruneOptions.PruneOptionsFor(pr) {
+				wantSums[string(pr)] = lp.VendorDigest()
+			} else {
+				wantSums[string(pr)] = nil
+			}
+		}
+
+		var err error
+		status, err = pkgtree.VerifyDepTree(from, wantSums)
+		if err != nil {
+			return vu, false, errors.Wrap(err, "failed to verify existing vendor tree")
+		}
+		vu.incremental = true
+	}
+
+	changed := make(map[gps.ProjectRoot]bool)
+	if sw.lockDiff != nil {
+		for _, lpd := range sw.lockDiff.Add {
+			changed[lpd.Name] = true
+		}
+		for _, lpd := range sw.lockDiff.Modify {
+			changed[lpd.Name] = true
+		}
+	}
+
+	var toExport gps.SimpleLock
+	for _, lp := range lps {
+		pr := string(lp.Ident().ProjectRoot)
+		if !changed[lp.Ident().ProjectRoot] && status[pr] == pkgtree.NoMismatch {
+			if logger != nil {
+				logger.Printf("Kept %s@%s, vendored tree matches %s\n", lp.Ident(), lp.Version(), LockName)
+			}
+			continue
+		}
+
+		toExport = append(toExport, lp)
+		if vu.incremental {
+			vu.exported = append(vu.exported, pr)
+			if status[pr] != pkgtree.NotInTree {
+				vu.stale = append(vu.stale, pr)
+			}
+		}
+	}
+
+	for path, st := range status {
+		if st == pkgtree.NotInLock {
+			vu.stale = append(vu.stale, path)
+		}
+	}
+	sort.Strings(vu.stale)
+
+	var onWrite func(gps.WriteProgress)
+	if logger != nil {
+		onWrite = func(progress gps.WriteProgress) {
+			logger.Println(progress)
+		}
+	}
+	if err := gps.WriteDepTree(to, toExport, sm, sw.pruneOptions, onWrite); err != nil {
+		return vu, false, err
+	}
+
+	exported := make(map[gps.ProjectRoot]bool, len(toExport))
+	for _, lp := range toExport {
+		exported[lp.Ident().ProjectRoot] = true
+	}
+
+	var lockChanged bool
+	for i, lp := range lps {
+		pr := lp.Ident().ProjectRoot
+		digest := lp.VendorDigest()
+		if exported[pr] {
+			var err error
+			digest, err = pkgtree.DigestFromDirectory(filepath.Join(to, filepath.FromSlash(string(pr))))
+			if err != nil {
+				return vu, false, errors.Wrapf(err, "failed to compute digest of %s", pr)
+			}
+		}
+
+		opts := sw.pruneOptions.PruneOptionsFor(pr)
+		if opts != lp.PruneOptions() || !bytes.Equal(digest, lp.VendorDigest()) {
+			lockChanged = true
+		}
+		lps[i] = lp.WithVendorDigest(opts, digest)
+	}
+
+	return vu, lockChanged, nil
+}
+
+// hasNestedProjects reports whether any of the given projects is rooted within
+// another one. Such projects cannot be swapped in and out of a vendor tree
+// independently of each other.
+func hasNestedProjects(lps []gps.LockedProject) bool {
+	roots := make(map[string]bool, len(lps))
+	for _, lp := range lps {
+		roots[string(lp.Ident().ProjectRoot)] = true
+	}
+
+	for root := range roots {
+		for i := strings.LastIndex(root, "/"); i > 0; i = strings.LastIndex(root[:i], "/") {
+			if roots[root[:i]] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// removeEmptyParents removes the empty directories between path and root,
+// starting with the parent of path. root itself is never removed.
+func removeEmptyParents(root, path string) {
+	for dir := filepath.Dir(path); dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
+
 // PrintPreparedActions logs the actions a call to Write would perform.
 func (sw *SafeWriter) PrintPreparedActions(output *log.Logger, verbose bool) error {
 	if sw.HasManifest() {
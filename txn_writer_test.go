@@ -5,6 +5,7 @@
 package dep
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -12,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/pkgtree"
 	"github.com/golang/dep/internal/test"
 	"github.com/pkg/errors"
 )
@@ -599,3 +601,164 @@ func TestSafeWriter_VendorDotGitPreservedWithForceVendor(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestSafeWriter_CarriesVendorDigests(t *testing.T) {
+	id := gps.ProjectIdentifier{ProjectRoot: "github.com/foo/bar"}
+	digest := []byte{0xde, 0xad, 0xbe, 0xef}
+	opts := gps.PruneOptions(gps.PruneNestedVendorDirs)
+
+	oldLock := &Lock{
+		P: []gps.LockedProject{
+			gps.NewLockedProject(id, gps.NewVersion("v1.0.0").Pair("abc123"), []string{"."}).WithVendorDigest(opts, digest),
+		},
+	}
+	unchanged := &Lock{
+		P: []gps.LockedProject{
+			gps.NewLockedProject(id, gps.NewVersion("v1.0.0").Pair("abc123"), []string{"."}),
+		},
+	}
+	changed := &Lock{
+		P: []gps.LockedProject{
+			gps.NewLockedProject(id, gps.NewVersion("v1.1.0").Pair("def456"), []string{"."}),
+		},
+	}
+
+	sw, err := NewSafeWriter(nil, oldLock, unchanged, VendorOnChanged, defaultCascadingPruneOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sw.lock.P[0].VendorDigest(); !bytes.Equal(got, digest) {
+		t.Fatalf("expected digest of unchanged project to be carried over, got %x", got)
+	}
+	if got := sw.lock.P[0].PruneOptions(); got != opts {
+		t.Fatalf("expected prune options of unchanged project to be carried over, got %v", got)
+	}
+
+	sw, err = NewSafeWriter(nil, oldLock, changed, VendorOnChanged, defaultCascadingPruneOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sw.lock.P[0].VendorDigest(); got != nil {
+		t.Fatalf("expected digest of changed project to be dropped, got %x", got)
+	}
+}
+
+func TestSafeWriter_VendorReusedWhenDigestMatches(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	pc := NewTestProjectContext(h, safeWriterProject)
+	defer pc.Release()
+
+	pr := gps.ProjectRoot("github.com/foo/bar")
+	h.TempFile(filepath.Join(pc.tempProjectDir, "vendor", "github.com", "foo", "bar", "bar.go"), "package bar\n")
+	digest, err := pkgtree.DigestFromDirectory(filepath.Join(pc.Project.AbsRoot, "vendor", "github.com", "foo", "bar"))
+	h.Must(err)
+
+	prune := defaultCascadingPruneOptions()
+	l := &Lock{
+		P: []gps.LockedProject{
+			gps.NewLockedProject(
+				gps.ProjectIdentifier{ProjectRoot: pr},
+				gps.NewVersion("v1.0.0").Pair("abc123"),
+				[]string{"."},
+			).WithVendorDigest(prune.PruneOptionsFor(pr), digest),
+		},
+	}
+
+	sw, err := NewSafeWriter(nil, l, l, VendorAlways, prune)
+	h.Must(err)
+
+	// The project is not available from any source, so this only succeeds if
+	// the existing vendored tree is reused.
+	err = sw.Write(pc.Project.AbsRoot, pc.SourceManager, true, nil)
+	h.Must(errors.Wrap(err, "SafeWriter.Write failed"))
+
+	if err := pc.VendorFileShouldExist("github.com/foo/bar/bar.go"); err != nil {
+		t.Fatal(err)
+	}
+	if err := pc.LockShouldNotExist(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSafeWriter_VendorUpdatedInPlace(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	pc := NewTestProjectContext(h, safeWriterProject)
+	defer pc.Release()
+
+	vendor := filepath.Join(pc.tempProjectDir, "vendor")
+	h.TempFile(filepath.Join(vendor, "github.com", "foo", "bar", "bar.go"), "package bar\n")
+	h.TempFile(filepath.Join(vendor, "github.com", "stray", "stray1", "stray.go"), "package stray1\n")
+	h.TempFile(filepath.Join(vendor, "README"), "stray file\n")
+	h.TempFile(filepath.Join(vendor, ".git", "HEAD"), "ref: refs/heads/master\n")
+
+	pr := gps.ProjectRoot("github.com/foo/bar")
+	barPath := filepath.Join(pc.Project.AbsRoot, "vendor", "github.com", "foo", "bar")
+	digest, err := pkgtree.DigestFromDirectory(barPath)
+	h.Must(err)
+	before, err := os.Stat(barPath)
+	h.Must(err)
+
+	prune := defaultCascadingPruneOptions()
+	l := &Lock{
+		P: []gps.LockedProject{
+			gps.NewLockedProject(
+				gps.ProjectIdentifier{ProjectRoot: pr},
+				gps.NewVersion("v1.0.0").Pair("abc123"),
+				[]string{"."},
+			).WithVendorDigest(prune.PruneOptionsFor(pr), digest),
+		},
+	}
+
+	sw, err := NewSafeWriter(nil, l, l, VendorAlways, prune)
+	h.Must(err)
+	err = sw.Write(pc.Project.AbsRoot, pc.SourceManager, true, nil)
+	h.Must(errors.Wrap(err, "SafeWriter.Write failed"))
+
+	// The untouched project should not have been moved at all.
+	after, err := os.Stat(barPath)
+	h.Must(err)
+	if !os.SameFile(before, after) {
+		t.Fatal("expected the vendored project to be left in place")
+	}
+
+	if err := pc.VendorFileShouldExist(".git/HEAD"); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"github.com/stray", "README"} {
+		h.MustNotExist(filepath.Join(pc.Project.AbsRoot, "vendor", filepath.FromSlash(path)))
+	}
+	h.MustNotExist(filepath.Join(pc.Project.AbsRoot, "vendor.orig"))
+}
+
+func TestHasNestedProjects(t *testing.T) {
+	lps := func(roots ...string) []gps.LockedProject {
+		var ret []gps.LockedProject
+		for _, root := range roots {
+			ret = append(ret, gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: gps.ProjectRoot(root)}, gps.Revision("abc123"), nil))
+		}
+		return ret
+	}
+
+	cases := map[string]struct {
+		lps  []gps.LockedProject
+		want bool
+	}{
+		"none":                  {lps: lps()},
+		"siblings":              {lps: lps("github.com/foo/bar", "github.com/foo/barbaz")},
+		"nested":                {lps: lps("github.com/foo/bar/baz", "github.com/foo/bar"), want: true},
+		"nested deeper":         {lps: lps("github.com/foo/bar", "github.com/foo/bar/baz/qux", "github.com/foo/bark"), want: true},
+		"nested past a sibling": {lps: lps("a/b", "a/b-c", "a/b/d"), want: true},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := hasNestedProjects(c.lps); got != c.want {
+				t.Fatalf("expected %v, got %v", c.want, got)
+			}
+		})
+	}
+}